@@ -5,32 +5,114 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"strings"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"codezone-wails/executor"
+	"codezone-wails/history"
+	"codezone-wails/profiles"
+	"codezone-wails/secrets"
+	"codezone-wails/server"
+	"codezone-wails/settings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// EventExecutionOutput is emitted with an executor.OutputChunk for every piece
+// of output produced by a streaming execution.
+const EventExecutionOutput = "execution:output"
+
+var (
+	errHistoryUnavailable  = errors.New("execution history is not available")
+	errProfilesUnavailable = errors.New("connection profiles are not available")
 )
 
 // App struct
 type App struct {
-	ctx     context.Context
-	execMgr *executor.ExecutionManager
+	ctx      context.Context
+	execMgr  *executor.ExecutionManager
+	history  *history.Store
+	profiles *profiles.Store
+
+	settings     settings.Settings
+	settingsPath string
+	settingsMu   sync.Mutex
+
+	apiServer *server.Server
+	apiMu     sync.Mutex
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	opts := executor.DefaultExecutorOptions()
-	// Allow a generous timeout for potentially long-running code.
-	opts.Timeout = 15 * time.Second
-	opts.MemoryMB = 128 // 128MB memory limit per execution context.
+	settingsPath, appSettings := loadSettings()
+
+	defaults, overrides := appSettings.ExecutorOptions()
+	execMgr := executor.NewExecutionManager(defaults)
+	execMgr.SetOptions(defaults, overrides)
 
 	return &App{
-		execMgr: executor.NewExecutionManager(opts),
+		execMgr:      execMgr,
+		history:      openHistory(appSettings.HistoryLimit),
+		profiles:     openProfiles(),
+		settings:     appSettings,
+		settingsPath: settingsPath,
 	}
 }
 
+// loadSettings reads the settings file, falling back to the defaults when it
+// is missing or invalid. The returned path is empty if there is no config dir.
+func loadSettings() (string, settings.Settings) {
+	path, err := settings.DefaultPath()
+	if err != nil {
+		log.Printf("Settings: Using defaults - %v", err)
+		return "", settings.Default()
+	}
+
+	appSettings, err := settings.Load(path)
+	if err != nil {
+		log.Printf("Settings: Using defaults - %v", err)
+	}
+	return path, appSettings
+}
+
+// openHistory opens the execution history, returning nil when it cannot be
+// used so that the app still runs without it.
+func openHistory(limit int) *history.Store {
+	path, err := history.DefaultPath()
+	if err != nil {
+		log.Printf("History: Disabled - %v", err)
+		return nil
+	}
+
+	store, err := history.Open(path, limit)
+	if err != nil {
+		log.Printf("History: Disabled - %v", err)
+		return nil
+	}
+	return store
+}
+
+// openProfiles opens the saved connection profiles, returning nil when they
+// cannot be used so that the app still runs without them.
+func openProfiles() *profiles.Store {
+	dir, err := profiles.DefaultDir()
+	if err != nil {
+		log.Printf("Profiles: Disabled - %v", err)
+		return nil
+	}
+
+	store, err := profiles.Open(filepath.Join(dir, "connections.json"), secrets.Default(dir))
+	if err != nil {
+		log.Printf("Profiles: Disabled - %v", err)
+		return nil
+	}
+	return store
+}
+
 // startup is called when the app starts.
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
@@ -39,8 +121,24 @@ func (a *App) startup(ctx context.Context) {
 // onBeforeClose is called just before the application shuts down.
 // It's the ideal place to clean up resources.
 func (a *App) onBeforeClose(ctx context.Context) (prevent bool) {
+	if a.hasOpenSQLTransaction() {
+		answer, err := runtime.MessageDialog(ctx, runtime.MessageDialogOptions{
+			Type:    runtime.QuestionDialog,
+			Title:   "Open transaction",
+			Message: "An open transaction will be rolled back. Quit anyway?",
+		})
+		if err == nil && answer != "Yes" {
+			log.Println("Application: Shutdown cancelled because of an open transaction")
+			return true
+		}
+	}
+
 	log.Println("Application: Starting shutdown process...")
 
+	if err := a.StopAPIServer(); err != nil {
+		log.Printf("Application: Error stopping API server: %v", err)
+	}
+
 	if a.execMgr != nil {
 		// Explicitly disconnect PostgreSQL if connected
 		pgExecutor, ok := a.execMgr.GetExecutor(executor.PostgreSQL).(*executor.PostgreSQLExecutor)
@@ -58,6 +156,12 @@ func (a *App) onBeforeClose(ctx context.Context) (prevent bool) {
 		a.execMgr.Cleanup()
 	}
 
+	if a.history != nil {
+		if err := a.history.Close(); err != nil {
+			log.Printf("Application: Error closing history: %v", err)
+		}
+	}
+
 	log.Println("Application: Shutdown process completed")
 	return false
 }
@@ -68,27 +172,43 @@ func (a *App) Greet(name string) string {
 }
 
 func (a *App) GetGoVersion() string {
-	output, err := executor.ExecCommand([]string{"go", "version"})
-	if err != nil {
+	version := executor.GoVersion()
+	if version == "" {
 		return "Error getting Go version"
 	}
-	version := strings.TrimSpace(output)
-	parts := strings.Fields(version)
-	if len(parts) >= 3 {
-		// Expected format: "go version go1.22.4 linux/amd64"
-		// Extract "go1.22.4" and remove "go" prefix
-		return "go v" + strings.TrimPrefix(parts[2], "go")
-	}
-	return "Unknown Go version"
+	return "go v" + version
 }
 
-// ExecuteCode executes code using the persistent execution manager.
+// ExecuteCode executes code using the persistent execution manager and records
+// the run in the history. When config.Stream is set, output is also emitted live
+// as EventExecutionOutput events.
 func (a *App) ExecuteCode(config executor.ExecutionConfig) (*executor.ExecutionResult, error) {
-	return a.execMgr.Execute(config)
+	var result *executor.ExecutionResult
+	var err error
+	if !config.Stream || a.ctx == nil {
+		result, err = a.execMgr.Execute(config)
+	} else {
+		result, err = a.execMgr.ExecuteStream(config, func(chunk executor.OutputChunk) {
+			runtime.EventsEmit(a.ctx, EventExecutionOutput, chunk)
+		})
+	}
+
+	if err == nil && a.history != nil {
+		if _, histErr := a.history.Add(config, result); histErr != nil {
+			log.Printf("History: Failed to record execution: %v", histErr)
+		}
+	}
+	return result, err
+}
+
+// CancelExecution stops a running execution started with the given execution ID.
+func (a *App) CancelExecution(id string) error {
+	log.Printf("Execution: Cancelling execution %s", id)
+	return a.execMgr.CancelExecution(id)
 }
 
-// GetSupportedLanguages returns available languages.
-func (a *App) GetSupportedLanguages() []executor.Language {
+// GetSupportedLanguages describes the available languages in a stable order.
+func (a *App) GetSupportedLanguages() []executor.LanguageDescriptor {
 	return a.execMgr.GetSupportedLanguages()
 }
 
@@ -97,6 +217,162 @@ func (a *App) RefreshExecutor(lang executor.Language) error {
 	return a.execMgr.RefreshExecutor(lang)
 }
 
+// ListHistory returns recorded executions, newest first. A limit of zero
+// returns every entry after offset.
+func (a *App) ListHistory(offset int, limit int) ([]history.Entry, error) {
+	if a.history == nil {
+		return nil, errHistoryUnavailable
+	}
+	return a.history.List(offset, limit), nil
+}
+
+// SearchHistory returns recorded executions matching the query, newest first.
+func (a *App) SearchHistory(query history.Query) ([]history.Entry, error) {
+	if a.history == nil {
+		return nil, errHistoryUnavailable
+	}
+	return a.history.Search(query), nil
+}
+
+// RerunHistoryEntry runs the code of a recorded execution again. PostgreSQL
+// entries run against the current connection, since passwords are not recorded.
+func (a *App) RerunHistoryEntry(id string) (*executor.ExecutionResult, error) {
+	if a.history == nil {
+		return nil, errHistoryUnavailable
+	}
+
+	entry, err := a.history.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	config := entry.Config
+	config.ExecutionID = ""
+	config.PostgreSQLConn = nil
+	return a.ExecuteCode(config)
+}
+
+// PinHistoryEntry pins or unpins an entry. Pinned entries survive the retention
+// limit and pruning.
+func (a *App) PinHistoryEntry(id string, pinned bool) (history.Entry, error) {
+	if a.history == nil {
+		return history.Entry{}, errHistoryUnavailable
+	}
+	return a.history.SetPinned(id, pinned)
+}
+
+// DeleteHistoryEntry removes a single entry, even if it is pinned.
+func (a *App) DeleteHistoryEntry(id string) error {
+	if a.history == nil {
+		return errHistoryUnavailable
+	}
+	return a.history.Delete(id)
+}
+
+// PruneHistory removes unpinned entries older than the given number of days and
+// returns how many were removed. Zero days removes every unpinned entry.
+func (a *App) PruneHistory(olderThanDays int) (int, error) {
+	if a.history == nil {
+		return 0, errHistoryUnavailable
+	}
+
+	var cutoff time.Time
+	if olderThanDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -olderThanDays)
+	}
+	removed, err := a.history.Prune(cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("History: Pruned %d entries", removed)
+	return removed, nil
+}
+
+// GetSettings returns the current settings.
+func (a *App) GetSettings() settings.Settings {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	return a.settings
+}
+
+// UpdateSettings validates and saves new settings, then applies them: executors
+// whose limits changed are rebuilt and the history retention limit is updated.
+func (a *App) UpdateSettings(updated settings.Settings) (settings.Settings, error) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+
+	if err := updated.Validate(); err != nil {
+		return a.settings, err
+	}
+
+	if a.settingsPath != "" {
+		if err := settings.Save(a.settingsPath, updated); err != nil {
+			log.Printf("Settings: Failed to save: %v", err)
+			return a.settings, err
+		}
+	}
+
+	defaults, overrides := updated.ExecutorOptions()
+	a.execMgr.SetOptions(defaults, overrides)
+
+	if a.history != nil {
+		if err := a.history.SetLimit(updated.HistoryLimit); err != nil {
+			log.Printf("Settings: Failed to apply history limit: %v", err)
+		}
+	}
+
+	a.settings = updated
+	log.Println("Settings: Updated")
+	return updated, nil
+}
+
+// StartAPIServer exposes the execution manager to local editors and scripts
+// over HTTP on a loopback address. Empty addr and token use the default address
+// and a random token.
+func (a *App) StartAPIServer(addr string, token string) (server.Info, error) {
+	a.apiMu.Lock()
+	defer a.apiMu.Unlock()
+
+	if a.apiServer != nil {
+		return a.apiServer.Info(), nil
+	}
+
+	srv, err := server.Start(a.execMgr, addr, token)
+	if err != nil {
+		log.Printf("API Server: Failed to start: %v", err)
+		return server.Info{}, err
+	}
+	a.apiServer = srv
+	return srv.Info(), nil
+}
+
+// StopAPIServer stops the API server if it is running.
+func (a *App) StopAPIServer() error {
+	a.apiMu.Lock()
+	defer a.apiMu.Unlock()
+
+	if a.apiServer == nil {
+		return nil
+	}
+
+	err := a.apiServer.Close()
+	a.apiServer = nil
+	log.Println("API Server: Stopped")
+	return err
+}
+
+// GetAPIServerInfo returns the address and token of the running API server.
+func (a *App) GetAPIServerInfo() server.Info {
+	a.apiMu.Lock()
+	defer a.apiMu.Unlock()
+
+	if a.apiServer == nil {
+		return server.Info{}
+	}
+	return a.apiServer.Info()
+}
+
 // HadleConnection creates pool and tests PostgreSQL connection
 func (a *App) HadleConnection(config *executor.PostgreSQLConfig) (bool, error) {
 	log.Printf("PostgreSQL: Attempting connection to %s:%d/%s as user %s",
@@ -133,6 +409,73 @@ func (a *App) HadleConnection(config *executor.PostgreSQLConfig) (bool, error) {
 	return true, nil
 }
 
+// ListConnectionProfiles returns the saved PostgreSQL connections.
+func (a *App) ListConnectionProfiles() ([]profiles.Profile, error) {
+	if a.profiles == nil {
+		return nil, errProfilesUnavailable
+	}
+	return a.profiles.List(), nil
+}
+
+// CreateConnectionProfile saves a new connection. The password and key
+// passphrases are stored in the OS keyring rather than with the profile.
+func (a *App) CreateConnectionProfile(profile profiles.Profile, secrets profiles.Secrets) (profiles.Profile, error) {
+	if a.profiles == nil {
+		return profiles.Profile{}, errProfilesUnavailable
+	}
+
+	created, err := a.profiles.Create(profile, secrets)
+	if err != nil {
+		log.Printf("Profiles: Failed to create %q: %v", profile.Name, err)
+		return profiles.Profile{}, err
+	}
+	return created, nil
+}
+
+// UpdateConnectionProfile edits a saved connection. Stored secrets are only
+// replaced when given; an empty value removes them.
+func (a *App) UpdateConnectionProfile(profile profiles.Profile, secrets profiles.Secrets) (profiles.Profile, error) {
+	if a.profiles == nil {
+		return profiles.Profile{}, errProfilesUnavailable
+	}
+
+	updated, err := a.profiles.Update(profile, secrets)
+	if err != nil {
+		log.Printf("Profiles: Failed to update %q: %v", profile.Name, err)
+		return profiles.Profile{}, err
+	}
+	return updated, nil
+}
+
+// DeleteConnectionProfile removes a saved connection and its secrets.
+func (a *App) DeleteConnectionProfile(id string) error {
+	if a.profiles == nil {
+		return errProfilesUnavailable
+	}
+	return a.profiles.Delete(id)
+}
+
+// ConnectProfile connects to PostgreSQL using a saved connection.
+func (a *App) ConnectProfile(id string) (bool, error) {
+	if a.profiles == nil {
+		return false, errProfilesUnavailable
+	}
+
+	config, err := a.profiles.Config(id)
+	if err != nil {
+		log.Printf("PostgreSQL: Connection failed - profile error: %v", err)
+		return false, err
+	}
+	return a.HadleConnection(config)
+}
+
+// ResolvePostgreSQLConfig expands a connection URI, service name, or partial
+// config into individual fields, filling gaps from PG* environment variables,
+// so the connection form can show what will actually be used.
+func (a *App) ResolvePostgreSQLConfig(config *executor.PostgreSQLConfig) (*executor.PostgreSQLConfig, error) {
+	return executor.ResolvePostgreSQLConfig(config)
+}
+
 // SetPostgreSQLConfig sets the PostgreSQL connection configuration
 func (a *App) SetPostgreSQLConfig(config *executor.PostgreSQLConfig) error {
 	if a.execMgr == nil {
@@ -166,29 +509,177 @@ func (a *App) GetPostgreSQLConnectionStatus() (bool, error) {
 	return pgExecutor.IsConnected(), nil
 }
 
-// DisconnectPostgreSQL disconnects from PostgreSQL database
-func (a *App) DisconnectPostgreSQL() error {
+// DisconnectPostgreSQL disconnects from PostgreSQL database. Disconnecting
+// rolls back the session's open transaction, so unless rollback is set it
+// leaves a session with one connected and returns false, for the frontend to
+// ask before discarding the uncommitted work.
+func (a *App) DisconnectPostgreSQL(rollback bool) (bool, error) {
 	log.Println("PostgreSQL: Attempting to disconnect from database")
 
 	if a.execMgr == nil {
 		log.Println("PostgreSQL: Disconnection failed - execution manager not initialized")
-		return fmt.Errorf("execution manager not initialized")
+		return false, fmt.Errorf("execution manager not initialized")
 	}
 
 	// Get the PostgreSQL executor
 	pgExecutor, ok := a.execMgr.GetExecutor(executor.PostgreSQL).(*executor.PostgreSQLExecutor)
 	if !ok {
 		log.Println("PostgreSQL: Disconnection failed - PostgreSQL executor not available")
-		return fmt.Errorf("PostgreSQL executor not available")
+		return false, fmt.Errorf("PostgreSQL executor not available")
+	}
+
+	if status := pgExecutor.SessionStatus(); status.Connected && status.Transaction != executor.TransactionIdle {
+		if !rollback {
+			log.Println("PostgreSQL: Disconnection held back - the session has an open transaction")
+			return false, nil
+		}
+		log.Println("PostgreSQL: Rolling back the session's open transaction")
 	}
 
 	// Cleanup the connection
 	err := pgExecutor.Cleanup()
 	if err != nil {
 		log.Printf("PostgreSQL: Disconnection failed - cleanup error: %v", err)
-		return err
+		return false, err
 	}
 
 	log.Println("PostgreSQL: Successfully disconnected from database")
+	return true, nil
+}
+
+// sqlRequestTimeout bounds requests on the PostgreSQL connection made outside
+// of runs, such as fetching a page of rows, ending the session's transaction
+// or loading the schema.
+const sqlRequestTimeout = 30 * time.Second
+
+// postgresExecutor returns the execution manager's PostgreSQL executor.
+func (a *App) postgresExecutor() (*executor.PostgreSQLExecutor, error) {
+	if a.execMgr == nil {
+		return nil, fmt.Errorf("execution manager not initialized")
+	}
+	pgExecutor, ok := a.execMgr.GetExecutor(executor.PostgreSQL).(*executor.PostgreSQLExecutor)
+	if !ok {
+		return nil, fmt.Errorf("PostgreSQL executor not available")
+	}
+	return pgExecutor, nil
+}
+
+// FetchSQLRows returns the next count rows of a query result that has more
+// rows than were returned with it, identified by its resultHandle. A count of
+// zero fetches a page of the configured maximum number of rows.
+func (a *App) FetchSQLRows(handle string, count int) (*executor.SQLPage, error) {
+	pgExecutor, err := a.postgresExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sqlRequestTimeout)
+	defer cancel()
+	return pgExecutor.FetchRows(ctx, handle, count)
+}
+
+// CloseSQLResult discards the remaining rows of a query result, freeing its
+// connection before the next query would.
+func (a *App) CloseSQLResult(handle string) error {
+	pgExecutor, err := a.postgresExecutor()
+	if err != nil {
+		return err
+	}
+
+	pgExecutor.CloseResult(handle)
 	return nil
 }
+
+// hasOpenSQLTransaction reports whether the PostgreSQL session has a
+// transaction that closing the connection would roll back.
+func (a *App) hasOpenSQLTransaction() bool {
+	pgExecutor, err := a.postgresExecutor()
+	if err != nil {
+		return false
+	}
+	status := pgExecutor.SessionStatus()
+	return status.Connected && status.Transaction != executor.TransactionIdle
+}
+
+// GetSQLSessionStatus reports the PostgreSQL session and sandbox modes and
+// the state of the session's transaction.
+func (a *App) GetSQLSessionStatus() (executor.SessionStatus, error) {
+	pgExecutor, err := a.postgresExecutor()
+	if err != nil {
+		return executor.SessionStatus{}, err
+	}
+	return pgExecutor.SessionStatus(), nil
+}
+
+// SetSQLSessionMode turns session mode on or off. In session mode SQL runs
+// share one connection, so transactions stay open between runs until they
+// are committed or rolled back.
+func (a *App) SetSQLSessionMode(enabled bool) (executor.SessionStatus, error) {
+	pgExecutor, err := a.postgresExecutor()
+	if err != nil {
+		return executor.SessionStatus{}, err
+	}
+	err = pgExecutor.SetSessionMode(enabled)
+	return pgExecutor.SessionStatus(), err
+}
+
+// SetSQLSandbox turns sandbox mode on or off. In sandbox mode every SQL run
+// is rolled back when it ends.
+func (a *App) SetSQLSandbox(enabled bool) (executor.SessionStatus, error) {
+	pgExecutor, err := a.postgresExecutor()
+	if err != nil {
+		return executor.SessionStatus{}, err
+	}
+	pgExecutor.SetSandbox(enabled)
+	return pgExecutor.SessionStatus(), nil
+}
+
+// CommitSQLTransaction commits the session's open transaction.
+func (a *App) CommitSQLTransaction() (executor.SessionStatus, error) {
+	pgExecutor, err := a.postgresExecutor()
+	if err != nil {
+		return executor.SessionStatus{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sqlRequestTimeout)
+	defer cancel()
+	return pgExecutor.Commit(ctx)
+}
+
+// RollbackSQLTransaction rolls back the session's open transaction.
+func (a *App) RollbackSQLTransaction() (executor.SessionStatus, error) {
+	pgExecutor, err := a.postgresExecutor()
+	if err != nil {
+		return executor.SessionStatus{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sqlRequestTimeout)
+	defer cancel()
+	return pgExecutor.Rollback(ctx)
+}
+
+// GetDatabaseSchema returns the schemas, tables, columns, indexes, constraints,
+// functions and sequences of the connected database, for the schema tree and
+// autocompletion. It is loaded once per connection.
+func (a *App) GetDatabaseSchema() (*executor.DatabaseSchema, error) {
+	pgExecutor, err := a.postgresExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sqlRequestTimeout)
+	defer cancel()
+	return pgExecutor.Schema(ctx)
+}
+
+// RefreshDatabaseSchema reloads the schema of the connected database.
+func (a *App) RefreshDatabaseSchema() (*executor.DatabaseSchema, error) {
+	pgExecutor, err := a.postgresExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sqlRequestTimeout)
+	defer cancel()
+	return pgExecutor.RefreshSchema(ctx)
+}
@@ -0,0 +1,310 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"codezone-wails/executor"
+	"codezone-wails/server"
+)
+
+const cliUsage = `Usage:
+  codezone run [--lang LANG] [--input FILE] [--timeout DURATION] [--json] <file>
+  codezone sql --conn CONNSTRING [--timeout DURATION] [--json] <file>
+  codezone serve [--addr ADDR] [--token TOKEN] [--timeout DURATION]
+
+Use "-" as <file> to read the code from standard input.
+`
+
+// cliExitUsage is returned for invalid command lines, matching the flag package.
+const cliExitUsage = 2
+
+// runCLI runs a headless command when args start with one. It reports whether
+// args were handled and the process exit status to use.
+func runCLI(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+
+	switch args[0] {
+	case "run":
+		return runCommand(args[1:], os.Stdout, os.Stderr), true
+	case "sql":
+		return sqlCommand(args[1:], os.Stdout, os.Stderr), true
+	case "serve":
+		return serveCommand(args[1:], os.Stdout, os.Stderr), true
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, cliUsage)
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// cliOptions are the flags shared by the run and sql commands.
+type cliOptions struct {
+	timeout time.Duration
+	json    bool
+	verbose bool
+}
+
+func (o *cliOptions) register(fs *flag.FlagSet) {
+	fs.DurationVar(&o.timeout, "timeout", 15*time.Second, "maximum execution time")
+	fs.BoolVar(&o.json, "json", false, "print the result as JSON")
+	fs.BoolVar(&o.verbose, "verbose", false, "print executor logs to stderr")
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, cliUsage) }
+	return fs
+}
+
+func runCommand(args []string, stdout, stderr io.Writer) int {
+	var opts cliOptions
+	var lang, inputFile string
+
+	fs := newFlagSet("run", stderr)
+	opts.register(fs)
+	fs.StringVar(&lang, "lang", "", "language of the code (default: inferred from the file extension)")
+	fs.StringVar(&inputFile, "input", "", "file passed to the program as standard input")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return cliExitUsage
+	}
+
+	file := fs.Arg(0)
+	language := executor.Language(lang)
+	if language == "" {
+		var ok bool
+		language, ok = executor.LanguageForExtension(filepath.Ext(file))
+		if !ok {
+			fmt.Fprintf(stderr, "codezone: cannot infer language of %s, use --lang\n", file)
+			return cliExitUsage
+		}
+	}
+
+	code, err := readSource(file)
+	if err != nil {
+		fmt.Fprintf(stderr, "codezone: %v\n", err)
+		return 1
+	}
+
+	var input string
+	if inputFile != "" {
+		data, err := os.ReadFile(inputFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "codezone: %v\n", err)
+			return 1
+		}
+		input = string(data)
+	}
+
+	config := executor.ExecutionConfig{
+		Code:     code,
+		Language: language,
+		Timeout:  opts.timeout,
+		Input:    input,
+	}
+	return executeCLI(config, file, opts, stdout, stderr)
+}
+
+func sqlCommand(args []string, stdout, stderr io.Writer) int {
+	var opts cliOptions
+	var conn, explain string
+	var readOnly bool
+
+	fs := newFlagSet("sql", stderr)
+	opts.register(fs)
+	fs.StringVar(&conn, "conn", "", "PostgreSQL connection string (URI or key=value pairs)")
+	fs.StringVar(&explain, "explain", "", "show the statement's plan instead of its rows: plan, or analyze to run it in a rolled back transaction")
+	fs.BoolVar(&readOnly, "read-only", false, "refuse statements that can write to the database")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	if fs.NArg() != 1 || conn == "" {
+		fs.Usage()
+		return cliExitUsage
+	}
+
+	pgConfig, err := parsePostgresConn(conn)
+	if err != nil {
+		fmt.Fprintf(stderr, "codezone: %v\n", err)
+		return cliExitUsage
+	}
+	pgConfig.ReadOnly = readOnly
+
+	file := fs.Arg(0)
+	code, err := readSource(file)
+	if err != nil {
+		fmt.Fprintf(stderr, "codezone: %v\n", err)
+		return 1
+	}
+
+	config := executor.ExecutionConfig{
+		Code:           code,
+		Language:       executor.PostgreSQL,
+		Timeout:        opts.timeout,
+		PostgreSQLConn: pgConfig,
+		Explain:        executor.ExplainMode(explain),
+	}
+	return executeCLI(config, file, opts, stdout, stderr)
+}
+
+// serveCommand runs the API server without the GUI until interrupted.
+// The token falls back to $CODEZONE_TOKEN and is generated when both are empty.
+func serveCommand(args []string, stdout, stderr io.Writer) int {
+	var addr, token string
+	var timeout time.Duration
+
+	fs := newFlagSet("serve", stderr)
+	fs.StringVar(&addr, "addr", server.DefaultAddr, "loopback address to listen on")
+	fs.StringVar(&token, "token", os.Getenv("CODEZONE_TOKEN"), "token clients must send as a bearer token")
+	fs.DurationVar(&timeout, "timeout", 15*time.Second, "default maximum execution time")
+	if err := fs.Parse(args); err != nil {
+		return cliExitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return cliExitUsage
+	}
+
+	opts := executor.DefaultExecutorOptions()
+	opts.Timeout = timeout
+	execMgr := executor.NewExecutionManager(opts)
+	defer execMgr.Cleanup()
+
+	srv, err := server.Start(execMgr, addr, token)
+	if err != nil {
+		fmt.Fprintf(stderr, "codezone: %v\n", err)
+		return 1
+	}
+
+	info := srv.Info()
+	fmt.Fprintf(stdout, "Listening on http://%s\nToken: %s\n", info.Address, info.Token)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	<-signals
+
+	if err := srv.Close(); err != nil {
+		fmt.Fprintf(stderr, "codezone: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// executeCLI runs config and prints the result. Plain text output is streamed
+// while the program runs; JSON output is printed once it finishes.
+func executeCLI(config executor.ExecutionConfig, file string, opts cliOptions, stdout, stderr io.Writer) int {
+	if !opts.verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	executorOpts := executor.DefaultExecutorOptions()
+	executorOpts.Timeout = opts.timeout
+	execMgr := executor.NewExecutionManager(executorOpts)
+	defer execMgr.Cleanup()
+
+	var streamed, streamedStderr bool
+	var onChunk func(executor.OutputChunk)
+	if !opts.json {
+		onChunk = func(chunk executor.OutputChunk) {
+			streamed = true
+			if chunk.Stream == executor.Stderr {
+				streamedStderr = true
+				io.WriteString(stderr, chunk.Data)
+			} else {
+				io.WriteString(stdout, chunk.Data)
+			}
+		}
+	}
+
+	result, err := execMgr.ExecuteStream(config, onChunk)
+	if err != nil {
+		fmt.Fprintf(stderr, "codezone: %v\n", err)
+		return 1
+	}
+
+	if opts.json {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fmt.Fprintf(stderr, "codezone: %v\n", err)
+			return 1
+		}
+		return result.ExitCode
+	}
+
+	printResult(result, file, streamed, streamedStderr, stdout, stderr)
+	return result.ExitCode
+}
+
+// printResult writes the parts of result that were not already streamed.
+func printResult(result *executor.ExecutionResult, file string, streamed, streamedStderr bool, stdout, stderr io.Writer) {
+	if !streamed {
+		if result.Output != "" {
+			fmt.Fprintln(stdout, strings.TrimRight(result.Output, "\n"))
+		}
+		if result.Error != "" {
+			fmt.Fprintln(stderr, result.Error)
+		}
+	} else if result.ExitCode != 0 && result.Error != "" && len(result.Diagnostics) == 0 && (!streamedStderr || stoppedByLimit(result.ExitCode)) {
+		// Timeouts, cancellations and limit errors are not part of the stream;
+		// the error of a program that wrote to stderr is what it wrote.
+		fmt.Fprintln(stderr, result.Error)
+	}
+
+	for _, d := range result.Diagnostics {
+		fmt.Fprintf(stderr, "%s:%d:%d: %s: %s (%s)\n", file, d.Line, d.Column, d.Severity, d.Message, d.Source)
+	}
+
+	if result.Truncation != nil {
+		fmt.Fprintf(stderr, "codezone: output truncated, %d lines (%d bytes) dropped\n",
+			result.Truncation.DroppedLines, result.Truncation.DroppedBytes)
+	}
+}
+
+// stoppedByLimit reports whether exitCode means the run was stopped from
+// outside, so that its error was never written by the program.
+func stoppedByLimit(exitCode int) bool {
+	switch exitCode {
+	case executor.ExitCodeTimeout, executor.ExitCodeCancelled, executor.ExitCodeMemoryLimitExceeded:
+		return true
+	}
+	return false
+}
+
+func readSource(file string) (string, error) {
+	if file == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+	}
+	data, err := os.ReadFile(file)
+	return string(data), err
+}
+
+// parsePostgresConn validates a libpq connection string or URI. Missing values
+// fall back to the service file and PG* environment variables.
+func parsePostgresConn(conn string) (*executor.PostgreSQLConfig, error) {
+	config := &executor.PostgreSQLConfig{ConnectionString: conn}
+	if _, err := executor.ResolvePostgreSQLConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
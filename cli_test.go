@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codezone-wails/executor"
+)
+
+// writeSource writes code to a file named name in a temporary directory.
+func writeSource(t *testing.T, name, code string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(code), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+// runCLICommand runs the run command with args and returns its exit status
+// and output.
+func runCLICommand(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := runCommand(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunCommand_Language(t *testing.T) {
+	t.Run("should infer the language from the file extension", func(t *testing.T) {
+		file := writeSource(t, "hello.ts", `const greeting: string = "hello"; console.log(greeting)`)
+
+		code, stdout, stderr := runCLICommand(file)
+		if code != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+		}
+		if strings.TrimSpace(stdout) != "hello" {
+			t.Errorf("Expected 'hello', got %q", stdout)
+		}
+	})
+
+	t.Run("should refuse files of unknown languages without --lang", func(t *testing.T) {
+		file := writeSource(t, "hello.txt", `console.log("hello")`)
+
+		code, _, stderr := runCLICommand(file)
+		if code != cliExitUsage {
+			t.Fatalf("Expected exit code %d, got %d", cliExitUsage, code)
+		}
+		if !strings.Contains(stderr, "cannot infer language") {
+			t.Errorf("Expected a hint to use --lang, got %q", stderr)
+		}
+	})
+
+	t.Run("should prefer --lang over the file extension", func(t *testing.T) {
+		file := writeSource(t, "hello.go", `console.log("from typescript")`)
+
+		code, stdout, stderr := runCLICommand("--lang", string(executor.TypeScript), "--json", file)
+		if code != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+		}
+		var result executor.ExecutionResult
+		if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+			t.Fatalf("Expected JSON output, got %q: %v", stdout, err)
+		}
+		if result.Language != executor.TypeScript || !strings.Contains(result.Output, "from typescript") {
+			t.Errorf("Expected the file to run as TypeScript, got %+v", result)
+		}
+	})
+}
+
+func TestRunCommand_JSON(t *testing.T) {
+	t.Run("should print the result as a single JSON object", func(t *testing.T) {
+		file := writeSource(t, "hello.js", `console.log("hello")`)
+
+		code, stdout, stderr := runCLICommand("--json", file)
+		if code != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(stdout), &fields); err != nil {
+			t.Fatalf("Expected a JSON object, got %q: %v", stdout, err)
+		}
+		for _, key := range []string{"output", "error", "exitCode", "duration", "durationString", "language"} {
+			if _, ok := fields[key]; !ok {
+				t.Errorf("Expected the %q field, got %s", key, stdout)
+			}
+		}
+		if string(fields["language"]) != `"typescript"` || !strings.Contains(string(fields["output"]), "hello") {
+			t.Errorf("Expected the output of a TypeScript run, got %s", stdout)
+		}
+	})
+}
+
+func TestRunCommand_ExitCode(t *testing.T) {
+	t.Run("should exit with the exit code of a failing run", func(t *testing.T) {
+		file := writeSource(t, "fail.ts", `throw new Error("boom")`)
+
+		code, _, stderr := runCLICommand(file)
+		if code == 0 {
+			t.Fatal("Expected a non-zero exit code")
+		}
+		if !strings.Contains(stderr, "boom") {
+			t.Errorf("Expected the error on stderr, got %q", stderr)
+		}
+
+		jsonCode, stdout, _ := runCLICommand("--json", file)
+		var result executor.ExecutionResult
+		if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+			t.Fatalf("Expected JSON output, got %q: %v", stdout, err)
+		}
+		if jsonCode != code || result.ExitCode != code {
+			t.Errorf("Expected exit code %d in both modes, got %d and %d in the JSON", code, jsonCode, result.ExitCode)
+		}
+	})
+
+	t.Run("should print what a failing program wrote to stderr once", func(t *testing.T) {
+		file := writeSource(t, "fail.go", `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Fprintln(os.Stderr, "boom")
+	os.Exit(3)
+}
+`)
+
+		code, _, stderr := runCLICommand("--timeout", "60s", file)
+		if code != 3 {
+			t.Fatalf("Expected exit code 3, got %d: %s", code, stderr)
+		}
+		if strings.Count(stderr, "boom") != 1 {
+			t.Errorf("Expected 'boom' once on stderr, got %q", stderr)
+		}
+	})
+
+	t.Run("should report a timeout after streamed stderr", func(t *testing.T) {
+		file := writeSource(t, "slow.ts", `console.error("working"); while (true) {}`)
+
+		code, _, stderr := runCLICommand("--timeout", "200ms", file)
+		if code != executor.ExitCodeTimeout {
+			t.Fatalf("Expected exit code %d, got %d: %s", executor.ExitCodeTimeout, code, stderr)
+		}
+		if !strings.Contains(stderr, "working") || !strings.Contains(strings.ToLower(stderr), "timed out") {
+			t.Errorf("Expected the streamed output and the timeout, got %q", stderr)
+		}
+	})
+
+	t.Run("should exit with the usage status for bad command lines", func(t *testing.T) {
+		if code, _, _ := runCLICommand(); code != cliExitUsage {
+			t.Errorf("Expected exit code %d without a file, got %d", cliExitUsage, code)
+		}
+		if code, _, _ := runCLICommand("--no-such-flag", "main.ts"); code != cliExitUsage {
+			t.Errorf("Expected exit code %d for an unknown flag, got %d", cliExitUsage, code)
+		}
+
+		var stdout, stderr bytes.Buffer
+		if code := sqlCommand([]string{"query.sql"}, &stdout, &stderr); code != cliExitUsage {
+			t.Errorf("Expected exit code %d for sql without --conn, got %d", cliExitUsage, code)
+		}
+	})
+
+	t.Run("should fail when the file cannot be read", func(t *testing.T) {
+		missing := filepath.Join(t.TempDir(), "missing.ts")
+		if code, _, stderr := runCLICommand(missing); code != 1 || !strings.Contains(stderr, "missing.ts") {
+			t.Errorf("Expected exit code 1 naming the file, got %d: %q", code, stderr)
+		}
+	})
+}
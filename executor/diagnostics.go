@@ -0,0 +1,173 @@
+package executor
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/go-sourcemap/sourcemap"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// goErrorPattern matches compiler output such as "./main.go:6:2: undefined: y".
+var goErrorPattern = regexp.MustCompile(`^(?:.*[/\\])?main\.go:(\d+)(?::(\d+))?: (.+)$`)
+
+// goCodeOffset describes how prepareGoCode moved the user's code, so compiler
+// positions can be mapped back to what the user typed.
+type goCodeOffset struct {
+	lines     int  // Lines added above the user's code
+	indented  bool // Non-blank user lines were indented by one tab
+	userLines int  // Number of lines in the user's code
+}
+
+// userPosition maps a position in the generated main.go to the user's code.
+func (o goCodeOffset) userPosition(line, column int) (int, int) {
+	line -= o.lines
+	if line < 1 || line > o.userLines {
+		return 0, 0
+	}
+	if o.indented && column > 1 {
+		column--
+	}
+	return line, column
+}
+
+// parseGoDiagnostics extracts positioned errors from `go build` output.
+func parseGoDiagnostics(output string, offset goCodeOffset) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	for _, line := range strings.Split(output, "\n") {
+		match := goErrorPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+
+		lineNum, _ := strconv.Atoi(match[1])
+		column, _ := strconv.Atoi(match[2])
+		lineNum, column = offset.userPosition(lineNum, column)
+
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Line:     lineNum,
+			Column:   column,
+			Message:  match[3],
+			Source:   "go",
+		})
+	}
+
+	return diagnostics
+}
+
+// esbuildDiagnostics converts esbuild transpile messages. esbuild reports
+// positions in the TypeScript source, so no mapping is needed.
+func esbuildDiagnostics(messages []api.Message, severity DiagnosticSeverity) []Diagnostic {
+	diagnostics := make([]Diagnostic, 0, len(messages))
+	for _, msg := range messages {
+		diagnostic := Diagnostic{
+			Severity: severity,
+			Message:  msg.Text,
+			Source:   "esbuild",
+		}
+		if msg.Location != nil {
+			diagnostic.Line = msg.Location.Line
+			diagnostic.Column = msg.Location.Column + 1
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics
+}
+
+// jsSourceMap maps positions in the transpiled JavaScript back to the
+// TypeScript the user wrote. A nil map leaves positions unchanged.
+type jsSourceMap struct {
+	consumer *sourcemap.Consumer
+}
+
+func newJSSourceMap(data []byte) *jsSourceMap {
+	if len(data) == 0 {
+		return nil
+	}
+	consumer, err := sourcemap.Parse("", data)
+	if err != nil {
+		return nil
+	}
+	return &jsSourceMap{consumer: consumer}
+}
+
+// userPosition maps a 1-based line and column in the transpiled code.
+func (m *jsSourceMap) userPosition(line, column int) (int, int) {
+	if m == nil {
+		return line, column
+	}
+	_, _, srcLine, srcColumn, ok := m.consumer.Source(line, column-1)
+	if !ok {
+		return line, column
+	}
+	return srcLine, srcColumn + 1
+}
+
+// parseJSLocation parses a "file:line:column" location as reported by V8.
+func parseJSLocation(location string) (int, int, bool) {
+	parts := strings.Split(location, ":")
+	if len(parts) < 3 {
+		return 0, 0, false
+	}
+
+	line, err := strconv.Atoi(parts[len(parts)-2])
+	if err != nil {
+		return 0, 0, false
+	}
+	column, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return line, column, true
+}
+
+// postgresDiagnostic points at the character reported in a PostgreSQL error
+// for the statement at byte offset in sqlCode, mapped through sqlMap back to
+// the query the user wrote.
+func postgresDiagnostic(err error, sqlCode string, offset int, sqlMap []sqlLineOrigin) *Diagnostic {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+
+	diagnostic := &Diagnostic{
+		Severity: SeverityError,
+		Message:  pgErr.Message,
+		Source:   "postgres",
+	}
+
+	if pgErr.Position > 0 {
+		position := utf8.RuneCountInString(sqlCode[:offset]) + int(pgErr.Position)
+		line, column := sqlPosition(sqlCode, position)
+		if line > 0 && line <= len(sqlMap) {
+			origin := sqlMap[line-1]
+			diagnostic.Line = origin.line
+			diagnostic.Column = column + origin.indent
+		}
+	}
+
+	return diagnostic
+}
+
+// sqlPosition converts a 1-based character offset into a 1-based line and column.
+func sqlPosition(sqlCode string, position int) (int, int) {
+	line, column := 1, 1
+	for i, r := range []rune(sqlCode) {
+		if i == position-1 {
+			return line, column
+		}
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,101 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestParseGoDiagnostics(t *testing.T) {
+	executor := NewGoExecutor(DefaultExecutorOptions())
+
+	testCases := []struct {
+		name     string
+		code     string
+		output   string
+		expected []Diagnostic
+	}{
+		{
+			name:   "full program",
+			code:   "package main\n\nfunc main() {\n\tfoo()\n}",
+			output: "# command-line-arguments\n./main.go:4:2: undefined: foo",
+			expected: []Diagnostic{
+				{Severity: SeverityError, Line: 4, Column: 2, Message: "undefined: foo", Source: "go"},
+			},
+		},
+		{
+			name:   "program without package clause",
+			code:   "func main() {\n\tfoo()\n}",
+			output: "# command-line-arguments\n./main.go:4:2: undefined: foo",
+			expected: []Diagnostic{
+				{Severity: SeverityError, Line: 2, Column: 2, Message: "undefined: foo", Source: "go"},
+			},
+		},
+		{
+			name:   "snippet wrapped in main",
+			code:   "x := 1\nfmt.Println(y)",
+			output: "# command-line-arguments\n./main.go:6:2: declared and not used: x\n./main.go:7:14: undefined: y",
+			expected: []Diagnostic{
+				{Severity: SeverityError, Line: 1, Column: 1, Message: "declared and not used: x", Source: "go"},
+				{Severity: SeverityError, Line: 2, Column: 13, Message: "undefined: y", Source: "go"},
+			},
+		},
+		{
+			name:   "error in generated code",
+			code:   "x := 1",
+			output: `./main.go:3:8: "fmt" imported and not used`,
+			expected: []Diagnostic{
+				{Severity: SeverityError, Line: 0, Column: 0, Message: `"fmt" imported and not used`, Source: "go"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, offset := executor.prepareGoCode(tc.code)
+			diagnostics := parseGoDiagnostics(tc.output, offset)
+
+			if fmt.Sprint(diagnostics) != fmt.Sprint(tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, diagnostics)
+			}
+		})
+	}
+}
+
+func TestParseJSLocation(t *testing.T) {
+	line, column, ok := parseJSLocation("user_code.js:3:7")
+	if !ok || line != 3 || column != 7 {
+		t.Errorf("Expected 3:7, got %d:%d (ok=%v)", line, column, ok)
+	}
+
+	if _, _, ok := parseJSLocation("user_code.js"); ok {
+		t.Error("Expected location without position to be rejected")
+	}
+}
+
+func TestPostgresDiagnostic(t *testing.T) {
+	executor := NewPostgreSQLExecutor(DefaultExecutorOptions())
+
+	code := "-- find users\nSELECT *\n  FROM userz"
+	sqlCode, origins := executor.prepareSQLCodeWithOrigins(code)
+
+	// PostgreSQL reports the 1-based character offset of "userz" in the prepared query.
+	err := &pgconn.PgError{Message: `relation "userz" does not exist`, Position: 15}
+	diagnostic := postgresDiagnostic(fmt.Errorf("wrapped: %w", err), sqlCode, 0, origins)
+
+	if diagnostic == nil {
+		t.Fatal("Expected a diagnostic")
+	}
+	if diagnostic.Line != 3 || diagnostic.Column != 8 {
+		t.Errorf("Expected position 3:8, got %d:%d", diagnostic.Line, diagnostic.Column)
+	}
+	if diagnostic.Source != "postgres" || diagnostic.Message != err.Message {
+		t.Errorf("Unexpected diagnostic %+v", diagnostic)
+	}
+
+	if postgresDiagnostic(context.DeadlineExceeded, sqlCode, 0, origins) != nil {
+		t.Error("Expected no diagnostic for non-PostgreSQL errors")
+	}
+}
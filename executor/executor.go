@@ -7,39 +7,66 @@ import (
 	"context"
 	"fmt"
 	"sync"
+
+	"github.com/google/uuid"
 )
 
 type ExecutionManager struct {
 	executors map[Language]Executor
 	options   ExecutorOptions
+	overrides map[Language]ExecutorOptions
 	mu        sync.RWMutex
+
+	running   map[string]context.CancelCauseFunc
+	runningMu sync.Mutex
 }
 
 func NewExecutionManager(opts ExecutorOptions) *ExecutionManager {
 	manager := &ExecutionManager{
 		executors: make(map[Language]Executor),
 		options:   opts,
+		running:   make(map[string]context.CancelCauseFunc),
 	}
 
-	manager.executors[TypeScript] = NewTypeScriptExecutor(opts)
-	manager.executors[Go] = NewGoExecutor(opts)
-	manager.executors[PostgreSQL] = NewPostgreSQLExecutor(opts)
+	for _, reg := range Registrations() {
+		manager.executors[reg.Language] = reg.New(opts)
+	}
 
 	return manager
 }
 
 func (em *ExecutionManager) Execute(config ExecutionConfig) (*ExecutionResult, error) {
-	ctx := context.Background()
-	var cancel context.CancelFunc
-	if config.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
-		defer cancel()
+	return em.ExecuteStream(config, nil)
+}
+
+// ExecuteStream runs the code like Execute and passes output chunks to onChunk
+// while the program is running, for executors that support streaming.
+func (em *ExecutionManager) ExecuteStream(config ExecutionConfig, onChunk func(OutputChunk)) (*ExecutionResult, error) {
+	if config.ExecutionID == "" {
+		config.ExecutionID = uuid.NewString()
+	}
+
+	ctx, done, err := em.Track(config.ExecutionID)
+	if err != nil {
+		return nil, err
 	}
+	defer done()
 
 	em.mu.RLock()
 	executor, exists := em.executors[config.Language]
+	timeout := em.optionsFor(config.Language).Timeout
 	em.mu.RUnlock()
 
+	// Runs without their own timeout use the language's configured one.
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	if !exists {
 		return nil, fmt.Errorf("executor for %s is not available", config.Language)
 	}
@@ -56,15 +83,81 @@ func (em *ExecutionManager) Execute(config ExecutionConfig) (*ExecutionResult, e
 		return nil, fmt.Errorf("executor for %s is not available", config.Language)
 	}
 
-	return executor.Execute(ctx, config.Code, config.Input)
+	var result *ExecutionResult
+	if config.Explain != "" {
+		pgExecutor, ok := executor.(*PostgreSQLExecutor)
+		if !ok {
+			return nil, fmt.Errorf("explain mode is only available for %s", PostgreSQL)
+		}
+		result, err = pgExecutor.Explain(ctx, config.Code, config.Explain)
+	} else if pgExecutor, ok := executor.(*PostgreSQLExecutor); ok && config.Confirmed {
+		result, err = pgExecutor.ExecuteConfirmed(ctx, config.Code)
+	} else if streamer, ok := executor.(StreamingExecutor); ok && onChunk != nil {
+		result, err = streamer.ExecuteStream(ctx, config.Code, config.Input, func(stream OutputStream, data string) {
+			onChunk(OutputChunk{
+				ExecutionID: config.ExecutionID,
+				Stream:      stream,
+				Data:        data,
+			})
+		})
+	} else {
+		result, err = executor.Execute(ctx, config.Code, config.Input)
+	}
+
+	if result != nil {
+		result.ExecutionID = config.ExecutionID
+	}
+	return result, err
+}
+
+// Track registers work done outside of ExecuteStream, such as an export, as
+// an execution with the given ID, so that CancelExecution stops it. It returns
+// the context to do the work with and the function to call once it is done.
+func (em *ExecutionManager) Track(id string) (context.Context, func(), error) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	em.runningMu.Lock()
+	defer em.runningMu.Unlock()
+	if _, exists := em.running[id]; exists {
+		cancel(nil)
+		return nil, nil, fmt.Errorf("execution %s is already running", id)
+	}
+	em.running[id] = cancel
+
+	return ctx, func() {
+		cancel(nil)
+		em.runningMu.Lock()
+		delete(em.running, id)
+		em.runningMu.Unlock()
+	}, nil
+}
+
+// CancelExecution stops the running execution with the given ID. The execution
+// returns a result with ExitCodeCancelled.
+func (em *ExecutionManager) CancelExecution(id string) error {
+	em.runningMu.Lock()
+	cancel, ok := em.running[id]
+	em.runningMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no running execution with id %s", id)
+	}
+
+	cancel(ErrExecutionCancelled)
+	return nil
 }
 
-func (em *ExecutionManager) GetSupportedLanguages() []Language {
+// GetSupportedLanguages describes the languages this manager can run, in
+// registration order.
+func (em *ExecutionManager) GetSupportedLanguages() []LanguageDescriptor {
 	em.mu.RLock()
 	defer em.mu.RUnlock()
-	languages := make([]Language, 0, len(em.executors))
-	for lang := range em.executors {
-		languages = append(languages, lang)
+
+	languages := make([]LanguageDescriptor, 0, len(em.executors))
+	for _, reg := range Registrations() {
+		if _, ok := em.executors[reg.Language]; ok {
+			languages = append(languages, reg.Describe())
+		}
 	}
 	return languages
 }
@@ -83,22 +176,83 @@ func (em *ExecutionManager) Cleanup() {
 	}
 }
 
+// RefreshExecutor replaces the executor for lang with a new one built from its
+// registration. A PostgreSQL executor keeps its connection configuration and
+// its session and sandbox modes, but opens a new pool on its next run.
 func (em *ExecutionManager) RefreshExecutor(lang Language) error {
+	reg, ok := Registration(lang)
+	if !ok {
+		return fmt.Errorf("cannot refresh unsupported language: %s", lang)
+	}
+
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	em.replaceExecutor(reg)
+	return nil
+}
+
+// Options returns the effective options for lang: the manager's defaults with
+// any per-language overrides applied.
+func (em *ExecutionManager) Options(lang Language) ExecutorOptions {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+	return em.optionsFor(lang)
+}
+
+func (em *ExecutionManager) optionsFor(lang Language) ExecutorOptions {
+	return em.options.merge(em.overrides[lang])
+}
+
+// SetOptions changes the default options and the per-language overrides, whose
+// zero fields inherit the defaults. Executors whose effective options changed
+// are rebuilt; the others are kept. The PostgreSQL executor takes new options
+// in place, so that its pool and any session with an open transaction are kept.
+func (em *ExecutionManager) SetOptions(defaults ExecutorOptions, overrides map[Language]ExecutorOptions) {
 	em.mu.Lock()
 	defer em.mu.Unlock()
 
+	previous := make(map[Language]ExecutorOptions, len(em.executors))
+	for lang := range em.executors {
+		previous[lang] = em.optionsFor(lang)
+	}
+
+	em.options = defaults
+	em.overrides = make(map[Language]ExecutorOptions, len(overrides))
+	for lang, override := range overrides {
+		em.overrides[lang] = override
+	}
+
+	for _, reg := range Registrations() {
+		old, ok := previous[reg.Language]
+		if !ok || old == em.optionsFor(reg.Language) {
+			continue
+		}
+		if pg, ok := em.executors[reg.Language].(*PostgreSQLExecutor); ok {
+			pg.setOptions(em.optionsFor(reg.Language))
+			continue
+		}
+		em.replaceExecutor(reg)
+	}
+}
+
+// replaceExecutor swaps in a new executor for reg. em.mu must be held.
+func (em *ExecutionManager) replaceExecutor(reg LanguageRegistration) {
+	lang := reg.Language
+	executor := reg.New(em.optionsFor(lang))
+
 	if oldExecutor, ok := em.executors[lang]; ok {
 		oldExecutor.Cleanup()
-	}
 
-	switch lang {
-	case TypeScript:
-		em.executors[TypeScript] = NewTypeScriptExecutor(em.options)
-	case Go:
-		em.executors[Go] = NewGoExecutor(em.options)
-	default:
-		return fmt.Errorf("cannot refresh unsupported language: %s", lang)
+		if oldPg, ok := oldExecutor.(*PostgreSQLExecutor); ok {
+			if newPg, ok := executor.(*PostgreSQLExecutor); ok {
+				newPg.SetConfig(oldPg.Config())
+				status := oldPg.SessionStatus()
+				newPg.SetSessionMode(status.Session)
+				newPg.SetSandbox(status.Sandbox)
+			}
+		}
 	}
 
-	return nil
+	em.executors[lang] = executor
 }
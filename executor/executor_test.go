@@ -0,0 +1,166 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecutionManager_ExecuteStream(t *testing.T) {
+	manager := NewExecutionManager(DefaultExecutorOptions())
+	defer manager.Cleanup()
+
+	t.Run("should tag chunks with the execution id", func(t *testing.T) {
+		var chunks []OutputChunk
+		config := ExecutionConfig{
+			ExecutionID: "run-1",
+			Code:        `console.log("streamed");`,
+			Language:    TypeScript,
+		}
+
+		result, err := manager.ExecuteStream(config, func(chunk OutputChunk) {
+			chunks = append(chunks, chunk)
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.ExecutionID != "run-1" {
+			t.Errorf("Expected execution id run-1, got %q", result.ExecutionID)
+		}
+
+		if len(chunks) != 1 {
+			t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+		}
+
+		if chunks[0].ExecutionID != "run-1" || chunks[0].Stream != Stdout || chunks[0].Data != "streamed\n" {
+			t.Errorf("Unexpected chunk %+v", chunks[0])
+		}
+	})
+
+	t.Run("should generate an execution id when none is given", func(t *testing.T) {
+		result, err := manager.Execute(ExecutionConfig{
+			Code:     `1 + 1`,
+			Language: TypeScript,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.ExecutionID == "" {
+			t.Error("Expected a generated execution id")
+		}
+	})
+}
+
+func TestExecutionManager_CancelExecution(t *testing.T) {
+	manager := NewExecutionManager(DefaultExecutorOptions())
+	defer manager.Cleanup()
+
+	t.Run("should stop a running script", func(t *testing.T) {
+		done := make(chan *ExecutionResult, 1)
+		go func() {
+			result, err := manager.Execute(ExecutionConfig{
+				ExecutionID: "loop",
+				Code:        `while(true) {}`,
+				Language:    TypeScript,
+				Timeout:     10 * time.Second,
+			})
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			done <- result
+		}()
+
+		deadline := time.Now().Add(2 * time.Second)
+		for manager.CancelExecution("loop") != nil {
+			if time.Now().After(deadline) {
+				t.Fatal("Execution never became cancellable")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		select {
+		case result := <-done:
+			if result.ExitCode != ExitCodeCancelled {
+				t.Errorf("Expected exit code %d, got %d", ExitCodeCancelled, result.ExitCode)
+			}
+			if !strings.Contains(result.Error, "cancelled") {
+				t.Errorf("Expected cancellation error, got %q", result.Error)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Execution did not stop after cancellation")
+		}
+	})
+
+	t.Run("should fail for unknown execution", func(t *testing.T) {
+		if err := manager.CancelExecution("missing"); err == nil {
+			t.Error("Expected error for unknown execution id")
+		}
+	})
+}
+
+func TestExecutionManager_SetOptions(t *testing.T) {
+	manager := NewExecutionManager(DefaultExecutorOptions())
+	defer manager.Cleanup()
+
+	t.Run("should merge per-language overrides over the defaults", func(t *testing.T) {
+		defaults := DefaultExecutorOptions()
+		manager.SetOptions(defaults, map[Language]ExecutorOptions{
+			Go: {Timeout: time.Minute},
+		})
+
+		if got := manager.Options(Go); got.Timeout != time.Minute || got.MemoryMB != defaults.MemoryMB {
+			t.Errorf("Expected Go timeout override with default memory, got %+v", got)
+		}
+		if got := manager.Options(TypeScript); got != defaults {
+			t.Errorf("Expected TypeScript to use the defaults, got %+v", got)
+		}
+	})
+
+	t.Run("should merge SQL overrides field by field", func(t *testing.T) {
+		defaults := DefaultExecutorOptions()
+		defaults.SQL = SQLOptions{ContinueOnError: true, LockTimeout: time.Second, IdleInTransactionTimeout: time.Minute}
+		manager.SetOptions(defaults, map[Language]ExecutorOptions{
+			PostgreSQL: {SQL: SQLOptions{StatementTimeout: 5 * time.Second}},
+		})
+
+		want := SQLOptions{ContinueOnError: true, StatementTimeout: 5 * time.Second, LockTimeout: time.Second, IdleInTransactionTimeout: time.Minute}
+		if got := manager.Options(PostgreSQL).SQL; got != want {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+	})
+
+	t.Run("should only rebuild executors whose options changed", func(t *testing.T) {
+		defaults := DefaultExecutorOptions()
+		manager.SetOptions(defaults, nil)
+		tsExecutor := manager.GetExecutor(TypeScript)
+		goExecutor := manager.GetExecutor(Go)
+
+		manager.SetOptions(defaults, map[Language]ExecutorOptions{Go: {MemoryMB: 256}})
+
+		if manager.GetExecutor(TypeScript) != tsExecutor {
+			t.Error("Expected the TypeScript executor to be kept")
+		}
+		if manager.GetExecutor(Go) == goExecutor {
+			t.Error("Expected the Go executor to be rebuilt")
+		}
+	})
+
+	t.Run("should apply the language timeout when the run has none", func(t *testing.T) {
+		manager.SetOptions(DefaultExecutorOptions(), map[Language]ExecutorOptions{
+			TypeScript: {Timeout: 200 * time.Millisecond},
+		})
+
+		result, err := manager.Execute(ExecutionConfig{
+			Code:     `while (true) {}`,
+			Language: TypeScript,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.ExitCode != ExitCodeTimeout {
+			t.Errorf("Expected exit code %d, got %d", ExitCodeTimeout, result.ExitCode)
+		}
+	})
+}
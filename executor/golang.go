@@ -5,10 +5,12 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -26,11 +28,17 @@ func NewGoExecutor(opts ExecutorOptions) *GoExecutor {
 }
 
 func (g *GoExecutor) Execute(ctx context.Context, code string, input string) (*ExecutionResult, error) {
+	return g.ExecuteStream(ctx, code, input, nil)
+}
+
+// ExecuteStream runs the program and reports its stdout and stderr through
+// onOutput while it runs. The returned result still holds the full output.
+func (g *GoExecutor) ExecuteStream(ctx context.Context, code string, input string, onOutput OutputFunc) (*ExecutionResult, error) {
 	start := time.Now()
 
 	if ctx == nil {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel = context.WithTimeout(context.Background(), g.options.timeoutOr(10*time.Second))
 		defer cancel()
 	}
 
@@ -55,7 +63,7 @@ func (g *GoExecutor) Execute(ctx context.Context, code string, input string) (*E
 	}
 	defer os.RemoveAll(tempDir)
 
-	goCode := g.prepareGoCode(code)
+	goCode, offset := g.prepareGoCode(code)
 
 	tempFile := filepath.Join(tempDir, "main.go")
 	if err := os.WriteFile(tempFile, []byte(goCode), 0644); err != nil {
@@ -64,16 +72,44 @@ func (g *GoExecutor) Execute(ctx context.Context, code string, input string) (*E
 		return result, nil
 	}
 
-	output, stderr, err := ExecCommandContext(ctx, []string{"go", "run", tempFile}, input, tempDir)
+	var streamOutput OutputFunc
+	if onOutput != nil {
+		streamOutput = func(stream OutputStream, data string) {
+			if stream == Stderr {
+				data = g.cleanGoError(data)
+			}
+			onOutput(stream, data)
+		}
+	}
+
+	binaryFile := filepath.Join(tempDir, "main")
+	if runtime.GOOS == "windows" {
+		binaryFile += ".exe"
+	}
 
-	result.Output = strings.TrimSpace(output)
+	// Build and run as separate steps so the memory limit applies to the
+	// user's program and not to the compiler.
+	output, err := ExecCommandContextStream(ctx, []string{"go", "build", "-o", binaryFile, tempFile}, "", tempDir, CommandOptions{}, streamOutput)
+	buildFailed := err != nil
+	if !buildFailed {
+		output, err = ExecCommandContextStream(ctx, []string{binaryFile}, input, tempDir, g.commandOptions(), streamOutput)
+	}
+
+	result.Output = strings.TrimSpace(output.Stdout)
+	result.Truncation = output.Truncation
 
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
+		if wasCancelled(ctx) {
+			result.Error = "Execution cancelled"
+			result.ExitCode = ExitCodeCancelled
+		} else if ctx.Err() == context.DeadlineExceeded {
 			result.Error = "Execution timed out"
-			result.ExitCode = 124
+			result.ExitCode = ExitCodeTimeout
+		} else if errors.Is(err, ErrMemoryLimitExceeded) {
+			result.Error = fmt.Sprintf("Memory limit exceeded: the program used more than %dMB", g.options.MemoryMB)
+			result.ExitCode = ExitCodeMemoryLimitExceeded
 		} else {
-			stderrText := strings.TrimSpace(stderr)
+			stderrText := strings.TrimSpace(output.Stderr)
 			if stderrText != "" {
 				result.Error = g.cleanGoError(stderrText)
 			} else {
@@ -84,6 +120,9 @@ func (g *GoExecutor) Execute(ctx context.Context, code string, input string) (*E
 			} else {
 				result.ExitCode = 1
 			}
+			if buildFailed {
+				result.Diagnostics = parseGoDiagnostics(stderrText, offset)
+			}
 		}
 	}
 
@@ -93,24 +132,47 @@ func (g *GoExecutor) Execute(ctx context.Context, code string, input string) (*E
 	return result, nil
 }
 
-func (g *GoExecutor) prepareGoCode(code string) string {
+// commandOptions returns the limits applied to the compiled program. GOMEMLIMIT
+// makes the Go runtime collect garbage harder as it nears the memory cap.
+func (g *GoExecutor) commandOptions() CommandOptions {
+	opts := CommandOptions{
+		MaxOutputLines: g.options.MaxOutputs,
+		MaxOutputBytes: g.options.MaxOutputBytes,
+	}
+
+	if g.options.MemoryMB > 0 {
+		opts.Env = []string{fmt.Sprintf("GOMEMLIMIT=%dMiB", g.options.MemoryMB)}
+		opts.MemoryMB = g.options.MemoryMB
+	}
+
+	return opts
+}
+
+// prepareGoCode turns a snippet into a complete program and reports how the
+// user's lines were moved so compiler positions can be mapped back.
+func (g *GoExecutor) prepareGoCode(code string) (string, goCodeOffset) {
+	offset := goCodeOffset{userLines: strings.Count(code, "\n") + 1}
+
 	if strings.Contains(code, "package ") {
-		return code
+		return code, offset
 	}
 
 	hasMain := strings.Contains(code, "func main(")
 
 	if hasMain {
-		return fmt.Sprintf("package main\n\n%s", code)
+		offset.lines = 2
+		return fmt.Sprintf("package main\n\n%s", code), offset
 	}
 
+	offset.lines = 5
+	offset.indented = true
 	return fmt.Sprintf(`package main
 
 import "fmt"
 
 func main() {
 %s
-}`, g.indentCode(code))
+}`, g.indentCode(code)), offset
 }
 
 func (g *GoExecutor) indentCode(code string) string {
@@ -155,6 +217,10 @@ func (g *GoExecutor) Language() Language {
 }
 
 func (g *GoExecutor) IsAvailable() bool {
+	return goInstalled()
+}
+
+func goInstalled() bool {
 	_, err := exec.LookPath("go")
 	return err == nil
 }
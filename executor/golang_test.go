@@ -2,6 +2,9 @@ package executor
 
 import (
 	"context"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -123,6 +126,10 @@ func main() {
 	if result.ExitCode == 0 {
 		t.Error("Expected non-zero exit code for compile error")
 	}
+
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Line != 4 || result.Diagnostics[0].Column != 2 {
+		t.Errorf("Expected a diagnostic at 4:2, got %+v", result.Diagnostics)
+	}
 }
 
 func TestGoExecutor_Timeout(t *testing.T) {
@@ -162,6 +169,163 @@ func main() {
 	}
 }
 
+func TestGoExecutor_ExecuteStream(t *testing.T) {
+	if !isGoAvailable() {
+		t.Skip("Go compiler not available, skipping test")
+	}
+
+	executor := NewGoExecutor(DefaultExecutorOptions())
+
+	code := `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Println("first")
+	fmt.Fprintln(os.Stderr, "warning")
+	fmt.Println("second")
+}`
+
+	var mu sync.Mutex
+	var stdout, stderr strings.Builder
+	result, err := executor.ExecuteStream(context.Background(), code, "", func(stream OutputStream, data string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if stream == Stdout {
+			stdout.WriteString(data)
+		} else {
+			stderr.WriteString(data)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+
+	if result.Output != "first\nsecond" {
+		t.Errorf("Expected output %q, got %q", "first\nsecond", result.Output)
+	}
+
+	if stdout.String() != "first\nsecond\n" {
+		t.Errorf("Expected streamed stdout %q, got %q", "first\nsecond\n", stdout.String())
+	}
+
+	if stderr.String() != "warning\n" {
+		t.Errorf("Expected streamed stderr %q, got %q", "warning\n", stderr.String())
+	}
+}
+
+func TestGoExecutor_Cancel(t *testing.T) {
+	if !isGoAvailable() {
+		t.Skip("Go compiler not available, skipping test")
+	}
+
+	executor := NewGoExecutor(DefaultExecutorOptions())
+
+	code := `package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func main() {
+	fmt.Println("started")
+	time.Sleep(time.Minute)
+}`
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	start := time.Now()
+	result, err := executor.ExecuteStream(ctx, code, "", func(stream OutputStream, data string) {
+		if strings.Contains(data, "started") {
+			cancel(ErrExecutionCancelled)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+
+	if result.ExitCode != ExitCodeCancelled {
+		t.Errorf("Expected exit code %d, got %d", ExitCodeCancelled, result.ExitCode)
+	}
+
+	// The compiled program must be killed too, not just `go run`.
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Cancellation took too long: %v", elapsed)
+	}
+}
+
+func TestGoExecutor_MemoryLimit(t *testing.T) {
+	if !isGoAvailable() {
+		t.Skip("Go compiler not available, skipping test")
+	}
+	if runtime.GOOS != "linux" {
+		t.Skip("Hard memory limit is only enforced on Linux")
+	}
+
+	opts := DefaultExecutorOptions()
+	opts.MemoryMB = 64
+	executor := NewGoExecutor(opts)
+
+	code := `package main
+
+import "time"
+
+func main() {
+	var chunks [][]byte
+	for {
+		chunk := make([]byte, 1<<20)
+		for i := range chunk {
+			chunk[i] = 1
+		}
+		chunks = append(chunks, chunk)
+		time.Sleep(time.Millisecond)
+	}
+}`
+
+	result, err := executor.Execute(context.Background(), code, "")
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+
+	if result.ExitCode != ExitCodeMemoryLimitExceeded {
+		t.Fatalf("Expected exit code %d, got %d (%s)", ExitCodeMemoryLimitExceeded, result.ExitCode, result.Error)
+	}
+
+	if !strings.Contains(result.Error, "Memory limit exceeded") {
+		t.Errorf("Expected memory limit error, got %q", result.Error)
+	}
+}
+
+func TestGoExecutor_MaxOutputs(t *testing.T) {
+	if !isGoAvailable() {
+		t.Skip("Go compiler not available, skipping test")
+	}
+
+	opts := DefaultExecutorOptions()
+	opts.MaxOutputs = 5
+	executor := NewGoExecutor(opts)
+
+	code := `for i := 0; i < 100; i++ {
+	fmt.Println(i)
+}`
+
+	result, err := executor.Execute(context.Background(), code, "")
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+
+	if result.Output != "0\n1\n2\n3\n4" {
+		t.Errorf("Expected first five lines, got %q", result.Output)
+	}
+
+	if result.Truncation == nil || result.Truncation.DroppedLines != 95 {
+		t.Errorf("Expected 95 dropped lines, got %+v", result.Truncation)
+	}
+}
+
 func TestGoExecutor_IsAvailable(t *testing.T) {
 	executor := NewGoExecutor(DefaultExecutorOptions())
 
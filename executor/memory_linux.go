@@ -0,0 +1,72 @@
+//go:build linux
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const memoryPollInterval = 20 * time.Millisecond
+
+// watchMemory polls the resident set size of pid and calls onExceeded once it
+// goes above limitMB. The returned function stops the watcher.
+func watchMemory(pid int, limitMB int, onExceeded func()) func() {
+	if limitMB <= 0 {
+		return func() {}
+	}
+
+	limit := int64(limitMB) * 1024 * 1024
+	pageSize := int64(os.Getpagesize())
+	statmPath := fmt.Sprintf("/proc/%d/statm", pid)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(memoryPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				rss, err := readResidentPages(statmPath)
+				if err != nil {
+					// The process has exited and its /proc entry is gone.
+					return
+				}
+				if rss*pageSize > limit {
+					onExceeded()
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// readResidentPages returns the resident set size, in pages, from a /proc/<pid>/statm file.
+func readResidentPages(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected statm format: %q", data)
+	}
+
+	return strconv.ParseInt(fields[1], 10, 64)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package executor
+
+// watchMemory is a no-op outside Linux; Go programs still get a soft limit
+// through GOMEMLIMIT.
+func watchMemory(pid int, limitMB int, onExceeded func()) func() {
+	return func() {}
+}
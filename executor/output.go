@@ -0,0 +1,160 @@
+package executor
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// outputLimiter enforces a cap on the number of lines and bytes kept from a
+// program's output and counts what it drops.
+type outputLimiter struct {
+	maxLines int
+	maxBytes int
+
+	lines        int
+	bytes        int
+	droppedLines int
+	droppedBytes int
+}
+
+func newOutputLimiter(maxLines, maxBytes int) *outputLimiter {
+	return &outputLimiter{
+		maxLines: maxLines,
+		maxBytes: maxBytes,
+	}
+}
+
+// accept returns the prefix of p that still fits within the limits and records
+// the rest as dropped.
+func (l *outputLimiter) accept(p []byte) []byte {
+	keep := len(p)
+	if l.maxBytes > 0 && l.bytes+keep > l.maxBytes {
+		keep = l.maxBytes - l.bytes
+	}
+
+	if l.maxLines > 0 {
+		if l.lines >= l.maxLines {
+			keep = 0
+		}
+		lines := l.lines
+		for i := 0; i < keep; i++ {
+			if p[i] == '\n' {
+				lines++
+				if lines >= l.maxLines {
+					keep = i + 1
+					break
+				}
+			}
+		}
+	}
+
+	kept, dropped := p[:keep], p[keep:]
+	l.bytes += len(kept)
+	l.lines += bytes.Count(kept, []byte{'\n'})
+
+	if len(dropped) > 0 {
+		l.droppedBytes += len(dropped)
+		l.droppedLines += bytes.Count(dropped, []byte{'\n'})
+		if dropped[len(dropped)-1] != '\n' {
+			l.droppedLines++
+		}
+	}
+
+	return kept
+}
+
+// acceptLine applies the limits to a single line of output, as produced by a
+// console call, and reports whether any of it was kept.
+func (l *outputLimiter) acceptLine(line string) (string, bool) {
+	kept := l.accept([]byte(line + "\n"))
+	if len(kept) == 0 {
+		return "", false
+	}
+	return strings.TrimSuffix(string(kept), "\n"), true
+}
+
+// truncation describes the dropped output, or returns nil when nothing was dropped.
+func (l *outputLimiter) truncation() *OutputTruncation {
+	if l.droppedBytes == 0 {
+		return nil
+	}
+	return &OutputTruncation{
+		DroppedLines: l.droppedLines,
+		DroppedBytes: l.droppedBytes,
+	}
+}
+
+// outputWriter buffers process output and forwards every write to onOutput.
+// Writers sharing the same mutex and limiter deliver their chunks one at a
+// time and share a single output budget.
+type outputWriter struct {
+	stream   OutputStream
+	onOutput OutputFunc
+	limiter  *outputLimiter
+	mu       *sync.Mutex
+	buf      strings.Builder
+}
+
+func newOutputWriters(limiter *outputLimiter, onOutput OutputFunc) (*outputWriter, *outputWriter) {
+	mu := &sync.Mutex{}
+	return &outputWriter{stream: Stdout, onOutput: onOutput, limiter: limiter, mu: mu},
+		&outputWriter{stream: Stderr, onOutput: onOutput, limiter: limiter, mu: mu}
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	kept := w.limiter.accept(p)
+	if len(kept) == 0 {
+		return len(p), nil
+	}
+
+	w.buf.Write(kept)
+	if w.onOutput != nil {
+		w.onOutput(w.stream, string(kept))
+	}
+	return len(p), nil
+}
+
+func (w *outputWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// consoleOutput collects the console calls made by a script, applying the
+// output limits and forwarding kept lines to onOutput.
+type consoleOutput struct {
+	outputs  []string
+	errors   []string
+	limiter  *outputLimiter
+	onOutput OutputFunc
+}
+
+func newConsoleOutput(opts ExecutorOptions, onOutput OutputFunc) *consoleOutput {
+	return &consoleOutput{
+		outputs:  make([]string, 0, 10),
+		errors:   make([]string, 0, 5),
+		limiter:  newOutputLimiter(opts.MaxOutputs, opts.MaxOutputBytes),
+		onOutput: onOutput,
+	}
+}
+
+func (c *consoleOutput) write(stream OutputStream, line string) {
+	line, ok := c.limiter.acceptLine(line)
+	if !ok {
+		return
+	}
+
+	if stream == Stderr {
+		c.errors = append(c.errors, line)
+	} else {
+		c.outputs = append(c.outputs, line)
+	}
+
+	if c.onOutput != nil {
+		c.onOutput(stream, line+"\n")
+	}
+}
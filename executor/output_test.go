@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"testing"
+)
+
+func TestOutputLimiter(t *testing.T) {
+	testCases := []struct {
+		name         string
+		maxLines     int
+		maxBytes     int
+		writes       []string
+		expected     string
+		droppedLines int
+		droppedBytes int
+	}{
+		{
+			name:     "unlimited",
+			writes:   []string{"a\n", "b\n", "c"},
+			expected: "a\nb\nc",
+		},
+		{
+			name:         "line limit",
+			maxLines:     2,
+			writes:       []string{"a\nb\nc\n", "d\n"},
+			expected:     "a\nb\n",
+			droppedLines: 2,
+			droppedBytes: 4,
+		},
+		{
+			name:         "byte limit",
+			maxBytes:     5,
+			writes:       []string{"abc\n", "defg\n"},
+			expected:     "abc\nd",
+			droppedLines: 1,
+			droppedBytes: 4,
+		},
+		{
+			name:         "partial trailing line",
+			maxLines:     1,
+			writes:       []string{"a\nb"},
+			expected:     "a\n",
+			droppedLines: 1,
+			droppedBytes: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := newOutputLimiter(tc.maxLines, tc.maxBytes)
+
+			var kept string
+			for _, w := range tc.writes {
+				kept += string(limiter.accept([]byte(w)))
+			}
+
+			if kept != tc.expected {
+				t.Errorf("Expected kept output %q, got %q", tc.expected, kept)
+			}
+
+			truncation := limiter.truncation()
+			if tc.droppedBytes == 0 {
+				if truncation != nil {
+					t.Errorf("Expected no truncation, got %+v", truncation)
+				}
+				return
+			}
+
+			if truncation == nil {
+				t.Fatal("Expected truncation, got nil")
+			}
+			if truncation.DroppedLines != tc.droppedLines || truncation.DroppedBytes != tc.droppedBytes {
+				t.Errorf("Expected %d lines/%d bytes dropped, got %+v", tc.droppedLines, tc.droppedBytes, truncation)
+			}
+		})
+	}
+}
+
+func TestConsoleOutput_Write(t *testing.T) {
+	opts := DefaultExecutorOptions()
+	opts.MaxOutputs = 2
+
+	var streamed []string
+	out := newConsoleOutput(opts, func(stream OutputStream, data string) {
+		streamed = append(streamed, data)
+	})
+
+	out.write(Stdout, "one")
+	out.write(Stderr, "two")
+	out.write(Stdout, "three")
+
+	if len(out.outputs) != 1 || out.outputs[0] != "one" {
+		t.Errorf("Expected outputs [one], got %v", out.outputs)
+	}
+	if len(out.errors) != 1 || out.errors[0] != "two" {
+		t.Errorf("Expected errors [two], got %v", out.errors)
+	}
+	if len(streamed) != 2 {
+		t.Errorf("Expected 2 streamed chunks, got %v", streamed)
+	}
+
+	truncation := out.limiter.truncation()
+	if truncation == nil || truncation.DroppedLines != 1 {
+		t.Errorf("Expected one dropped line, got %+v", truncation)
+	}
+}
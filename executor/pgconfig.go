@@ -0,0 +1,370 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgservicefile"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// connString builds the libpq connection string for the config. A
+// ConnectionString is used as given; otherwise keyword/value pairs are built
+// from the fields that are set, leaving the rest to the service file and the
+// PG* environment variables, as libpq does.
+//
+// TLS fields are added to a ConnectionString unless it already sets them.
+func (c *PostgreSQLConfig) connString() string {
+	if c.ConnectionString != "" {
+		return c.withTLSSettings(strings.TrimSpace(c.ConnectionString))
+	}
+
+	var pairs []string
+	add := func(key, value string) {
+		if value != "" {
+			pairs = append(pairs, key+"="+quoteConnValue(value))
+		}
+	}
+
+	add("service", c.Service)
+	add("host", c.Host)
+	if c.Port > 0 {
+		add("port", strconv.Itoa(c.Port))
+	}
+	add("dbname", c.Database)
+	add("user", c.Username)
+	add("password", c.Password)
+	add("sslmode", c.SSLMode)
+	for _, setting := range c.tlsSettings() {
+		add(setting[0], setting[1])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// tlsSettings lists the libpq TLS keywords set in the config.
+func (c *PostgreSQLConfig) tlsSettings() [][2]string {
+	var settings [][2]string
+	for _, setting := range [][2]string{
+		{"sslrootcert", c.SSLRootCert},
+		{"sslcert", c.SSLCert},
+		{"sslkey", c.SSLKey},
+		{"sslpassword", c.SSLPassword},
+	} {
+		if setting[1] != "" {
+			settings = append(settings, setting)
+		}
+	}
+	return settings
+}
+
+// withTLSSettings adds the config's TLS settings to connString where it does
+// not set them itself.
+func (c *PostgreSQLConfig) withTLSSettings(connString string) string {
+	settings := c.tlsSettings()
+	if len(settings) == 0 {
+		return connString
+	}
+
+	existing, err := parseConnSettings(connString)
+	if err != nil {
+		// Leave it to the parser to report the problem.
+		return connString
+	}
+
+	if strings.HasPrefix(connString, "postgres://") || strings.HasPrefix(connString, "postgresql://") {
+		u, err := url.Parse(connString)
+		if err != nil {
+			return connString
+		}
+		query := u.Query()
+		for _, setting := range settings {
+			if _, ok := existing[setting[0]]; !ok {
+				query.Set(setting[0], setting[1])
+			}
+		}
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+
+	for _, setting := range settings {
+		if _, ok := existing[setting[0]]; !ok {
+			connString += " " + setting[0] + "=" + quoteConnValue(setting[1])
+		}
+	}
+	return connString
+}
+
+// quoteConnValue quotes a keyword/value connection string value when needed.
+func quoteConnValue(value string) string {
+	if !strings.ContainsAny(value, " \t\n\r\v\f'\\") {
+		return value
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	return "'" + escaped + "'"
+}
+
+// poolConfig parses the config into a pool configuration, resolving the
+// service file, environment variables and password file like libpq. The
+// Password field is used when the connection string does not contain one.
+func (c *PostgreSQLConfig) poolConfig() (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(c.connString())
+	if err != nil {
+		return nil, connConfigError(err)
+	}
+
+	if c.ConnectionString != "" && c.Password != "" {
+		if _, ok := c.explicitSettings()["password"]; !ok {
+			poolConfig.ConnConfig.Password = c.Password
+		}
+	}
+
+	if c.SSLServerName != "" {
+		setTLSServerName(&poolConfig.ConnConfig.Config, c.SSLServerName)
+	}
+	return poolConfig, nil
+}
+
+// connConfigError rewords parse errors without repeating the connection
+// string, which may contain a password.
+func connConfigError(err error) error {
+	var parseErr *pgconn.ParseConfigError
+	if errors.As(err, &parseErr) {
+		if inner := parseErr.Unwrap(); inner != nil {
+			err = inner
+		}
+	}
+	if explained := explainTLSError(err); explained != err {
+		return fmt.Errorf("invalid connection settings: %w", explained)
+	}
+	return fmt.Errorf("invalid connection settings: %v", err)
+}
+
+// ResolvePostgreSQLConfig resolves config the way a connection would: a
+// connection URI or service is expanded and PG* environment variables fill in
+// missing values. The result has the individual fields set, so that they can be
+// shown to the user. Passwords are only returned when the user supplied them,
+// never from a password file.
+func ResolvePostgreSQLConfig(config *PostgreSQLConfig) (*PostgreSQLConfig, error) {
+	if config == nil {
+		return nil, fmt.Errorf("no configuration provided")
+	}
+
+	poolConfig, err := config.poolConfig()
+	if err != nil {
+		return nil, err
+	}
+	connConfig := poolConfig.ConnConfig
+
+	explicit := config.explicitSettings()
+	resolved := &PostgreSQLConfig{
+		Host:             connConfig.Host,
+		Port:             int(connConfig.Port),
+		Database:         connConfig.Database,
+		Username:         connConfig.User,
+		Password:         config.Password,
+		SSLMode:          config.resolvedSetting("sslmode", "PGSSLMODE"),
+		Service:          config.resolvedSetting("service", "PGSERVICE"),
+		ConnectionString: config.ConnectionString,
+		SSLRootCert:      config.resolvedSetting("sslrootcert", "PGSSLROOTCERT"),
+		SSLCert:          config.resolvedSetting("sslcert", "PGSSLCERT"),
+		SSLKey:           config.resolvedSetting("sslkey", "PGSSLKEY"),
+		SSLPassword:      explicit["sslpassword"],
+		SSLServerName:    config.SSLServerName,
+		SSHTunnel:        config.SSHTunnel,
+		ReadOnly:         config.ReadOnly,
+		SafeMode:         config.SafeMode,
+	}
+	if password, ok := explicit["password"]; ok {
+		resolved.Password = password
+	}
+	if resolved.SSLMode == "" {
+		resolved.SSLMode = "prefer"
+	}
+	return resolved, nil
+}
+
+// explicitSettings returns the settings spelled out in the connection string
+// or fields, keyed by libpq keyword.
+func (c *PostgreSQLConfig) explicitSettings() map[string]string {
+	settings, err := parseConnSettings(c.connString())
+	if err != nil {
+		return map[string]string{}
+	}
+	return settings
+}
+
+// resolvedSetting finds key with libpq's precedence: the connection string,
+// then the service file, then the environment variable.
+func (c *PostgreSQLConfig) resolvedSetting(key, envVar string) string {
+	explicit := c.explicitSettings()
+	if value, ok := explicit[key]; ok {
+		return value
+	}
+
+	service := explicit["service"]
+	if service == "" {
+		service = os.Getenv("PGSERVICE")
+	}
+	if service != "" && key != "service" {
+		if value, ok := serviceSetting(explicit["servicefile"], service, key); ok {
+			return value
+		}
+	}
+
+	return os.Getenv(envVar)
+}
+
+// serviceSetting looks key up in the named service of the service file.
+func serviceSetting(path, service, key string) (string, bool) {
+	if path == "" {
+		path = os.Getenv("PGSERVICEFILE")
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		path = filepath.Join(home, ".pg_service.conf")
+	}
+
+	file, err := pgservicefile.ReadServicefile(path)
+	if err != nil {
+		return "", false
+	}
+	entry, err := file.GetService(service)
+	if err != nil {
+		return "", false
+	}
+	value, ok := entry.Settings[key]
+	return value, ok
+}
+
+// parseConnSettings splits a URI or keyword/value connection string into its
+// settings, keyed by libpq keyword.
+func parseConnSettings(connString string) (map[string]string, error) {
+	settings := make(map[string]string)
+
+	if strings.HasPrefix(connString, "postgres://") || strings.HasPrefix(connString, "postgresql://") {
+		u, err := url.Parse(connString)
+		if err != nil {
+			return nil, err
+		}
+		if u.User != nil {
+			settings["user"] = u.User.Username()
+			if password, ok := u.User.Password(); ok {
+				settings["password"] = password
+			}
+		}
+		if database := strings.TrimLeft(u.Path, "/"); database != "" {
+			settings["dbname"] = database
+		}
+		for key, values := range u.Query() {
+			settings[key] = values[0]
+		}
+		return settings, nil
+	}
+
+	s := strings.TrimSpace(connString)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("missing '=' after %q", s)
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = strings.TrimLeft(s[eq+1:], " \t\n\r\v\f")
+
+		var value strings.Builder
+		quoted := strings.HasPrefix(s, "'")
+		if quoted {
+			s = s[1:]
+		}
+
+		i := 0
+		for ; i < len(s); i++ {
+			ch := s[i]
+			if quoted && ch == '\'' {
+				break
+			}
+			if !quoted && strings.IndexByte(" \t\n\r\v\f", ch) >= 0 {
+				break
+			}
+			if ch == '\\' && i+1 < len(s) {
+				i++
+				ch = s[i]
+			}
+			value.WriteByte(ch)
+		}
+		if quoted && i == len(s) {
+			return nil, fmt.Errorf("unterminated quoted value for %s", key)
+		}
+
+		settings[key] = value.String()
+		if i < len(s) {
+			i++
+		}
+		s = strings.TrimLeft(s[i:], " \t\n\r\v\f")
+	}
+	return settings, nil
+}
+
+// connSecrets are the connection string settings that hold secrets.
+var connSecrets = []string{"password", "sslpassword"}
+
+// ConnStringWithoutSecrets returns connString with its password and key
+// passphrase removed, so that it can be stored. URIs keep their form;
+// keyword/value strings are rebuilt with the keywords sorted. A string that
+// cannot be parsed yields "", since a secret in it could not be found.
+func ConnStringWithoutSecrets(connString string) string {
+	if connString == "" {
+		return ""
+	}
+
+	if strings.HasPrefix(connString, "postgres://") || strings.HasPrefix(connString, "postgresql://") {
+		u, err := url.Parse(connString)
+		if err != nil {
+			return ""
+		}
+		if u.User != nil {
+			u.User = url.User(u.User.Username())
+		}
+		query := u.Query()
+		for _, key := range connSecrets {
+			query.Del(key)
+		}
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+
+	settings, err := parseConnSettings(connString)
+	if err != nil {
+		return ""
+	}
+	for _, key := range connSecrets {
+		delete(settings, key)
+	}
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		value := quoteConnValue(settings[key])
+		if value == "" {
+			value = "''"
+		}
+		pairs[i] = key + "=" + value
+	}
+	return strings.Join(pairs, " ")
+}
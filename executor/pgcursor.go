@@ -0,0 +1,166 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// sqlCursor is a result whose rows are still being read from the server, a
+// page at a time. It holds the connection its statement runs on until all
+// rows are read or it is closed.
+type sqlCursor struct {
+	handle      string
+	conn        *pgxpool.Conn
+	release     func() // Gives back conn; set when the run hands conn over to the cursor
+	statement   int    // Index of the statement in its run
+	multiReader *pgconn.MultiResultReader
+	reader      *pgconn.ResultReader
+	cancel      context.CancelFunc // Cancels the statement
+	next        []interface{}      // Row read ahead to tell whether more rows follow
+	fetched     int64              // Rows returned so far
+	commandTag  string             // Set once all rows are read
+	done        bool               // All rows are read
+}
+
+// readPage reads up to count rows of cursor. Once the last row is read, the
+// cursor is done and its command tag is set.
+func (p *PostgreSQLExecutor) readPage(cursor *sqlCursor, count int) ([][]interface{}, error) {
+	typeMap := cursor.conn.Conn().TypeMap()
+	fieldDescriptions := cursor.reader.FieldDescriptions()
+
+	rows := make([][]interface{}, 0, min(count, 1024))
+	if cursor.next != nil {
+		rows = append(rows, cursor.next)
+		cursor.next = nil
+	}
+	for cursor.reader.NextRow() {
+		row, err := p.decodeRow(typeMap, fieldDescriptions, cursor.reader.Values())
+		if err != nil {
+			return rows, err
+		}
+		if len(rows) == count {
+			cursor.next = row
+			break
+		}
+		rows = append(rows, row)
+	}
+	cursor.fetched += int64(len(rows))
+
+	if cursor.next == nil {
+		commandTag, err := cursor.reader.Close()
+		cursor.commandTag = commandTag.String()
+		cursor.done = true
+		return rows, err
+	}
+	return rows, nil
+}
+
+// stop stops the statement if rows are left, keeping the connection.
+func (cursor *sqlCursor) stop() error {
+	if !cursor.done {
+		// Ask the server to stop sending rows rather than reading them all.
+		cursor.cancel()
+	}
+	err := cursor.multiReader.Close()
+	cursor.cancel()
+	if cursor.done {
+		return err
+	}
+	return nil
+}
+
+// close stops the statement and gives back its connection.
+func (cursor *sqlCursor) close() error {
+	err := cursor.stop()
+	cursor.release()
+	return err
+}
+
+// closeCursor closes the open result, if any. The caller must hold p.mu.
+func (p *PostgreSQLExecutor) closeCursor() {
+	if p.cursor == nil {
+		return
+	}
+	p.cursor.close()
+	p.cursor = nil
+	log.Println("PostgreSQL Executor: Closed open result")
+}
+
+// FetchRows returns up to count more rows of the result with the given
+// handle, as set in SQLQueryResult.ResultHandle. A count of zero fetches a
+// page of ExecutorOptions.MaxOutputs rows. The result is closed once its last
+// row is fetched, when another query runs, and when the connection changes.
+func (p *PostgreSQLExecutor) FetchRows(ctx context.Context, handle string, count int) (*SQLPage, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cursor := p.cursor
+	if cursor == nil || cursor.handle != handle {
+		return nil, fmt.Errorf("result %s is no longer open; run the query again", handle)
+	}
+	if count <= 0 {
+		count = max(p.options.MaxOutputs, 1)
+	}
+
+	// A fetch that runs out of time or is cancelled also stops the statement.
+	stop := context.AfterFunc(ctx, cursor.cancel)
+	p.notices.start()
+	p.notices.setStatement(cursor.statement)
+	rows, err := p.readPage(cursor, count)
+	collected := p.notices.stop()
+	if !stop() && err == nil {
+		err = ctx.Err()
+	}
+
+	page := &SQLPage{
+		Handle:      handle,
+		Rows:        rows,
+		HasMore:     err == nil && !cursor.done,
+		CommandTag:  cursor.commandTag,
+		RowsFetched: cursor.fetched,
+		Notices:     sqlNotices(collected, "", nil, nil),
+	}
+	if err == nil && cursor.done {
+		err = cursor.close()
+		p.cursor = nil
+	}
+	if err != nil {
+		if p.cursor != nil {
+			p.closeCursor()
+		}
+		page.HasMore = false
+		return page, fmt.Errorf("failed to fetch rows: %w", err)
+	}
+	return page, nil
+}
+
+// CloseResult closes the result with the given handle if it is still open,
+// stopping its statement on the server.
+func (p *PostgreSQLExecutor) CloseResult(handle string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cursor != nil && p.cursor.handle == handle {
+		p.closeCursor()
+	}
+}
+
+// lookupColumnDetailsInPool is lookupColumnDetails for a result whose
+// connection is still busy sending rows; it reads the catalog on another
+// connection of the pool.
+func (p *PostgreSQLExecutor) lookupColumnDetailsInPool(ctx context.Context, columns []SQLColumn) {
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("PostgreSQL Executor: Failed to look up column details: %v", err)
+		return
+	}
+	defer conn.Release()
+	p.lookupColumnDetails(ctx, conn.Conn(), columns)
+}
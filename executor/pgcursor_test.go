@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// seriesHandler answers "SELECT n FROM series" with 25 rows.
+func seriesHandler(query string) []standInResult {
+	if query != "SELECT n FROM series" {
+		return []standInResult{{Tag: "SELECT 0", Columns: textColumns("?column?")}}
+	}
+	rows := make([][]*string, 25)
+	for i := range rows {
+		rows[i] = textRow(strconv.Itoa(i + 1))
+	}
+	return []standInResult{{Columns: []standInColumn{{Name: "n", OID: 23}}, Rows: rows, Tag: "SELECT 25"}}
+}
+
+func TestPostgreSQLExecutor_PagedResults(t *testing.T) {
+	opts := DefaultExecutorOptions()
+	opts.MaxOutputs = 10
+
+	t.Run("should return the first page and fetch the rest by handle", func(t *testing.T) {
+		executor := newStandInExecutor(t, opts, seriesHandler)
+
+		result, err := executor.Execute(context.Background(), "SELECT n FROM series", "")
+		if err != nil || result.ExitCode != 0 {
+			t.Fatalf("Expected the query to succeed, got %v %q", err, result.Error)
+		}
+		first := result.SQLResult
+		if len(first.Rows) != 10 || !first.HasMore || first.ResultHandle == "" || first.Truncated {
+			t.Fatalf("Expected a first page of 10 rows with more to fetch, got %d rows, hasMore=%v handle=%q truncated=%v",
+				len(first.Rows), first.HasMore, first.ResultHandle, first.Truncated)
+		}
+		if !strings.Contains(result.Output, "more rows are available") {
+			t.Errorf("Expected the output to mention the remaining rows, got:\n%s", result.Output)
+		}
+
+		page, err := executor.FetchRows(context.Background(), first.ResultHandle, 10)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(page.Rows) != 10 || page.Rows[0][0] != int32(11) || !page.HasMore || page.RowsFetched != 20 {
+			t.Fatalf("Expected rows 11 to 20, got %+v", page)
+		}
+
+		page, err = executor.FetchRows(context.Background(), first.ResultHandle, 0)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(page.Rows) != 5 || page.HasMore || page.CommandTag != "SELECT 25" || page.RowsFetched != 25 {
+			t.Fatalf("Expected the last 5 rows, got %+v", page)
+		}
+
+		if _, err := executor.FetchRows(context.Background(), first.ResultHandle, 10); err == nil {
+			t.Fatal("Expected an error once all rows were fetched")
+		}
+	})
+
+	t.Run("should close the result when another query runs", func(t *testing.T) {
+		executor := newStandInExecutor(t, opts, seriesHandler)
+
+		result, _ := executor.Execute(context.Background(), "SELECT n FROM series", "")
+		handle := result.SQLResult.ResultHandle
+		if handle == "" {
+			t.Fatal("Expected an open result")
+		}
+
+		next, _ := executor.Execute(context.Background(), "SELECT 1", "")
+		if next.ExitCode != 0 {
+			t.Fatalf("Expected the next query to succeed, got %q", next.Error)
+		}
+		if _, err := executor.FetchRows(context.Background(), handle, 10); err == nil || !strings.Contains(err.Error(), "no longer open") {
+			t.Fatalf("Expected the result to be closed, got %v", err)
+		}
+	})
+
+	t.Run("should close the result on disconnect", func(t *testing.T) {
+		executor := newStandInExecutor(t, opts, seriesHandler)
+
+		result, _ := executor.Execute(context.Background(), "SELECT n FROM series", "")
+		executor.CloseResult("unknown")
+		if executor.cursor == nil {
+			t.Fatal("Expected an unknown handle to leave the result open")
+		}
+
+		executor.Cleanup()
+		if executor.cursor != nil {
+			t.Fatal("Expected the result to be closed")
+		}
+		if _, err := executor.FetchRows(context.Background(), result.SQLResult.ResultHandle, 10); err == nil {
+			t.Fatal("Expected an error after disconnecting")
+		}
+	})
+
+	t.Run("should read results that fit on one page completely", func(t *testing.T) {
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), seriesHandler)
+
+		result, _ := executor.Execute(context.Background(), "SELECT n FROM series", "")
+		if len(result.SQLResult.Rows) != 25 || result.SQLResult.HasMore || result.SQLResult.CommandTag != "SELECT 25" {
+			t.Fatalf("Expected all 25 rows, got %+v", result.SQLResult)
+		}
+		if executor.cursor != nil {
+			t.Fatal("Expected no open result")
+		}
+	})
+}
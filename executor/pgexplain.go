@@ -0,0 +1,386 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// misestimateFactor is how far apart estimated and actual rows of a plan node
+// must be for it to be flagged as misestimated.
+const misestimateFactor = 10
+
+// planNodeKeys are the EXPLAIN properties PlanNode has fields for; the others
+// go into PlanNode.Details.
+var planNodeKeys = map[string]bool{
+	"Node Type": true, "Parent Relationship": true, "Relation Name": true, "Schema": true,
+	"Alias": true, "Index Name": true, "Join Type": true, "Startup Cost": true,
+	"Total Cost": true, "Plan Rows": true, "Plan Width": true, "Actual Startup Time": true,
+	"Actual Total Time": true, "Actual Rows": true, "Actual Loops": true,
+	"Shared Hit Blocks": true, "Shared Read Blocks": true, "Shared Dirtied Blocks": true,
+	"Shared Written Blocks": true, "Local Hit Blocks": true, "Local Read Blocks": true,
+	"Temp Read Blocks": true, "Temp Written Blocks": true, "Plans": true,
+}
+
+// Explain explains a single SQL statement instead of running it, returning
+// its plan in ExecutionResult.Plan and as text in ExecutionResult.Output.
+// With ExplainAnalyze the statement runs, with buffer usage reported, inside
+// a transaction that is rolled back, or a savepoint if the session has a
+// transaction open, so that writes leave nothing behind.
+func (p *PostgreSQLExecutor) Explain(ctx context.Context, code string, mode ExplainMode) (*ExecutionResult, error) {
+	start := time.Now()
+
+	if mode != ExplainPlan && mode != ExplainAnalyze {
+		return nil, fmt.Errorf("unknown explain mode: %q", mode)
+	}
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), p.options.timeoutOr(30*time.Second))
+		defer cancel()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := &ExecutionResult{
+		Language: PostgreSQL,
+	}
+
+	if !p.isAvailableInternal() {
+		result.Error = "PostgreSQL connection is not configured or unavailable"
+		result.ExitCode = ExitCodePostgresNotAvailable
+		return result, nil
+	}
+
+	p.closeCursor()
+
+	sqlCode, sqlOrigins := p.prepareSQLCodeWithOrigins(code)
+	statements := splitSQLStatements(sqlCode)
+	switch {
+	case len(statements) == 0:
+		result.Error = "No SQL query provided"
+	case len(statements) > 1:
+		result.Error = fmt.Sprintf("Explain mode needs a single statement, got %d", len(statements))
+	case leadingKeyword(statements[0].Tokens) == "EXPLAIN":
+		result.Error = "The statement is already an EXPLAIN; run it without explain mode"
+	}
+	if result.Error != "" {
+		result.ExitCode = ExitCodePostgresQueryError
+		return result, nil
+	}
+	statement := statements[0]
+	// Analyzed statements run, but are always rolled back, so only
+	// read-only mode applies.
+	if mode == ExplainAnalyze && p.config.ReadOnly {
+		if findings := readOnlyViolations(statements); len(findings) > 0 {
+			setGuarded(result, findings, true, sqlCode, statements, sqlOrigins)
+			return result, nil
+		}
+	}
+
+	if err := p.ensureConnection(ctx); err != nil {
+		result.Error = fmt.Sprintf("Failed to connect to PostgreSQL: %v", err)
+		result.ExitCode = ExitCodePostgresConnFailed
+		return result, nil
+	}
+
+	prefix := "EXPLAIN (FORMAT JSON) "
+	if mode == ExplainAnalyze {
+		prefix = "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) "
+	}
+	p.notices.start()
+	plan, txStatus, err := p.explainStatement(ctx, prefix+statement.Text, mode == ExplainAnalyze)
+	collected := p.notices.stop()
+	for _, c := range collected {
+		// Positions count from the start of the EXPLAIN, so only the statement's line is known.
+		c.notice.Position = 0
+	}
+	result.Notices = sqlNotices(collected, sqlCode, statements, sqlOrigins)
+	if p.sessionMode {
+		result.Transaction = txStatus
+	}
+
+	result.Duration = time.Since(start)
+	result.DurationString = formatDuration(result.Duration)
+
+	if err != nil {
+		// Positions in errors count from the start of the EXPLAIN.
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && int(pgErr.Position) > len(prefix) {
+			shifted := *pgErr
+			shifted.Position -= int32(len(prefix))
+			if diagnostic := postgresDiagnostic(&shifted, sqlCode, statement.Offset, sqlOrigins); diagnostic != nil {
+				result.Diagnostics = append(result.Diagnostics, *diagnostic)
+			}
+		}
+		setQueryError(ctx, result, err, p.limits)
+		return result, nil
+	}
+
+	plan.Statement = statement.Text
+	result.Plan = plan
+	result.Output = formatNotices(result.Notices) + formatPlan(plan)
+	result.ExitCode = 0
+	return result, nil
+}
+
+// explainStatement runs an EXPLAIN in FORMAT JSON and parses its plan. An
+// EXPLAIN ANALYZE runs in a transaction that is rolled back.
+func (p *PostgreSQLExecutor) explainStatement(ctx context.Context, explain string, analyze bool) (*QueryPlan, TransactionStatus, error) {
+	conn, release, err := p.acquireConn(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+
+	status := transactionStatus(conn.Conn().PgConn())
+	if analyze {
+		rollback, err := beginSandbox(ctx, conn)
+		if err != nil {
+			return nil, status, err
+		}
+		defer rollback()
+	}
+
+	results, err := conn.Conn().PgConn().Exec(ctx, explain).ReadAll()
+	if err != nil {
+		return nil, status, err
+	}
+	if len(results) == 0 || len(results[0].Rows) == 0 || len(results[0].Rows[0]) == 0 {
+		return nil, status, fmt.Errorf("EXPLAIN returned no plan")
+	}
+
+	plan, err := parsePlan(results[0].Rows[0][0])
+	if err != nil {
+		return nil, status, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	return plan, status, nil
+}
+
+// parsePlan parses the output of EXPLAIN (FORMAT JSON) and flags the nodes
+// worth a closer look.
+func parsePlan(raw []byte) (*QueryPlan, error) {
+	var explained []map[string]interface{}
+	if err := json.Unmarshal(raw, &explained); err != nil {
+		return nil, err
+	}
+	if len(explained) == 0 {
+		return nil, fmt.Errorf("no plan in EXPLAIN output")
+	}
+	root, ok := explained[0]["Plan"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no plan in EXPLAIN output")
+	}
+
+	plan := &QueryPlan{
+		Root:          planNode(root),
+		PlanningTime:  planNumber(explained[0], "Planning Time"),
+		ExecutionTime: planNumber(explained[0], "Execution Time"),
+		Raw:           append(json.RawMessage(nil), raw...),
+	}
+	_, plan.Analyzed = explained[0]["Execution Time"]
+	return plan, nil
+}
+
+// planNode converts a node of EXPLAIN's JSON output and its children.
+func planNode(raw map[string]interface{}) PlanNode {
+	node := PlanNode{
+		NodeType:           planString(raw, "Node Type"),
+		ParentRelationship: planString(raw, "Parent Relationship"),
+		RelationName:       planString(raw, "Relation Name"),
+		Schema:             planString(raw, "Schema"),
+		Alias:              planString(raw, "Alias"),
+		IndexName:          planString(raw, "Index Name"),
+		JoinType:           planString(raw, "Join Type"),
+		StartupCost:        planNumber(raw, "Startup Cost"),
+		TotalCost:          planNumber(raw, "Total Cost"),
+		PlanRows:           planNumber(raw, "Plan Rows"),
+		PlanWidth:          int(planNumber(raw, "Plan Width")),
+	}
+
+	if _, ok := raw["Actual Loops"]; ok {
+		node.Actual = &PlanActual{
+			StartupTime: planNumber(raw, "Actual Startup Time"),
+			TotalTime:   planNumber(raw, "Actual Total Time"),
+			Rows:        planNumber(raw, "Actual Rows"),
+			Loops:       planNumber(raw, "Actual Loops"),
+		}
+	}
+	if _, ok := raw["Shared Hit Blocks"]; ok {
+		node.Buffers = &PlanBuffers{
+			SharedHit:     int64(planNumber(raw, "Shared Hit Blocks")),
+			SharedRead:    int64(planNumber(raw, "Shared Read Blocks")),
+			SharedDirtied: int64(planNumber(raw, "Shared Dirtied Blocks")),
+			SharedWritten: int64(planNumber(raw, "Shared Written Blocks")),
+			LocalHit:      int64(planNumber(raw, "Local Hit Blocks")),
+			LocalRead:     int64(planNumber(raw, "Local Read Blocks")),
+			TempRead:      int64(planNumber(raw, "Temp Read Blocks")),
+			TempWritten:   int64(planNumber(raw, "Temp Written Blocks")),
+		}
+	}
+
+	for key, value := range raw {
+		if !planNodeKeys[key] {
+			if node.Details == nil {
+				node.Details = make(map[string]interface{})
+			}
+			node.Details[key] = value
+		}
+	}
+
+	if children, ok := raw["Plans"].([]interface{}); ok {
+		for _, child := range children {
+			if child, ok := child.(map[string]interface{}); ok {
+				node.Children = append(node.Children, planNode(child))
+			}
+		}
+	}
+
+	node.Flags = planFlags(&node)
+	return node
+}
+
+// planFlags returns the flags of node: a misestimate when the rows it
+// returned are misestimateFactor or more times off the estimate, and a
+// sequential scan when it reads a whole table.
+func planFlags(node *PlanNode) []PlanFlag {
+	var flags []PlanFlag
+
+	if node.Actual != nil && node.Actual.Loops > 0 {
+		estimated, actual := math.Max(node.PlanRows, 1), math.Max(node.Actual.Rows, 1)
+		if actual/estimated >= misestimateFactor || estimated/actual >= misestimateFactor {
+			flags = append(flags, PlanFlag{
+				Kind: PlanFlagMisestimate,
+				Message: fmt.Sprintf("Estimated %s rows but got %s; the table statistics may be out of date (run ANALYZE)",
+					formatPlanNumber(node.PlanRows), formatPlanNumber(node.Actual.Rows)),
+			})
+		}
+	}
+
+	if node.NodeType == "Seq Scan" {
+		message := fmt.Sprintf("Sequential scan reads all of %s", node.RelationName)
+		if removed := planNumber(node.Details, "Rows Removed by Filter"); removed > 0 {
+			message += fmt.Sprintf(" and its filter removes %s rows per loop; an index on the filtered columns may help", formatPlanNumber(removed))
+		} else if _, filtered := node.Details["Filter"]; filtered {
+			message += "; an index on the filtered columns may help"
+		}
+		flags = append(flags, PlanFlag{Kind: PlanFlagSeqScan, Message: message})
+	}
+
+	return flags
+}
+
+func planString(raw map[string]interface{}, key string) string {
+	value, _ := raw[key].(string)
+	return value
+}
+
+func planNumber(raw map[string]interface{}, key string) float64 {
+	value, _ := raw[key].(float64)
+	return value
+}
+
+// formatPlan renders plan as text, in the layout of EXPLAIN's text format,
+// with flags as warnings under their nodes.
+func formatPlan(plan *QueryPlan) string {
+	var output strings.Builder
+	formatPlanNode(&output, &plan.Root, 0)
+	if plan.PlanningTime > 0 {
+		fmt.Fprintf(&output, "Planning Time: %.3f ms\n", plan.PlanningTime)
+	}
+	if plan.Analyzed {
+		fmt.Fprintf(&output, "Execution Time: %.3f ms\n", plan.ExecutionTime)
+	}
+	return output.String()
+}
+
+func formatPlanNode(output *strings.Builder, node *PlanNode, depth int) {
+	detailIndent := strings.Repeat("      ", depth) + "  "
+	if depth > 0 {
+		output.WriteString(strings.Repeat("      ", depth-1) + "  ->  ")
+	}
+
+	output.WriteString(node.NodeType)
+	if node.IndexName != "" {
+		fmt.Fprintf(output, " using %s", node.IndexName)
+	}
+	if node.RelationName != "" {
+		fmt.Fprintf(output, " on %s", node.RelationName)
+		if node.Alias != "" && node.Alias != node.RelationName {
+			fmt.Fprintf(output, " %s", node.Alias)
+		}
+	}
+	fmt.Fprintf(output, "  (cost=%.2f..%.2f rows=%s width=%d)",
+		node.StartupCost, node.TotalCost, formatPlanNumber(node.PlanRows), node.PlanWidth)
+	if node.Actual != nil {
+		if node.Actual.Loops == 0 {
+			output.WriteString(" (never executed)")
+		} else {
+			fmt.Fprintf(output, " (actual time=%.3f..%.3f rows=%s loops=%s)", node.Actual.StartupTime,
+				node.Actual.TotalTime, formatPlanNumber(node.Actual.Rows), formatPlanNumber(node.Actual.Loops))
+		}
+	}
+	output.WriteString("\n")
+
+	for _, key := range []string{"Hash Cond", "Index Cond", "Merge Cond", "Join Filter", "Filter"} {
+		if value, ok := node.Details[key].(string); ok {
+			fmt.Fprintf(output, "%s%s: %s\n", detailIndent, key, value)
+		}
+	}
+	if buffers := node.Buffers; buffers != nil {
+		if line := formatBuffers(buffers); line != "" {
+			fmt.Fprintf(output, "%sBuffers: %s\n", detailIndent, line)
+		}
+	}
+	for _, flag := range node.Flags {
+		fmt.Fprintf(output, "%sWarning: %s\n", detailIndent, flag.Message)
+	}
+
+	for i := range node.Children {
+		formatPlanNode(output, &node.Children[i], depth+1)
+	}
+}
+
+// formatBuffers renders buffer counts as EXPLAIN does, leaving out zeros.
+func formatBuffers(buffers *PlanBuffers) string {
+	var groups []string
+	for _, group := range []struct {
+		name   string
+		labels []string
+		values []int64
+	}{
+		{"shared", []string{"hit", "read", "dirtied", "written"},
+			[]int64{buffers.SharedHit, buffers.SharedRead, buffers.SharedDirtied, buffers.SharedWritten}},
+		{"local", []string{"hit", "read"}, []int64{buffers.LocalHit, buffers.LocalRead}},
+		{"temp", []string{"read", "written"}, []int64{buffers.TempRead, buffers.TempWritten}},
+	} {
+		var counts []string
+		for i, value := range group.values {
+			if value > 0 {
+				counts = append(counts, fmt.Sprintf("%s=%d", group.labels[i], value))
+			}
+		}
+		if len(counts) > 0 {
+			groups = append(groups, group.name+" "+strings.Join(counts, " "))
+		}
+	}
+	return strings.Join(groups, ", ")
+}
+
+// formatPlanNumber formats a row count or loop count without a fraction
+// unless it has one.
+func formatPlanNumber(value float64) string {
+	if value == math.Trunc(value) {
+		return fmt.Sprintf("%.0f", value)
+	}
+	return fmt.Sprintf("%.2f", value)
+}
@@ -0,0 +1,124 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const analyzedPlan = `[{"Plan": {"Node Type": "Hash Join", "Join Type": "Inner", "Startup Cost": 1.5,
+"Total Cost": 40.25, "Plan Rows": 5, "Plan Width": 12, "Actual Startup Time": 0.1,
+"Actual Total Time": 2.5, "Actual Rows": 900, "Actual Loops": 1, "Hash Cond": "(o.user_id = u.id)",
+"Shared Hit Blocks": 12, "Shared Read Blocks": 3, "Shared Dirtied Blocks": 0, "Shared Written Blocks": 0,
+"Local Hit Blocks": 0, "Local Read Blocks": 0, "Temp Read Blocks": 0, "Temp Written Blocks": 0,
+"Plans": [
+  {"Node Type": "Seq Scan", "Parent Relationship": "Outer", "Relation Name": "orders", "Schema": "public",
+   "Alias": "o", "Startup Cost": 0, "Total Cost": 30, "Plan Rows": 1000, "Plan Width": 8,
+   "Actual Startup Time": 0.01, "Actual Total Time": 1.2, "Actual Rows": 1000, "Actual Loops": 1,
+   "Filter": "(total > 10)", "Rows Removed by Filter": 4000},
+  {"Node Type": "Index Scan", "Parent Relationship": "Inner", "Index Name": "users_pkey",
+   "Relation Name": "users", "Alias": "u", "Startup Cost": 0.3, "Total Cost": 8.3, "Plan Rows": 1,
+   "Plan Width": 4, "Actual Startup Time": 0, "Actual Total Time": 0, "Actual Rows": 0, "Actual Loops": 0}
+]}, "Planning Time": 0.2, "Triggers": [], "Execution Time": 2.75}]`
+
+func TestParsePlan(t *testing.T) {
+	plan, err := parsePlan([]byte(analyzedPlan))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	t.Run("should build the plan tree with actuals and buffers", func(t *testing.T) {
+		root := plan.Root
+		if !plan.Analyzed || plan.ExecutionTime != 2.75 || plan.PlanningTime != 0.2 {
+			t.Fatalf("Expected an analyzed plan with timings, got %+v", plan)
+		}
+		if root.NodeType != "Hash Join" || root.JoinType != "Inner" || len(root.Children) != 2 {
+			t.Fatalf("Expected a hash join with two children, got %+v", root)
+		}
+		if root.Actual == nil || root.Actual.Rows != 900 || root.Buffers == nil || root.Buffers.SharedRead != 3 {
+			t.Fatalf("Expected actual rows and buffers, got %+v %+v", root.Actual, root.Buffers)
+		}
+		if root.Details["Hash Cond"] != "(o.user_id = u.id)" {
+			t.Errorf("Expected the hash condition in the details, got %+v", root.Details)
+		}
+		if index := root.Children[1]; index.IndexName != "users_pkey" || index.Actual.Loops != 0 {
+			t.Errorf("Expected a never executed index scan, got %+v", index)
+		}
+	})
+
+	t.Run("should flag misestimates and sequential scans", func(t *testing.T) {
+		if flags := plan.Root.Flags; len(flags) != 1 || flags[0].Kind != PlanFlagMisestimate {
+			t.Errorf("Expected the join to be flagged as misestimated, got %+v", flags)
+		}
+		scan := plan.Root.Children[0]
+		if len(scan.Flags) != 1 || scan.Flags[0].Kind != PlanFlagSeqScan || !strings.Contains(scan.Flags[0].Message, "4000 rows") {
+			t.Errorf("Expected the scan to be flagged, got %+v", scan.Flags)
+		}
+		if flags := plan.Root.Children[1].Flags; len(flags) != 0 {
+			t.Errorf("Expected nodes that never ran not to be flagged, got %+v", flags)
+		}
+	})
+
+	t.Run("should render the plan as text", func(t *testing.T) {
+		output := formatPlan(plan)
+		for _, want := range []string{
+			"Hash Join  (cost=1.50..40.25 rows=5 width=12) (actual time=0.100..2.500 rows=900 loops=1)",
+			"  ->  Seq Scan on orders o",
+			"Buffers: shared hit=12 read=3",
+			"Index Scan using users_pkey on users u  (cost=0.30..8.30 rows=1 width=4) (never executed)",
+			"Execution Time: 2.750 ms",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Expected the output to contain %q, got:\n%s", want, output)
+			}
+		}
+	})
+}
+
+func TestPostgreSQLExecutor_Explain(t *testing.T) {
+	ctx := context.Background()
+	log := &transactionLog{}
+	handler := func(query string) []standInResult {
+		if strings.HasPrefix(query, "EXPLAIN") {
+			log.handler(query)
+			return []standInResult{{Columns: textColumns("QUERY PLAN"), Rows: [][]*string{textRow(analyzedPlan)}, Tag: "EXPLAIN"}}
+		}
+		return log.handler(query)
+	}
+
+	t.Run("should analyze writes inside a rolled back transaction", func(t *testing.T) {
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), handler)
+
+		result, err := executor.Explain(ctx, "DELETE FROM orders WHERE total > 10", ExplainAnalyze)
+		if err != nil || result.ExitCode != 0 {
+			t.Fatalf("Expected the explain to succeed, got %v %q", err, result.Error)
+		}
+		if result.Plan == nil || result.Plan.Statement != "DELETE FROM orders WHERE total > 10" {
+			t.Fatalf("Expected the plan of the statement, got %+v", result.Plan)
+		}
+		if !log.contains("BEGIN") || !log.contains("EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) DELETE FROM orders WHERE total > 10") || !log.contains("ROLLBACK") {
+			t.Fatalf("Expected EXPLAIN ANALYZE between BEGIN and ROLLBACK, got %v", log.queries)
+		}
+	})
+
+	t.Run("should explain without running the statement", func(t *testing.T) {
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), handler)
+
+		result, _ := executor.Explain(ctx, "SELECT * FROM orders", ExplainPlan)
+		if result.ExitCode != 0 || !log.contains("EXPLAIN (FORMAT JSON) SELECT * FROM orders") {
+			t.Fatalf("Expected a plain EXPLAIN, got %q %v", result.Error, log.queries)
+		}
+	})
+
+	t.Run("should need a single statement", func(t *testing.T) {
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), handler)
+
+		result, _ := executor.Explain(ctx, "SELECT 1; SELECT 2", ExplainPlan)
+		if result.ExitCode != ExitCodePostgresQueryError || !strings.Contains(result.Error, "single statement") {
+			t.Fatalf("Expected a single statement error, got %d %q", result.ExitCode, result.Error)
+		}
+		if _, err := executor.Explain(ctx, "SELECT 1", "verbose"); err == nil {
+			t.Fatal("Expected an error for an unknown explain mode")
+		}
+	})
+}
@@ -0,0 +1,552 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// defaultExportTable is the table of exported INSERT statements when
+// ExportOptions.Table is empty.
+const defaultExportTable = "exported_rows"
+
+// resultWriter writes a result set in one of the export formats, a row at a
+// time.
+type resultWriter interface {
+	writeHeader(columns []SQLColumn) error
+	writeRow(row []interface{}) error
+	finish() error
+}
+
+// newResultWriter returns the writer of opts.Format.
+func newResultWriter(w io.Writer, opts ExportOptions) (resultWriter, error) {
+	out := bufio.NewWriter(w)
+	switch opts.Format {
+	case ExportCSV:
+		delimiter := ','
+		if opts.Delimiter != "" {
+			r, size := utf8.DecodeRuneInString(opts.Delimiter)
+			if size != len(opts.Delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+				return nil, fmt.Errorf("invalid CSV delimiter %q: use a single character other than a quote or line break", opts.Delimiter)
+			}
+			delimiter = r
+		}
+		return &delimitedWriter{out: out, opts: opts, delimiter: delimiter}, nil
+	case ExportTSV:
+		return &delimitedWriter{out: out, opts: opts, delimiter: '\t'}, nil
+	case ExportJSON, ExportNDJSON:
+		return &jsonWriter{out: out, lines: opts.Format == ExportNDJSON}, nil
+	case ExportMarkdown:
+		return &markdownWriter{out: out, null: opts.Null}, nil
+	case ExportInsert:
+		table := strings.TrimSpace(opts.Table)
+		if table == "" {
+			table = defaultExportTable
+		}
+		return &insertWriter{out: out, table: table}, nil
+	}
+	return nil, fmt.Errorf("unknown export format: %q", opts.Format)
+}
+
+// ExportResult writes the rows of result to w in the format of opts and
+// returns how many it wrote. Only the rows the result holds are written; use
+// ExportQuery for rows that were left out of it.
+func ExportResult(w io.Writer, result *SQLQueryResult, opts ExportOptions) (int64, error) {
+	if result == nil || !result.ReturnsRows {
+		return 0, fmt.Errorf("the statement did not return rows to export")
+	}
+	writer, err := newResultWriter(w, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	columns := result.ColumnInfo
+	if len(columns) != len(result.Columns) {
+		columns = make([]SQLColumn, len(result.Columns))
+		for i, name := range result.Columns {
+			columns[i] = SQLColumn{Name: name}
+		}
+	}
+	if err := writer.writeHeader(columns); err != nil {
+		return 0, fmt.Errorf("failed to write export: %w", err)
+	}
+	for i, row := range result.Rows {
+		if err := writer.writeRow(row); err != nil {
+			return int64(i), fmt.Errorf("failed to write export: %w", err)
+		}
+	}
+	if err := writer.finish(); err != nil {
+		return int64(len(result.Rows)), fmt.Errorf("failed to write export: %w", err)
+	}
+	return int64(len(result.Rows)), nil
+}
+
+// ExportQuery runs a query again and writes all of its rows to w in the
+// format of opts, as they arrive and without the row limit of runs. It
+// returns how many rows it wrote. Only single statements that return rows
+// and cannot write are run again.
+//
+// The rows are streamed on a connection of the export's own, so that other
+// requests go on meanwhile; closing the connection cancels the export. Only
+// in a session with an open transaction, whose changes the export should
+// see, does it run on the session's connection, which it keeps until done.
+// Cancelling ctx stops the export with ErrExecutionCancelled.
+func (p *PostgreSQLExecutor) ExportQuery(ctx context.Context, code string, w io.Writer, opts ExportOptions) (int64, error) {
+	writer, err := newResultWriter(w, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	p.mu.Lock()
+	locked := true
+	defer func() {
+		if locked {
+			p.mu.Unlock()
+		}
+	}()
+
+	if !p.isAvailableInternal() {
+		return 0, fmt.Errorf("PostgreSQL connection is not configured or unavailable")
+	}
+
+	statements := splitSQLStatements(p.prepareSQLCode(code))
+	switch {
+	case len(statements) != 1:
+		return 0, fmt.Errorf("exporting needs a single statement, got %d", len(statements))
+	case !statementReturnsRows(statements[0].Tokens):
+		return 0, fmt.Errorf("the statement does not return rows to export")
+	}
+	if reason := readOnlyReason(significantTokens(statements[0].Tokens)); reason != "" {
+		return 0, fmt.Errorf("the statement is not run again for export, since %s", strings.TrimPrefix(reason, "it "))
+	}
+
+	if err := p.ensureConnection(ctx); err != nil {
+		return 0, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	var conn *pgxpool.Conn
+	var release func()
+	if p.sessionMode && p.session != nil && transactionStatus(p.session.Conn().PgConn()) != TransactionIdle {
+		// The session's connection cannot run the export while a result is open on it.
+		p.closeCursor()
+		conn, release, err = p.acquireConn(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+		}
+	} else {
+		conn, err = p.pool.Acquire(ctx)
+		if err == nil {
+			if err = p.applyLimits(ctx, conn); err != nil {
+				conn.Release()
+			}
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+		}
+		release = conn.Release
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		defer p.untrackExport(p.trackExport(cancel))
+		p.mu.Unlock()
+		locked = false
+	}
+	if p.sandbox {
+		rollback, err := beginSandbox(ctx, conn)
+		if err != nil {
+			release()
+			return 0, err
+		}
+		releaseConn := release
+		release = func() {
+			rollback()
+			releaseConn()
+		}
+	}
+	defer release()
+
+	log.Printf("PostgreSQL Executor: Exporting %s as %s", statementSummary(statements[0].Text), opts.Format)
+	typeMap := conn.Conn().TypeMap()
+	multiReader := conn.Conn().PgConn().Exec(ctx, statements[0].Text)
+	var rows int64
+	for multiReader.NextResult() {
+		reader := multiReader.ResultReader()
+		fieldDescriptions := reader.FieldDescriptions()
+		if len(fieldDescriptions) == 0 {
+			continue
+		}
+
+		if err := writer.writeHeader(describeColumns(typeMap, fieldDescriptions)); err != nil {
+			multiReader.Close()
+			return rows, fmt.Errorf("failed to write export: %w", err)
+		}
+		for reader.NextRow() {
+			row, err := p.decodeRow(typeMap, fieldDescriptions, reader.Values())
+			if err == nil {
+				err = writer.writeRow(row)
+			}
+			if err != nil {
+				multiReader.Close()
+				return rows, fmt.Errorf("failed to export rows: %w", err)
+			}
+			rows++
+		}
+	}
+	if err := multiReader.Close(); err != nil {
+		if wasCancelled(ctx) {
+			return rows, fmt.Errorf("export stopped after %d rows: %w", rows, ErrExecutionCancelled)
+		}
+		return rows, fmt.Errorf("failed to export rows: %w", err)
+	}
+	if err := writer.finish(); err != nil {
+		return rows, fmt.Errorf("failed to write export: %w", err)
+	}
+	return rows, nil
+}
+
+// trackExport records the cancel function of an export that streams rows
+// without holding p.mu, so that closing the pool can stop it, and returns
+// the export's ID. p.mu must be held.
+func (p *PostgreSQLExecutor) trackExport(cancel context.CancelFunc) uint64 {
+	if p.exports == nil {
+		p.exports = make(map[uint64]context.CancelFunc)
+	}
+	p.exportSeq++
+	p.exports[p.exportSeq] = cancel
+	return p.exportSeq
+}
+
+// untrackExport forgets a finished export. Its connection must have been
+// released, since closing the pool waits for it while holding p.mu.
+func (p *PostgreSQLExecutor) untrackExport(id uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.exports, id)
+}
+
+// delimitedWriter writes CSV and TSV. Fields are quoted and lines end in CRLF
+// as in RFC 4180, which is also how Excel reads pasted cells.
+type delimitedWriter struct {
+	out       *bufio.Writer
+	opts      ExportOptions
+	delimiter rune
+	columns   []SQLColumn
+}
+
+func (d *delimitedWriter) writeHeader(columns []SQLColumn) error {
+	d.columns = columns
+	if d.opts.NoHeader {
+		return nil
+	}
+	fields := make([]string, len(columns))
+	for i, column := range columns {
+		fields[i] = column.Name
+	}
+	return d.writeFields(fields, nil)
+}
+
+func (d *delimitedWriter) writeRow(row []interface{}) error {
+	fields := make([]string, len(row))
+	for i, value := range row {
+		if value == nil {
+			fields[i] = d.opts.Null
+		} else {
+			fields[i] = exportText(value, columnAt(d.columns, i))
+		}
+	}
+	return d.writeFields(fields, row)
+}
+
+// writeFields writes a line. Given row, the values of the fields, NULLs are
+// never quoted and empty strings always are, as PostgreSQL's COPY does, so
+// that the two stay apart.
+func (d *delimitedWriter) writeFields(fields []string, row []interface{}) error {
+	for i, field := range fields {
+		if i > 0 {
+			d.out.WriteRune(d.delimiter)
+		}
+		isNull := row != nil && row[i] == nil
+		if !isNull && (d.opts.Quoting == QuoteAll || field == "" || d.needsQuotes(field)) {
+			d.out.WriteByte('"')
+			d.out.WriteString(strings.ReplaceAll(field, `"`, `""`))
+			d.out.WriteByte('"')
+		} else {
+			d.out.WriteString(field)
+		}
+	}
+	_, err := d.out.WriteString("\r\n")
+	return err
+}
+
+func (d *delimitedWriter) needsQuotes(field string) bool {
+	return strings.ContainsRune(field, d.delimiter) || strings.ContainsAny(field, "\"\r\n")
+}
+
+func (d *delimitedWriter) finish() error {
+	return d.out.Flush()
+}
+
+// jsonWriter writes an array of objects keyed by column name, or with lines,
+// one object per line. Values are written as in ExecutionResult.
+type jsonWriter struct {
+	out     *bufio.Writer
+	lines   bool
+	keys    []string // Column names encoded as JSON strings
+	written int
+}
+
+func (j *jsonWriter) writeHeader(columns []SQLColumn) error {
+	j.keys = make([]string, len(columns))
+	for i, column := range columns {
+		key, err := json.Marshal(column.Name)
+		if err != nil {
+			return err
+		}
+		j.keys[i] = string(key)
+	}
+	if !j.lines {
+		_, err := j.out.WriteString("[")
+		return err
+	}
+	return nil
+}
+
+func (j *jsonWriter) writeRow(row []interface{}) error {
+	if !j.lines {
+		if j.written > 0 {
+			j.out.WriteString(",")
+		}
+		j.out.WriteString("\n  ")
+	}
+	j.out.WriteString("{")
+	for i, value := range row {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			j.out.WriteString(", ")
+		}
+		j.out.WriteString(j.keys[i])
+		j.out.WriteString(": ")
+		j.out.Write(data)
+	}
+	j.out.WriteString("}")
+	if j.lines {
+		j.out.WriteString("\n")
+	}
+	j.written++
+	return nil
+}
+
+func (j *jsonWriter) finish() error {
+	if !j.lines {
+		if j.written > 0 {
+			j.out.WriteString("\n")
+		}
+		j.out.WriteString("]\n")
+	}
+	return j.out.Flush()
+}
+
+// markdownWriter writes a GitHub-flavored Markdown table, with numeric
+// columns aligned right.
+type markdownWriter struct {
+	out     *bufio.Writer
+	null    string
+	columns []SQLColumn
+}
+
+func (m *markdownWriter) writeHeader(columns []SQLColumn) error {
+	m.columns = columns
+	names := make([]string, len(columns))
+	alignments := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = markdownCell(column.Name)
+		alignments[i] = "---"
+		if isNumericType(column.TypeName) {
+			alignments[i] = "---:"
+		}
+	}
+	m.writeLine(names)
+	return m.writeLine(alignments)
+}
+
+func (m *markdownWriter) writeRow(row []interface{}) error {
+	cells := make([]string, len(row))
+	for i, value := range row {
+		if value == nil {
+			cells[i] = markdownCell(m.null)
+		} else {
+			cells[i] = markdownCell(exportText(value, columnAt(m.columns, i)))
+		}
+	}
+	return m.writeLine(cells)
+}
+
+func (m *markdownWriter) writeLine(cells []string) error {
+	_, err := m.out.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	return err
+}
+
+func (m *markdownWriter) finish() error {
+	return m.out.Flush()
+}
+
+// markdownCell escapes text for a table cell, which cannot hold pipes or line
+// breaks.
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\\`)
+	text = strings.ReplaceAll(text, "|", `\|`)
+	text = strings.ReplaceAll(text, "\r\n", "<br>")
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
+// insertWriter writes an INSERT statement per row. Values other than numbers
+// and booleans are written as quoted literals, which PostgreSQL converts to
+// the type of their column.
+type insertWriter struct {
+	out     *bufio.Writer
+	table   string
+	columns []SQLColumn
+	prefix  string // INSERT INTO table (columns) VALUES
+}
+
+func (s *insertWriter) writeHeader(columns []SQLColumn) error {
+	s.columns = columns
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = quoteSQLIdentifier(column.Name)
+	}
+	s.prefix = fmt.Sprintf("INSERT INTO %s (%s) VALUES (", s.table, strings.Join(names, ", "))
+	return nil
+}
+
+func (s *insertWriter) writeRow(row []interface{}) error {
+	s.out.WriteString(s.prefix)
+	for i, value := range row {
+		if i > 0 {
+			s.out.WriteString(", ")
+		}
+		s.out.WriteString(sqlLiteral(value, columnAt(s.columns, i)))
+	}
+	_, err := s.out.WriteString(");\n")
+	return err
+}
+
+func (s *insertWriter) finish() error {
+	return s.out.Flush()
+}
+
+// columnAt returns the column at index i, or an unknown column.
+func columnAt(columns []SQLColumn, i int) SQLColumn {
+	if i < len(columns) {
+		return columns[i]
+	}
+	return SQLColumn{}
+}
+
+// isNumericType reports whether values of the type with the given pg_type
+// name are numbers.
+func isNumericType(typeName string) bool {
+	switch typeName {
+	case "int2", "int4", "int8", "float4", "float8", "numeric", "oid", "money":
+		return true
+	}
+	return false
+}
+
+// isJSONType reports whether the type with the given pg_type name is json or
+// jsonb.
+func isJSONType(typeName string) bool {
+	return typeName == "json" || typeName == "jsonb"
+}
+
+// exportText returns the text of a converted, non-NULL value of column as
+// PostgreSQL writes it: arrays as {...} literals, except in json columns,
+// whose values are written as JSON.
+func exportText(value interface{}, column SQLColumn) string {
+	if isJSONType(column.TypeName) {
+		if data, err := json.Marshal(value); err == nil {
+			return string(data)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		return arrayLiteral(v)
+	}
+	return formatValue(value)
+}
+
+// arrayLiteral writes the elements of an array value as a PostgreSQL array
+// literal, quoting every element that is not a number.
+func arrayLiteral(elements []interface{}) string {
+	var literal strings.Builder
+	literal.WriteByte('{')
+	for i, element := range elements {
+		if i > 0 {
+			literal.WriteByte(',')
+		}
+		switch v := element.(type) {
+		case nil:
+			literal.WriteString("NULL")
+		case []interface{}:
+			literal.WriteString(arrayLiteral(v))
+		case int64, float64, json.Number, bool:
+			literal.WriteString(exportText(v, SQLColumn{}))
+		default:
+			text := exportText(v, SQLColumn{})
+			text = strings.ReplaceAll(text, `\`, `\\`)
+			literal.WriteString(`"` + strings.ReplaceAll(text, `"`, `\"`) + `"`)
+		}
+	}
+	literal.WriteByte('}')
+	return literal.String()
+}
+
+// sqlLiteral returns a converted value of column as a SQL literal.
+func sqlLiteral(value interface{}, column SQLColumn) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		if isNumericType(column.TypeName) || column.TypeName == "" {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return "'" + strings.ReplaceAll(exportText(value, column), "'", "''") + "'"
+}
+
+// quoteSQLIdentifier quotes name as a SQL identifier.
+func quoteSQLIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
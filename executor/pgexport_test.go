@@ -0,0 +1,214 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// exportedResult is a result as the frontend sends it back for export, with
+// numbers decoded from JSON as float64.
+var exportedResult = &SQLQueryResult{
+	ReturnsRows: true,
+	Columns:     []string{"id", "name", "tags", "data"},
+	ColumnInfo: []SQLColumn{
+		{Name: "id", TypeName: "int4"},
+		{Name: "name", TypeName: "text"},
+		{Name: "tags", TypeName: "text[]"},
+		{Name: "data", TypeName: "jsonb"},
+	},
+	Rows: [][]interface{}{
+		{float64(1), "Ann, \"the\" first", []interface{}{"a", "b c"}, map[string]interface{}{"n": float64(1)}},
+		{float64(2000000), "", nil, []interface{}{"x"}},
+	},
+}
+
+func TestExportResult(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     ExportOptions
+		expected string
+	}{
+		{
+			name: "should write CSV with minimal quoting and CRLF line ends",
+			opts: ExportOptions{Format: ExportCSV},
+			expected: "id,name,tags,data\r\n" +
+				"1,\"Ann, \"\"the\"\" first\",\"{\"\"a\"\",\"\"b c\"\"}\",\"{\"\"n\"\":1}\"\r\n" +
+				"2000000,\"\",,\"[\"\"x\"\"]\"\r\n",
+		},
+		{
+			name: "should write CSV with another delimiter, quoting all but NULLs",
+			opts: ExportOptions{Format: ExportCSV, Delimiter: ";", Quoting: QuoteAll, NoHeader: true, Null: `\N`},
+			expected: "\"1\";\"Ann, \"\"the\"\" first\";\"{\"\"a\"\",\"\"b c\"\"}\";\"{\"\"n\"\":1}\"\r\n" +
+				"\"2000000\";\"\";\\N;\"[\"\"x\"\"]\"\r\n",
+		},
+		{
+			name: "should write TSV",
+			opts: ExportOptions{Format: ExportTSV},
+			expected: "id\tname\ttags\tdata\r\n" +
+				"1\t\"Ann, \"\"the\"\" first\"\t\"{\"\"a\"\",\"\"b c\"\"}\"\t\"{\"\"n\"\":1}\"\r\n" +
+				"2000000\t\"\"\t\t\"[\"\"x\"\"]\"\r\n",
+		},
+		{
+			name: "should write JSON",
+			opts: ExportOptions{Format: ExportJSON},
+			expected: "[\n" +
+				"  {\"id\": 1, \"name\": \"Ann, \\\"the\\\" first\", \"tags\": [\"a\",\"b c\"], \"data\": {\"n\":1}},\n" +
+				"  {\"id\": 2000000, \"name\": \"\", \"tags\": null, \"data\": [\"x\"]}\n" +
+				"]\n",
+		},
+		{
+			name: "should write NDJSON",
+			opts: ExportOptions{Format: ExportNDJSON},
+			expected: "{\"id\": 1, \"name\": \"Ann, \\\"the\\\" first\", \"tags\": [\"a\",\"b c\"], \"data\": {\"n\":1}}\n" +
+				"{\"id\": 2000000, \"name\": \"\", \"tags\": null, \"data\": [\"x\"]}\n",
+		},
+		{
+			name: "should write a Markdown table",
+			opts: ExportOptions{Format: ExportMarkdown, Null: "NULL"},
+			expected: "| id | name | tags | data |\n" +
+				"| ---: | --- | --- | --- |\n" +
+				"| 1 | Ann, \"the\" first | {\"a\",\"b c\"} | {\"n\":1} |\n" +
+				"| 2000000 |  | NULL | [\"x\"] |\n",
+		},
+		{
+			name: "should write INSERT statements",
+			opts: ExportOptions{Format: ExportInsert, Table: "public.people"},
+			expected: "INSERT INTO public.people (\"id\", \"name\", \"tags\", \"data\") VALUES (1, 'Ann, \"the\" first', '{\"a\",\"b c\"}', '{\"n\":1}');\n" +
+				"INSERT INTO public.people (\"id\", \"name\", \"tags\", \"data\") VALUES (2000000, '', NULL, '[\"x\"]');\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var output bytes.Buffer
+			rows, err := ExportResult(&output, exportedResult, tc.opts)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if rows != 2 {
+				t.Errorf("Expected 2 rows, got %d", rows)
+			}
+			if output.String() != tc.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tc.expected, output.String())
+			}
+		})
+	}
+
+	t.Run("should escape pipes and line breaks in Markdown", func(t *testing.T) {
+		result := &SQLQueryResult{ReturnsRows: true, Columns: []string{"a|b"}, Rows: [][]interface{}{{"x|y\nz"}}}
+		var output bytes.Buffer
+		ExportResult(&output, result, ExportOptions{Format: ExportMarkdown})
+		if !strings.Contains(output.String(), `| a\|b |`) || !strings.Contains(output.String(), `| x\|y<br>z |`) {
+			t.Fatalf("Expected escaped cells, got %q", output.String())
+		}
+	})
+
+	t.Run("should reject bad options", func(t *testing.T) {
+		for _, opts := range []ExportOptions{{Format: "xml"}, {Format: ExportCSV, Delimiter: `"`}, {Format: ExportCSV, Delimiter: ";;"}} {
+			if _, err := ExportResult(&bytes.Buffer{}, exportedResult, opts); err == nil {
+				t.Errorf("Expected an error for %+v", opts)
+			}
+		}
+	})
+}
+
+func TestPostgreSQLExecutor_ExportQuery(t *testing.T) {
+	ctx := context.Background()
+	const total = 250
+	handler := func(query string) []standInResult {
+		rows := make([][]*string, total)
+		for i := range rows {
+			rows[i] = textRow(strconv.Itoa(i + 1))
+		}
+		columns := []standInColumn{{Name: "id", OID: pgtype.Int4OID}}
+		return []standInResult{{Columns: columns, Rows: rows, Tag: "SELECT " + strconv.Itoa(total)}}
+	}
+	opts := DefaultExecutorOptions()
+	opts.MaxOutputs = 10
+	executor := newStandInExecutor(t, opts, handler)
+
+	t.Run("should export every row without the row limit", func(t *testing.T) {
+		var output bytes.Buffer
+		rows, err := executor.ExportQuery(ctx, "SELECT id FROM generate_series(1, 250) id", &output, ExportOptions{Format: ExportNDJSON})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+		if rows != total || len(lines) != total || lines[total-1] != `{"id": 250}` {
+			t.Fatalf("Expected %d rows, got %d and %d lines ending in %q", total, rows, len(lines), lines[len(lines)-1])
+		}
+	})
+
+	t.Run("should not run statements that can write again", func(t *testing.T) {
+		for _, code := range []string{"DELETE FROM t RETURNING id", "SELECT 1; SELECT 2", "CREATE TABLE t (id int)"} {
+			if _, err := executor.ExportQuery(ctx, code, &bytes.Buffer{}, ExportOptions{Format: ExportCSV}); err == nil {
+				t.Errorf("Expected %q to be refused", code)
+			}
+		}
+	})
+}
+
+func TestPostgreSQLExecutor_ExportQueryCancel(t *testing.T) {
+	clearPGEnv(t)
+	started, cancelled := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	port := listenPostgresStandIn(t, nil, func(query string) []standInResult {
+		results := seriesHandler(query)
+		if query == "SELECT n FROM series" {
+			// An export that goes on until cancelled.
+			results[0].Cancel = cancelled
+			close(started)
+		}
+		return results
+	}, func() { once.Do(func() { close(cancelled) }) })
+
+	executor := NewPostgreSQLExecutor(DefaultExecutorOptions())
+	executor.SetConfig(&PostgreSQLConfig{
+		Host: "127.0.0.1", Port: port, Database: "app", Username: "postgres", SSLMode: "disable",
+	})
+	t.Cleanup(func() { executor.Cleanup() })
+	manager := NewExecutionManager(DefaultExecutorOptions())
+
+	ctx, done, err := manager.Track("export-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer done()
+	exported := make(chan error, 1)
+	go func() {
+		_, err := executor.ExportQuery(ctx, "SELECT n FROM series", &bytes.Buffer{}, ExportOptions{Format: ExportCSV})
+		exported <- err
+	}()
+	<-started
+
+	t.Run("should run other statements while an export streams rows", func(t *testing.T) {
+		runCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		result, err := executor.Execute(runCtx, "SELECT 1", "")
+		if err != nil || result.ExitCode != 0 {
+			t.Fatalf("Expected the statement to run, got %v: %+v", err, result)
+		}
+	})
+
+	t.Run("should stop the export when its execution is cancelled", func(t *testing.T) {
+		if err := manager.CancelExecution("export-1"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		select {
+		case err := <-exported:
+			if !errors.Is(err, ErrExecutionCancelled) {
+				t.Fatalf("Expected the export to be cancelled, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the export to stop")
+		}
+	})
+}
@@ -0,0 +1,342 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// readOnlySettings are the settings that would take a session out of
+// read-only mode.
+var readOnlySettings = []string{"default_transaction_read_only", "transaction_read_only"}
+
+// guardFinding is a statement of a script that read-only or safe mode keeps
+// from running.
+type guardFinding struct {
+	statement int
+	reason    string
+}
+
+// readOnlyViolations returns the statements that read-only mode refuses. The
+// classification is conservative: statements it does not know to be
+// read-only are refused. Writes it cannot see, such as those of functions
+// called by a query, are refused by the server, since read-only connections
+// start with default_transaction_read_only.
+func readOnlyViolations(statements []sqlStatement) []guardFinding {
+	var findings []guardFinding
+	for i, statement := range statements {
+		if reason := readOnlyReason(significantTokens(statement.Tokens)); reason != "" {
+			findings = append(findings, guardFinding{statement: i, reason: reason})
+		}
+	}
+	return findings
+}
+
+// destructiveStatements returns the statements that safe mode holds back
+// until the run is confirmed.
+func destructiveStatements(statements []sqlStatement) []guardFinding {
+	var findings []guardFinding
+	for i, statement := range statements {
+		if reason := destructiveReason(significantTokens(statement.Tokens)); reason != "" {
+			findings = append(findings, guardFinding{statement: i, reason: reason})
+		}
+	}
+	return findings
+}
+
+// readOnlyReason returns why a statement, given as its significant tokens,
+// could write, or "" if it cannot.
+func readOnlyReason(tokens []sqlToken) string {
+	tokens, executes := executedStatement(tokens)
+	if !executes || len(tokens) == 0 {
+		return ""
+	}
+	if changesReadOnly(tokens) {
+		return "it would turn off read-only mode"
+	}
+	if dml := dataModifications(tokens); len(dml) > 0 {
+		return fmt.Sprintf("%s writes to the database", tokens[dml[0]].keyword())
+	}
+
+	switch keyword := mainKeyword(tokens); keyword {
+	case "SELECT", "VALUES", "TABLE":
+		if keyword == "SELECT" && hasTopLevelKeyword(tokens, "INTO") {
+			return "SELECT INTO creates a table"
+		}
+		return ""
+	case "BEGIN", "START":
+		if hasKeywordPair(tokens, "READ", "WRITE") {
+			return "it starts a read-write transaction"
+		}
+		return ""
+	case "COPY":
+		if hasTopLevelKeyword(tokens, "FROM") {
+			return "COPY FROM writes to the database"
+		}
+		return ""
+	case "SHOW", "SET", "RESET", "FETCH", "MOVE", "CLOSE", "DECLARE", "EXECUTE", "DEALLOCATE",
+		"LISTEN", "UNLISTEN", "DISCARD", "COMMIT", "END", "ROLLBACK", "ABORT", "SAVEPOINT", "RELEASE":
+		return ""
+	case "":
+		return "it could not be classified"
+	default:
+		return fmt.Sprintf("%s can change the database", keyword)
+	}
+}
+
+// destructiveReason returns what a statement, given as its significant
+// tokens, would destroy, or "" if it is not destructive: DROP, ALTER ...
+// DROP, TRUNCATE, and UPDATE and DELETE without a WHERE clause, including
+// those in WITH queries. DO blocks count too, since what their code does
+// cannot be seen.
+func destructiveReason(tokens []sqlToken) string {
+	tokens, executes := executedStatement(tokens)
+	if !executes || len(tokens) == 0 {
+		return ""
+	}
+
+	switch tokens[0].keyword() {
+	case "DROP":
+		if len(tokens) > 1 && tokens[1].keyword() != "" {
+			return fmt.Sprintf("DROP %s removes the object and everything in it", tokens[1].keyword())
+		}
+		return "DROP removes the object and everything in it"
+	case "ALTER":
+		if hasClause(tokens[1:], "DROP") {
+			if tokens[1].keyword() != "" {
+				return fmt.Sprintf("ALTER %s ... DROP removes what it drops and everything in it", tokens[1].keyword())
+			}
+			return "ALTER ... DROP removes what it drops and everything in it"
+		}
+	case "TRUNCATE":
+		return "TRUNCATE removes every row"
+	case "DO":
+		return "DO runs code that safe mode cannot check"
+	}
+
+	for _, dml := range dataModifications(tokens) {
+		if hasClause(tokens[dml+1:], "WHERE") {
+			continue
+		}
+		switch tokens[dml].keyword() {
+		case "UPDATE":
+			return "UPDATE without WHERE changes every row"
+		case "DELETE":
+			return "DELETE without WHERE removes every row"
+		}
+	}
+	return ""
+}
+
+// significantTokens returns tokens without whitespace and comments.
+func significantTokens(tokens []sqlToken) []sqlToken {
+	significant := make([]sqlToken, 0, len(tokens))
+	for _, token := range tokens {
+		if token.significant() {
+			significant = append(significant, token)
+		}
+	}
+	return significant
+}
+
+// executedStatement returns the statement that running tokens executes, and
+// whether it executes one at all: EXPLAIN only runs its statement with the
+// ANALYZE option, and PREPARE stores its statement for EXECUTE to run.
+func executedStatement(tokens []sqlToken) ([]sqlToken, bool) {
+	if len(tokens) == 0 {
+		return tokens, true
+	}
+
+	switch tokens[0].keyword() {
+	case "EXPLAIN":
+		analyze := false
+		i := 1
+		if i < len(tokens) && tokens[i].Text == "(" {
+			for i++; i < len(tokens) && tokens[i].Text != ")"; i++ {
+				switch tokens[i].keyword() {
+				case "ANALYZE", "ANALYSE":
+					analyze = i+1 >= len(tokens) || !isFalseOption(tokens[i+1])
+				}
+			}
+			i++
+		} else {
+			for ; i < len(tokens); i++ {
+				keyword := tokens[i].keyword()
+				if keyword == "ANALYZE" || keyword == "ANALYSE" {
+					analyze = true
+				} else if keyword != "VERBOSE" {
+					break
+				}
+			}
+		}
+		if !analyze || i >= len(tokens) {
+			return nil, false
+		}
+		return executedStatement(tokens[i:])
+
+	case "PREPARE":
+		if len(tokens) > 1 && tokens[1].keyword() == "TRANSACTION" {
+			return tokens, true
+		}
+		depth := 0
+		for i, token := range tokens {
+			switch {
+			case token.Text == "(":
+				depth++
+			case token.Text == ")":
+				depth--
+			case depth == 0 && token.keyword() == "AS":
+				return executedStatement(tokens[i+1:])
+			}
+		}
+	}
+	return tokens, true
+}
+
+// isFalseOption reports whether token turns an EXPLAIN option off.
+func isFalseOption(token sqlToken) bool {
+	switch token.keyword() {
+	case "FALSE", "OFF":
+		return true
+	}
+	return token.Text == "0"
+}
+
+// dataModifications returns the indexes of the UPDATE, DELETE, INSERT and
+// MERGE keywords that start a statement within tokens: the statement itself,
+// the statements of its WITH queries, and those of COPY (...) TO. Such a
+// keyword starts tokens or follows a parenthesis; elsewhere, as in FOR
+// UPDATE, ON DELETE or GRANT UPDATE, it does not start a statement.
+func dataModifications(tokens []sqlToken) []int {
+	var indexes []int
+	for i, token := range tokens {
+		switch token.keyword() {
+		case "UPDATE", "DELETE", "INSERT", "MERGE":
+			if i == 0 || tokens[i-1].Text == "(" || tokens[i-1].Text == ")" {
+				indexes = append(indexes, i)
+			}
+		}
+	}
+	return indexes
+}
+
+// hasClause reports whether keyword appears at the top level of the statement
+// that tokens start in, which ends at an unmatched closing parenthesis.
+func hasClause(tokens []sqlToken, keyword string) bool {
+	depth := 0
+	for _, token := range tokens {
+		switch {
+		case token.Text == "(":
+			depth++
+		case token.Text == ")":
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case depth == 0 && token.keyword() == keyword:
+			return true
+		}
+	}
+	return false
+}
+
+// hasKeywordPair reports whether first is directly followed by second.
+func hasKeywordPair(tokens []sqlToken, first, second string) bool {
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].keyword() == first && tokens[i+1].keyword() == second {
+			return true
+		}
+	}
+	return false
+}
+
+// changesReadOnly reports whether a statement sets one of readOnlySettings,
+// with SET, set_config or ALTER ... SET, or asks for a read-write transaction.
+func changesReadOnly(tokens []sqlToken) bool {
+	switch tokens[0].keyword() {
+	case "SET", "ALTER":
+		if hasKeywordPair(tokens, "READ", "WRITE") {
+			return true
+		}
+	case "SHOW":
+		return false
+	}
+
+	mentioned, setConfig := false, false
+	for _, token := range tokens {
+		name := strings.ToLower(strings.Trim(token.Text, `'"`))
+		for _, setting := range readOnlySettings {
+			if name == setting {
+				mentioned = true
+			}
+		}
+		if token.keyword() == "SET_CONFIG" {
+			setConfig = true
+		}
+	}
+	if !mentioned {
+		return false
+	}
+	switch tokens[0].keyword() {
+	case "SET", "ALTER":
+		return true
+	}
+	return setConfig
+}
+
+// guard returns the statements of a run that the connection's guards keep
+// from running, and whether read-only mode refused them. Safe mode lets
+// confirmed runs through, and sandboxed ones, which are rolled back anyway.
+// The caller must hold p.mu.
+func (p *PostgreSQLExecutor) guard(statements []sqlStatement, confirmed bool) ([]guardFinding, bool) {
+	if p.config == nil {
+		return nil, false
+	}
+	if p.config.ReadOnly {
+		if findings := readOnlyViolations(statements); len(findings) > 0 {
+			return findings, true
+		}
+	}
+	if p.config.SafeMode && !confirmed && !p.sandbox {
+		return destructiveStatements(statements), false
+	}
+	return nil, false
+}
+
+// setGuarded reports on result the statements that kept a run from
+// starting.
+func setGuarded(result *ExecutionResult, findings []guardFinding, readOnly bool, sqlCode string, statements []sqlStatement, sqlMap []sqlLineOrigin) {
+	for _, finding := range findings {
+		statement := statements[finding.statement]
+		guarded := GuardedStatement{
+			Statement: finding.statement,
+			Line:      statementLine(sqlCode, statement.Offset, sqlMap),
+			Text:      statementSummary(statement.Text),
+			Reason:    finding.reason,
+		}
+		result.Guarded = append(result.Guarded, guarded)
+
+		diagnostic := Diagnostic{Severity: SeverityError, Message: finding.reason, Source: "postgres"}
+		line, column := sqlPosition(sqlCode, utf8.RuneCountInString(sqlCode[:statement.Offset])+1)
+		if line > 0 && line <= len(sqlMap) {
+			diagnostic.Line = sqlMap[line-1].line
+			diagnostic.Column = column + sqlMap[line-1].indent
+		}
+		result.Diagnostics = append(result.Diagnostics, diagnostic)
+	}
+
+	first := result.Guarded[0]
+	if readOnly {
+		result.Error = fmt.Sprintf("Read-only mode refused statement %d (%s): %s", first.Statement+1, first.Text, first.Reason)
+		result.ExitCode = ExitCodePostgresQueryError
+	} else {
+		result.Error = fmt.Sprintf("Safe mode needs confirmation to run statement %d (%s): %s", first.Statement+1, first.Text, first.Reason)
+		result.ExitCode = ExitCodePostgresUnconfirmed
+	}
+	if len(findings) > 1 {
+		result.Error += fmt.Sprintf(" (%d more statements held back)", len(findings)-1)
+	}
+}
@@ -0,0 +1,153 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReadOnlyReason(t *testing.T) {
+	testCases := []struct {
+		statement string
+		refused   bool
+	}{
+		{"SELECT * FROM orders WHERE id = 1 FOR UPDATE", false},
+		{"(SELECT 1) UNION (SELECT 2)", false},
+		{"WITH recent AS (SELECT * FROM orders) SELECT count(*) FROM recent", false},
+		{"EXPLAIN DELETE FROM orders", false},
+		{"EXPLAIN (ANALYZE false) DELETE FROM orders", false},
+		{"SHOW transaction_read_only", false},
+		{"SELECT current_setting('transaction_read_only')", false},
+		{"SET search_path TO app", false},
+		{"BEGIN READ ONLY", false},
+		{"COPY orders TO STDOUT", false},
+		{"DECLARE c CURSOR FOR SELECT * FROM orders", false},
+		{"CREATE FUNCTION f() RETURNS void AS $$ DELETE FROM orders $$ LANGUAGE sql", true},
+		{"DELETE FROM orders WHERE id = 1", true},
+		{"WITH gone AS (DELETE FROM orders RETURNING *) SELECT * FROM gone", true},
+		{"SELECT * INTO backup FROM orders", true},
+		{"EXPLAIN ANALYZE VERBOSE UPDATE orders SET total = 0", true},
+		{"EXPLAIN (ANALYZE, BUFFERS) INSERT INTO orders DEFAULT VALUES", true},
+		{"PREPARE purge AS DELETE FROM orders", true},
+		{"COPY orders FROM STDIN", true},
+		{"COPY (DELETE FROM orders RETURNING id) TO STDOUT", true},
+		{"BEGIN READ WRITE", true},
+		{"SET default_transaction_read_only = off", true},
+		{"SET SESSION CHARACTERISTICS AS TRANSACTION READ WRITE", true},
+		{"SELECT set_config('default_transaction_read_only', 'off', false)", true},
+		{"CALL refresh_totals()", true},
+		{"VACUUM orders", true},
+	}
+
+	for _, tc := range testCases {
+		statements := splitSQLStatements(tc.statement)
+		reason := readOnlyReason(significantTokens(statements[0].Tokens))
+		if (reason != "") != tc.refused {
+			t.Errorf("Expected %q refused: %v, got reason %q", tc.statement, tc.refused, reason)
+		}
+	}
+}
+
+func TestDestructiveReason(t *testing.T) {
+	testCases := []struct {
+		statement string
+		expected  string
+	}{
+		{"DELETE FROM orders WHERE id = 1", ""},
+		{"DELETE FROM orders WHERE CURRENT OF c", ""},
+		{"UPDATE orders SET total = (SELECT sum(x) FROM items WHERE items.order_id = orders.id) WHERE id = 1", ""},
+		{"SELECT * FROM orders FOR UPDATE", ""},
+		{"CREATE TABLE items (order_id int REFERENCES orders ON DELETE CASCADE)", ""},
+		{"INSERT INTO orders VALUES (1) ON CONFLICT (id) DO UPDATE SET total = 0", ""},
+		{"GRANT UPDATE ON orders TO app", ""},
+		{"EXPLAIN DELETE FROM orders", ""},
+		{"DELETE FROM orders", "DELETE without WHERE removes every row"},
+		{"delete from orders -- where id = 1", "DELETE without WHERE removes every row"},
+		{"UPDATE orders SET total = (SELECT max(total) FROM orders WHERE id = 1)", "UPDATE without WHERE changes every row"},
+		{"WITH gone AS (DELETE FROM orders RETURNING *) SELECT * FROM gone WHERE id = 1", "DELETE without WHERE removes every row"},
+		{"WITH ids AS (SELECT id FROM stale) DELETE FROM orders", "DELETE without WHERE removes every row"},
+		{"EXPLAIN ANALYZE UPDATE orders SET total = 0", "UPDATE without WHERE changes every row"},
+		{"DROP TABLE orders", "DROP TABLE removes the object and everything in it"},
+		{"TRUNCATE orders", "TRUNCATE removes every row"},
+		{"ALTER TABLE orders ADD COLUMN note text", ""},
+		{"ALTER TABLE orders DROP COLUMN total", "ALTER TABLE ... DROP removes what it drops and everything in it"},
+		{"alter table orders drop total, add note text", "ALTER TABLE ... DROP removes what it drops and everything in it"},
+		{"DO $$ BEGIN DELETE FROM orders; END $$", "DO runs code that safe mode cannot check"},
+		{"DO LANGUAGE plpgsql 'BEGIN NULL; END'", "DO runs code that safe mode cannot check"},
+	}
+
+	for _, tc := range testCases {
+		statements := splitSQLStatements(tc.statement)
+		if reason := destructiveReason(significantTokens(statements[0].Tokens)); reason != tc.expected {
+			t.Errorf("Expected %q for %q, got %q", tc.expected, tc.statement, reason)
+		}
+	}
+}
+
+func TestPostgreSQLExecutor_Guards(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should hold back destructive statements in safe mode until confirmed", func(t *testing.T) {
+		log := &transactionLog{}
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), log.handler)
+		executor.config.SafeMode = true
+
+		result, _ := executor.Execute(ctx, "INSERT INTO t VALUES (1);\nDELETE FROM t", "")
+		if result.ExitCode != ExitCodePostgresUnconfirmed || len(result.Guarded) != 1 {
+			t.Fatalf("Expected the DELETE to be held back, got %d %+v", result.ExitCode, result.Guarded)
+		}
+		if guarded := result.Guarded[0]; guarded.Statement != 1 || guarded.Line != 2 || guarded.Text != "DELETE FROM t" {
+			t.Errorf("Expected the second statement on line 2, got %+v", guarded)
+		}
+		if log.contains("INSERT INTO t VALUES (1)") {
+			t.Fatal("Expected nothing of the run to be executed")
+		}
+
+		result, _ = executor.ExecuteConfirmed(ctx, "INSERT INTO t VALUES (1);\nDELETE FROM t")
+		if result.ExitCode != 0 || !log.contains("DELETE FROM t") {
+			t.Fatalf("Expected the confirmed run to execute, got %d %s", result.ExitCode, result.Error)
+		}
+	})
+
+	t.Run("should hold back ALTER ... DROP and DO blocks in safe mode", func(t *testing.T) {
+		log := &transactionLog{}
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), log.handler)
+		executor.config.SafeMode = true
+
+		result, _ := executor.Execute(ctx, "ALTER TABLE t DROP COLUMN x;\nDO $$ BEGIN DELETE FROM t; END $$", "")
+		if result.ExitCode != ExitCodePostgresUnconfirmed || len(result.Guarded) != 2 {
+			t.Fatalf("Expected both statements to be held back, got %d %+v", result.ExitCode, result.Guarded)
+		}
+		if log.contains("ALTER TABLE t DROP COLUMN x") {
+			t.Fatal("Expected nothing of the run to be executed")
+		}
+	})
+
+	t.Run("should let sandboxed runs through safe mode", func(t *testing.T) {
+		log := &transactionLog{}
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), log.handler)
+		executor.config.SafeMode = true
+		executor.SetSandbox(true)
+
+		if result, _ := executor.Execute(ctx, "DELETE FROM t", ""); result.ExitCode != 0 {
+			t.Fatalf("Expected the sandboxed run to execute, got %s", result.Error)
+		}
+	})
+
+	t.Run("should refuse writes in read-only mode even when confirmed", func(t *testing.T) {
+		log := &transactionLog{}
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), log.handler)
+		executor.config.ReadOnly = true
+
+		result, _ := executor.ExecuteConfirmed(ctx, "SELECT 1; UPDATE t SET x = 1 WHERE id = 1")
+		if result.ExitCode != ExitCodePostgresQueryError || len(result.Guarded) != 1 {
+			t.Fatalf("Expected the UPDATE to be refused, got %d %+v", result.ExitCode, result.Guarded)
+		}
+		if !strings.HasPrefix(result.Error, "Read-only mode refused statement 2") || len(result.Diagnostics) != 1 {
+			t.Errorf("Expected the error and a diagnostic to point at statement 2, got %q %+v", result.Error, result.Diagnostics)
+		}
+		if !executor.SessionStatus().ReadOnly {
+			t.Error("Expected the session status to report read-only mode")
+		}
+	})
+}
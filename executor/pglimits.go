@@ -0,0 +1,115 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// limitsKey is the key of the sessionLimits applied to a connection in its
+// CustomData.
+const limitsKey = "codezone.limits"
+
+// sessionLimits are the timeout settings of a connection, in milliseconds as
+// PostgreSQL takes them. An empty value is the server's own setting.
+type sessionLimits struct {
+	statement         string
+	lock              string
+	idleInTransaction string
+}
+
+func limitsFor(opts SQLOptions) sessionLimits {
+	milliseconds := func(d time.Duration) string {
+		if d <= 0 {
+			return ""
+		}
+		return strconv.FormatInt(max(d.Milliseconds(), 1), 10)
+	}
+	return sessionLimits{
+		statement:         milliseconds(opts.StatementTimeout),
+		lock:              milliseconds(opts.LockTimeout),
+		idleInTransaction: milliseconds(opts.IdleInTransactionTimeout),
+	}
+}
+
+func (l sessionLimits) settings() [][2]string {
+	return [][2]string{
+		{"statement_timeout", l.statement},
+		{"lock_timeout", l.lock},
+		{"idle_in_transaction_session_timeout", l.idleInTransaction},
+	}
+}
+
+// setSQL returns the SET statements that apply the limits to a connection.
+func (l sessionLimits) setSQL() string {
+	statements := make([]string, 0, 3)
+	for _, setting := range l.settings() {
+		if setting[1] == "" {
+			statements = append(statements, fmt.Sprintf("SET %s TO DEFAULT", setting[0]))
+		} else {
+			statements = append(statements, fmt.Sprintf("SET %s = %s", setting[0], setting[1]))
+		}
+	}
+	return strings.Join(statements, "; ")
+}
+
+// applyLimits brings the timeouts of a connection in line with the options.
+// Connections start with the server's settings: passing the limits as startup
+// parameters would make them the session default that SET ... TO DEFAULT
+// returns to. Inside a transaction it waits for the next run, since the
+// transaction's rollback would undo the change. The limits in effect are kept
+// in p.limits, to tell which of them stopped the run.
+func (p *PostgreSQLExecutor) applyLimits(ctx context.Context, conn *pgxpool.Conn) error {
+	limits := limitsFor(p.options.SQL)
+	pgConn := conn.Conn().PgConn()
+	applied, _ := pgConn.CustomData()[limitsKey].(sessionLimits)
+	p.limits = applied
+	if applied == limits || pgConn.TxStatus() != 'I' {
+		return nil
+	}
+
+	if _, err := pgConn.Exec(ctx, limits.setSQL()).ReadAll(); err != nil {
+		return fmt.Errorf("failed to apply session limits: %w", err)
+	}
+	pgConn.CustomData()[limitsKey] = limits
+	p.limits = limits
+	return nil
+}
+
+// serverLimit returns the limit that made the server end a statement with
+// pgErr, if any, given the limits in effect on its connection and how long
+// the run took until then. The error's message is translated under
+// lc_messages, so only its SQLSTATE is used: a query_canceled error is put
+// down to the statement timeout when the run lasted at least that long, and
+// a lock_not_available error to the lock timeout, when those are set. Shorter
+// runs were cancelled otherwise, as with pg_cancel_backend. Callers rule out
+// the run's own cancellation first.
+func serverLimit(pgErr *pgconn.PgError, limits sessionLimits, elapsed time.Duration) SQLLimit {
+	if pgErr == nil {
+		return ""
+	}
+	switch {
+	case pgErr.Code == "57014" && limits.statement != "" && elapsed >= limits.statementTimeout():
+		return LimitStatementTimeout
+	case pgErr.Code == "55P03" && limits.lock != "":
+		return LimitLockTimeout
+	case pgErr.Code == "25P03":
+		return LimitIdleInTransactionTimeout
+	}
+	return ""
+}
+
+// statementTimeout returns the statement timeout of the limits, or 0 for the
+// server's own setting.
+func (l sessionLimits) statementTimeout() time.Duration {
+	milliseconds, _ := strconv.ParseInt(l.statement, 10, 64)
+	return time.Duration(milliseconds) * time.Millisecond
+}
@@ -0,0 +1,188 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+func TestSessionLimits(t *testing.T) {
+	t.Run("should set configured limits and reset the others", func(t *testing.T) {
+		limits := limitsFor(SQLOptions{StatementTimeout: 5 * time.Second, LockTimeout: 500 * time.Microsecond})
+		want := "SET statement_timeout = 5000; SET lock_timeout = 1; SET idle_in_transaction_session_timeout TO DEFAULT"
+		if sql := limits.setSQL(); sql != want {
+			t.Fatalf("Expected %q, got %q", want, sql)
+		}
+	})
+
+	t.Run("should tell which limit stopped a statement from its SQLSTATE", func(t *testing.T) {
+		both := limitsFor(SQLOptions{StatementTimeout: time.Second, LockTimeout: time.Second})
+		testCases := []struct {
+			err      *pgconn.PgError
+			limits   sessionLimits
+			elapsed  time.Duration
+			expected SQLLimit
+		}{
+			{&pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"}, both, time.Second, LimitStatementTimeout},
+			{&pgconn.PgError{Code: "57014", Message: "storniere Anfrage wegen Zeitüberschreitung der Anfrage"}, both, 2 * time.Second, LimitStatementTimeout},
+			{&pgconn.PgError{Code: "57014", Message: "canceling statement due to user request"}, both, 10 * time.Millisecond, ""},
+			{&pgconn.PgError{Code: "57014", Message: "canceling statement due to user request"}, sessionLimits{}, time.Minute, ""},
+			{&pgconn.PgError{Code: "55P03", Message: "storniere Anfrage wegen Zeitüberschreitung einer Sperre"}, both, time.Second, LimitLockTimeout},
+			{&pgconn.PgError{Code: "55P03", Message: `could not obtain lock on relation "users"`}, sessionLimits{}, time.Second, ""},
+			{&pgconn.PgError{Code: "25P03", Message: "terminating connection due to idle-in-transaction timeout"}, sessionLimits{}, time.Minute, LimitIdleInTransactionTimeout},
+			{nil, both, time.Minute, ""},
+		}
+		for _, tc := range testCases {
+			if limit := serverLimit(tc.err, tc.limits, tc.elapsed); limit != tc.expected {
+				t.Errorf("Expected %q for %+v with %+v after %v, got %q", tc.expected, tc.err, tc.limits, tc.elapsed, limit)
+			}
+		}
+	})
+}
+
+func TestPostgreSQLExecutor_Limits(t *testing.T) {
+	// cancelledHandler ends statements that sleep with query_canceled after
+	// delay, as the server does when a statement is cancelled.
+	cancelledHandler := func(delay time.Duration) standInHandler {
+		return func(query string) []standInResult {
+			if !strings.Contains(query, "pg_sleep") {
+				return []standInResult{{Tag: "SELECT 0", Columns: textColumns("?column?")}}
+			}
+			time.Sleep(delay)
+			return []standInResult{{Err: &pgproto3.ErrorResponse{
+				Severity: "ERROR", Code: "57014", Message: "canceling statement due to statement timeout",
+			}}}
+		}
+	}
+
+	t.Run("should report the limit and SQLSTATE that stopped the run", func(t *testing.T) {
+		opts := DefaultExecutorOptions()
+		opts.SQL.StatementTimeout = 50 * time.Millisecond
+		executor := newStandInExecutor(t, opts, cancelledHandler(100*time.Millisecond))
+
+		result, _ := executor.Execute(context.Background(), "SELECT pg_sleep(60)", "")
+		if result.ExitCode != ExitCodeTimeout || result.Limit != LimitStatementTimeout || result.SQLState != "57014" {
+			t.Fatalf("Expected a statement_timeout with SQLSTATE 57014, got %d %q %q", result.ExitCode, result.Limit, result.SQLState)
+		}
+		if !strings.Contains(result.Error, "statement_timeout (SQLSTATE 57014)") {
+			t.Errorf("Expected the error to name the limit, got %q", result.Error)
+		}
+	})
+
+	t.Run("should not put cancellations before the statement timeout down to it", func(t *testing.T) {
+		opts := DefaultExecutorOptions()
+		opts.SQL.StatementTimeout = 30 * time.Second
+		executor := newStandInExecutor(t, opts, cancelledHandler(0))
+
+		// As when another session cancels the statement with pg_cancel_backend.
+		result, _ := executor.Execute(context.Background(), "SELECT pg_sleep(60)", "")
+		if result.ExitCode != ExitCodePostgresQueryError || result.Limit != "" || result.SQLState != "57014" {
+			t.Fatalf("Expected a query error with SQLSTATE 57014, got %d %q %q", result.ExitCode, result.Limit, result.SQLState)
+		}
+
+		// As when the caller's context is cancelled without ExecutionManager.
+		opts.SQL.StatementTimeout = 50 * time.Millisecond
+		executor = newStandInExecutor(t, opts, cancelledHandler(200*time.Millisecond))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		time.AfterFunc(100*time.Millisecond, cancel)
+		result, _ = executor.Execute(ctx, "SELECT pg_sleep(60)", "")
+		if result.ExitCode != ExitCodeCancelled || result.Limit != "" {
+			t.Fatalf("Expected the run to be cancelled, got %d %q: %s", result.ExitCode, result.Limit, result.Error)
+		}
+	})
+
+	t.Run("should apply changed limits to open connections", func(t *testing.T) {
+		log := &transactionLog{}
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), log.handler)
+		executor.SetSessionMode(true)
+		executor.Execute(context.Background(), "BEGIN", "")
+
+		opts := DefaultExecutorOptions()
+		opts.SQL.StatementTimeout = 2 * time.Second
+		executor.setOptions(opts)
+
+		executor.Execute(context.Background(), "INSERT INTO t VALUES (1)", "")
+		set := "SET statement_timeout = 2000; SET lock_timeout TO DEFAULT; SET idle_in_transaction_session_timeout TO DEFAULT"
+		if log.contains(set) {
+			t.Fatal("Expected the limits to wait for the transaction to end")
+		}
+
+		executor.Commit(context.Background())
+		executor.Execute(context.Background(), "INSERT INTO t VALUES (2)", "")
+		if !log.contains(set) {
+			t.Fatalf("Expected the limits to be applied, got %v", log.queries)
+		}
+	})
+
+	t.Run("should return to the server's setting when a limit is cleared", func(t *testing.T) {
+		log := &transactionLog{}
+		opts := DefaultExecutorOptions()
+		opts.SQL.StatementTimeout = 2 * time.Second
+		executor := newStandInExecutor(t, opts, log.handler)
+
+		executor.Execute(context.Background(), "INSERT INTO t VALUES (1)", "")
+		if !log.contains("SET statement_timeout = 2000; SET lock_timeout TO DEFAULT; SET idle_in_transaction_session_timeout TO DEFAULT") {
+			t.Fatalf("Expected the limit to be set on the connection, got %v", log.queries)
+		}
+		if _, ok := executor.pool.Config().ConnConfig.RuntimeParams["statement_timeout"]; ok {
+			t.Fatal("Expected no limits in the startup parameters, which would become the session default")
+		}
+
+		opts.SQL.StatementTimeout = 0
+		executor.setOptions(opts)
+		executor.Execute(context.Background(), "INSERT INTO t VALUES (2)", "")
+		if !log.contains("SET statement_timeout TO DEFAULT; SET lock_timeout TO DEFAULT; SET idle_in_transaction_session_timeout TO DEFAULT") {
+			t.Fatalf("Expected the limit to be reset, got %v", log.queries)
+		}
+	})
+
+	t.Run("should send a cancel request when the run times out", func(t *testing.T) {
+		clearPGEnv(t)
+		cancelled := make(chan struct{})
+		var once sync.Once
+		port := listenPostgresStandIn(t, nil, func(query string) []standInResult {
+			if !strings.Contains(query, "pg_sleep") {
+				return []standInResult{{Tag: "SELECT 0", Columns: textColumns("?column?")}}
+			}
+			select {
+			case <-cancelled:
+			case <-time.After(5 * time.Second):
+			}
+			return []standInResult{{Err: &pgproto3.ErrorResponse{
+				Severity: "ERROR", Code: "57014", Message: "canceling statement due to user request",
+			}}}
+		}, func() { once.Do(func() { close(cancelled) }) })
+
+		// A statement timeout is set too, but did not stop the statement.
+		opts := DefaultExecutorOptions()
+		opts.SQL.StatementTimeout = time.Minute
+		executor := NewPostgreSQLExecutor(opts)
+		executor.SetConfig(&PostgreSQLConfig{
+			Host: "127.0.0.1", Port: port, Database: "app", Username: "postgres", SSLMode: "disable",
+		})
+		t.Cleanup(func() { executor.Cleanup() })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		result, _ := executor.Execute(ctx, "SELECT pg_sleep(60)", "")
+
+		select {
+		case <-cancelled:
+		default:
+			t.Fatal("Expected a cancel request")
+		}
+		if time.Since(start) > 4*time.Second {
+			t.Errorf("Expected the cancel request to end the statement, took %v", time.Since(start))
+		}
+		if result.ExitCode != ExitCodeTimeout || result.Limit != LimitRunTimeout || result.SQLState != "57014" {
+			t.Fatalf("Expected the run's timeout with SQLSTATE 57014, got %d %q %q", result.ExitCode, result.Limit, result.SQLState)
+		}
+	})
+}
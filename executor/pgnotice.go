@@ -0,0 +1,117 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// noticeCollector gathers the notices the pool's connections receive while a
+// run collects them. Connections hand notices over while they read a
+// statement's results, when the executor already holds p.mu, so the
+// collector has a lock of its own.
+type noticeCollector struct {
+	mu        sync.Mutex
+	active    bool
+	statement int // Index of the statement that is running
+	notices   []collectedNotice
+}
+
+type collectedNotice struct {
+	statement int
+	notice    *pgconn.Notice
+}
+
+// start begins collecting notices, for the first statement.
+func (c *noticeCollector) start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.active = true
+	c.statement = 0
+	c.notices = nil
+}
+
+// setStatement attributes the notices that follow to the statement at index i.
+func (c *noticeCollector) setStatement(i int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.statement = i
+}
+
+// stop ends collecting and returns the notices in the order they arrived.
+func (c *noticeCollector) stop() []collectedNotice {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	notices := c.notices
+	c.active = false
+	c.notices = nil
+	return notices
+}
+
+// add is the pool's OnNotice handler. Notices outside runs are dropped.
+func (c *noticeCollector) add(_ *pgconn.PgConn, notice *pgconn.Notice) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.active {
+		c.notices = append(c.notices, collectedNotice{statement: c.statement, notice: notice})
+	}
+}
+
+// sqlNotices converts collected notices of the statements of sqlCode, pointing
+// each at the line of the user's code it came from: the reported position if
+// there is one, otherwise the start of its statement.
+func sqlNotices(collected []collectedNotice, sqlCode string, statements []sqlStatement, sqlMap []sqlLineOrigin) []SQLNotice {
+	if len(collected) == 0 {
+		return nil
+	}
+
+	notices := make([]SQLNotice, len(collected))
+	for i, c := range collected {
+		notice := SQLNotice{
+			Severity:  c.notice.Severity,
+			Code:      c.notice.Code,
+			Message:   c.notice.Message,
+			Detail:    c.notice.Detail,
+			Hint:      c.notice.Hint,
+			Where:     c.notice.Where,
+			Position:  int(c.notice.Position),
+			Statement: c.statement,
+		}
+		if c.statement < len(statements) {
+			offset := statements[c.statement].Offset
+			notice.Line = statementLine(sqlCode, offset, sqlMap)
+			if diagnostic := postgresDiagnostic((*pgconn.PgError)(c.notice), sqlCode, offset, sqlMap); diagnostic != nil && diagnostic.Line > 0 {
+				notice.Line, notice.Column = diagnostic.Line, diagnostic.Column
+			}
+		}
+		notices[i] = notice
+	}
+	return notices
+}
+
+// formatNotices renders notices as psql shows them.
+func formatNotices(notices []SQLNotice) string {
+	var output strings.Builder
+	for _, notice := range notices {
+		fmt.Fprintf(&output, "%s:  %s\n", notice.Severity, notice.Message)
+		if notice.Detail != "" {
+			fmt.Fprintf(&output, "DETAIL:  %s\n", notice.Detail)
+		}
+		if notice.Hint != "" {
+			fmt.Fprintf(&output, "HINT:  %s\n", notice.Hint)
+		}
+		if notice.Where != "" {
+			fmt.Fprintf(&output, "CONTEXT:  %s\n", notice.Where)
+		}
+	}
+	return output.String()
+}
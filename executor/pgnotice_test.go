@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+func TestPostgreSQLExecutor_Notices(t *testing.T) {
+	handler := func(query string) []standInResult {
+		switch {
+		case strings.HasPrefix(query, "DO"):
+			return []standInResult{{Tag: "DO", Notices: []pgproto3.NoticeResponse{
+				{Severity: "NOTICE", Code: "00000", Message: "migrating 3 rows", Where: "PL/pgSQL function inline_code_block line 3 at RAISE"},
+				{Severity: "WARNING", Code: "01000", Message: "column is deprecated", Hint: "use total instead"},
+			}}}
+		case strings.HasPrefix(query, "SELECT check_rows"):
+			return []standInResult{{
+				Notices: []pgproto3.NoticeResponse{{Severity: "NOTICE", Code: "00000", Message: "checking", Position: 8}},
+				Err:     &pgproto3.ErrorResponse{Severity: "ERROR", Code: "P0001", Message: "too many rows"},
+			}}
+		}
+		return []standInResult{{Tag: "SELECT 0", Columns: textColumns("?column?")}}
+	}
+
+	t.Run("should return notices in order with their statements", func(t *testing.T) {
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), handler)
+
+		result, err := executor.Execute(context.Background(), "SELECT 1;\n\nDO $$\nBEGIN\n  RAISE NOTICE 'migrating % rows', 3;\nEND $$", "")
+		if err != nil || result.ExitCode != 0 {
+			t.Fatalf("Expected the run to succeed, got %v %q", err, result.Error)
+		}
+		if len(result.Notices) != 2 {
+			t.Fatalf("Expected 2 notices, got %+v", result.Notices)
+		}
+		first, second := result.Notices[0], result.Notices[1]
+		if first.Severity != "NOTICE" || first.Message != "migrating 3 rows" || first.Statement != 1 || first.Line != 3 || first.Where == "" {
+			t.Errorf("Expected the notice of the DO block on line 3, got %+v", first)
+		}
+		if second.Severity != "WARNING" || second.Hint != "use total instead" {
+			t.Errorf("Expected the warning second, got %+v", second)
+		}
+		if !strings.Contains(result.Output, "NOTICE:  migrating 3 rows\nCONTEXT:") || !strings.Contains(result.Output, "HINT:  use total instead") {
+			t.Errorf("Expected the notices in the output, got:\n%s", result.Output)
+		}
+	})
+
+	t.Run("should keep notices of a failed statement", func(t *testing.T) {
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), handler)
+
+		result, _ := executor.Execute(context.Background(), "SELECT 1;\nSELECT check_rows()", "")
+		if result.ExitCode != ExitCodePostgresQueryError {
+			t.Fatalf("Expected the run to fail, got %d", result.ExitCode)
+		}
+		if len(result.Notices) != 1 || result.Notices[0].Line != 2 || result.Notices[0].Column != 8 {
+			t.Fatalf("Expected the notice at line 2, column 8, got %+v", result.Notices)
+		}
+	})
+
+	t.Run("should drop notices outside runs", func(t *testing.T) {
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), handler)
+		executor.Execute(context.Background(), "DO $$ BEGIN END $$", "")
+
+		result, _ := executor.Execute(context.Background(), "SELECT 1", "")
+		if len(result.Notices) != 0 {
+			t.Fatalf("Expected no notices, got %+v", result.Notices)
+		}
+	})
+}
@@ -0,0 +1,292 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// userSchemas leaves out PostgreSQL's own schemas, including those of
+// temporary tables, from catalog queries on the namespace n.
+const userSchemas = `n.nspname !~ '^pg_' AND n.nspname <> 'information_schema'`
+
+// foreignKeyAction spells out a pg_constraint action code.
+func foreignKeyAction(column string) string {
+	return fmt.Sprintf(`CASE %s WHEN 'a' THEN 'NO ACTION' WHEN 'r' THEN 'RESTRICT' WHEN 'c' THEN 'CASCADE'
+  WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT' END`, column)
+}
+
+// attributeNames lists the names of the attributes of relation whose numbers
+// are in the array attnums, in order, as a JSON array.
+func attributeNames(relation, attnums string) string {
+	return fmt.Sprintf(`coalesce((SELECT json_agg(a.attname ORDER BY k.ord)
+  FROM unnest(%s::int2[]) WITH ORDINALITY k(attnum, ord)
+  JOIN pg_catalog.pg_attribute a ON a.attrelid = %s AND a.attnum = k.attnum), '[]')`, attnums, relation)
+}
+
+// schemaQueries read the catalog for loadSchema, one JSON array of objects
+// per query. Their order is the order of the fields of schemaRows.
+var schemaQueries = []string{
+	`SELECT n.nspname AS "name", pg_catalog.pg_get_userbyid(n.nspowner) AS "owner",
+  pg_catalog.obj_description(n.oid, 'pg_namespace') AS "comment"
+FROM pg_catalog.pg_namespace n
+WHERE ` + userSchemas + ` AND pg_catalog.has_schema_privilege(n.oid, 'USAGE')
+ORDER BY n.nspname`,
+
+	`SELECT n.nspname AS "schema", c.relname AS "name",
+  CASE c.relkind WHEN 'r' THEN 'table' WHEN 'p' THEN 'partitionedTable' WHEN 'v' THEN 'view'
+    WHEN 'm' THEN 'materializedView' WHEN 'f' THEN 'foreignTable' END AS "kind",
+  pg_catalog.obj_description(c.oid, 'pg_class') AS "comment"
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f') AND ` + userSchemas + `
+ORDER BY n.nspname, c.relname`,
+
+	`SELECT n.nspname AS "schema", c.relname AS "table", a.attname AS "name",
+  pg_catalog.format_type(a.atttypid, a.atttypmod) AS "type", NOT a.attnotnull AS "nullable",
+  pg_catalog.pg_get_expr(d.adbin, d.adrelid) AS "default",
+  CASE a.attidentity WHEN 'a' THEN 'always' WHEN 'd' THEN 'by default' END AS "identity",
+  a.attnum AS "position", pg_catalog.col_description(c.oid, a.attnum) AS "comment"
+FROM pg_catalog.pg_attribute a
+JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_catalog.pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE a.attnum > 0 AND NOT a.attisdropped AND c.relkind IN ('r', 'p', 'v', 'm', 'f') AND ` + userSchemas + `
+ORDER BY n.nspname, c.relname, a.attnum`,
+
+	`SELECT n.nspname AS "schema", c.relname AS "table", i.relname AS "name",
+  ` + attributeNames("x.indrelid", "x.indkey") + ` AS "columns",
+  x.indisunique AS "unique", x.indisprimary AS "primary", am.amname AS "method",
+  pg_catalog.pg_get_indexdef(x.indexrelid) AS "definition"
+FROM pg_catalog.pg_index x
+JOIN pg_catalog.pg_class i ON i.oid = x.indexrelid
+JOIN pg_catalog.pg_class c ON c.oid = x.indrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+JOIN pg_catalog.pg_am am ON am.oid = i.relam
+WHERE ` + userSchemas + `
+ORDER BY n.nspname, c.relname, i.relname`,
+
+	`SELECT n.nspname AS "schema", c.relname AS "table", k.conname AS "name",
+  CASE k.contype WHEN 'p' THEN 'primaryKey' WHEN 'u' THEN 'unique' WHEN 'c' THEN 'check'
+    WHEN 'x' THEN 'exclusion' END AS "kind",
+  ` + attributeNames("k.conrelid", "k.conkey") + ` AS "columns",
+  pg_catalog.pg_get_constraintdef(k.oid) AS "definition"
+FROM pg_catalog.pg_constraint k
+JOIN pg_catalog.pg_class c ON c.oid = k.conrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE k.contype IN ('p', 'u', 'c', 'x') AND ` + userSchemas + `
+ORDER BY n.nspname, c.relname, k.conname`,
+
+	`SELECT n.nspname AS "schema", c.relname AS "table", k.conname AS "name",
+  ` + attributeNames("k.conrelid", "k.conkey") + ` AS "columns",
+  rn.nspname AS "referencedSchema", r.relname AS "referencedTable",
+  ` + attributeNames("k.confrelid", "k.confkey") + ` AS "referencedColumns",
+  ` + foreignKeyAction("k.confupdtype") + ` AS "onUpdate",
+  ` + foreignKeyAction("k.confdeltype") + ` AS "onDelete"
+FROM pg_catalog.pg_constraint k
+JOIN pg_catalog.pg_class c ON c.oid = k.conrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+JOIN pg_catalog.pg_class r ON r.oid = k.confrelid
+JOIN pg_catalog.pg_namespace rn ON rn.oid = r.relnamespace
+WHERE k.contype = 'f' AND ` + userSchemas + `
+ORDER BY n.nspname, c.relname, k.conname`,
+
+	`SELECT n.nspname AS "schema", p.proname AS "name",
+  CASE p.prokind WHEN 'f' THEN 'function' WHEN 'p' THEN 'procedure' WHEN 'a' THEN 'aggregate'
+    WHEN 'w' THEN 'window' END AS "kind",
+  pg_catalog.pg_get_function_identity_arguments(p.oid) AS "arguments",
+  pg_catalog.pg_get_function_result(p.oid) AS "result", l.lanname AS "language",
+  pg_catalog.obj_description(p.oid, 'pg_proc') AS "comment"
+FROM pg_catalog.pg_proc p
+JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+JOIN pg_catalog.pg_language l ON l.oid = p.prolang
+WHERE ` + userSchemas + `
+ORDER BY n.nspname, p.proname, 4`,
+
+	`SELECT n.nspname AS "schema", c.relname AS "name",
+  pg_catalog.format_type(s.seqtypid, NULL) AS "dataType", s.seqstart::text AS "start",
+  s.seqincrement::text AS "increment", s.seqmin::text AS "min", s.seqmax::text AS "max",
+  s.seqcycle AS "cycle"
+FROM pg_catalog.pg_sequence s
+JOIN pg_catalog.pg_class c ON c.oid = s.seqrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE ` + userSchemas + `
+ORDER BY n.nspname, c.relname`,
+}
+
+// tableObject is a catalog row of an object that belongs to a table.
+type tableObject struct {
+	Schema string `json:"schema"`
+	Table  string `json:"table"`
+}
+
+// schemaRows holds the results of schemaQueries.
+type schemaRows struct {
+	schemas   []SchemaInfo
+	relations []struct {
+		Schema string `json:"schema"`
+		TableInfo
+	}
+	columns []struct {
+		tableObject
+		ColumnInfo
+	}
+	indexes []struct {
+		tableObject
+		IndexInfo
+	}
+	constraints []struct {
+		tableObject
+		ConstraintInfo
+	}
+	foreignKeys []struct {
+		tableObject
+		ForeignKeyInfo
+	}
+	functions []struct {
+		Schema string `json:"schema"`
+		FunctionInfo
+	}
+	sequences []struct {
+		Schema string `json:"schema"`
+		SequenceInfo
+	}
+}
+
+// Schema returns the objects of the connected database, loading them on
+// first use. The result is cached until RefreshSchema is called or the
+// connection changes.
+func (p *PostgreSQLExecutor) Schema(ctx context.Context) (*DatabaseSchema, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.schema != nil {
+		return p.schema, nil
+	}
+	return p.loadSchema(ctx)
+}
+
+// RefreshSchema reloads the objects of the connected database, for example
+// after tables were created or altered.
+func (p *PostgreSQLExecutor) RefreshSchema(ctx context.Context) (*DatabaseSchema, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.schema = nil
+	return p.loadSchema(ctx)
+}
+
+// loadSchema reads the catalog on a connection of its own, so that the
+// session's transaction is neither seen nor disturbed. The caller must hold
+// p.mu.
+func (p *PostgreSQLExecutor) loadSchema(ctx context.Context) (*DatabaseSchema, error) {
+	if !p.isAvailableInternal() {
+		return nil, fmt.Errorf("PostgreSQL connection is not configured")
+	}
+	if err := p.ensureConnection(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	queries := make([]string, len(schemaQueries))
+	for i, query := range schemaQueries {
+		queries[i] = fmt.Sprintf("SELECT coalesce(json_agg(q), '[]') FROM (%s) q", query)
+	}
+	results, err := conn.Conn().PgConn().Exec(ctx, strings.Join(queries, ";\n")).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the schema: %w", err)
+	}
+
+	var rows schemaRows
+	targets := []interface{}{&rows.schemas, &rows.relations, &rows.columns, &rows.indexes,
+		&rows.constraints, &rows.foreignKeys, &rows.functions, &rows.sequences}
+	if len(results) != len(targets) {
+		return nil, fmt.Errorf("failed to read the schema: expected %d results, got %d", len(targets), len(results))
+	}
+	for i, result := range results {
+		if len(result.Rows) != 1 || len(result.Rows[0]) != 1 {
+			return nil, fmt.Errorf("failed to read the schema: unexpected result of query %d", i+1)
+		}
+		if err := json.Unmarshal(result.Rows[0][0], targets[i]); err != nil {
+			return nil, fmt.Errorf("failed to read the schema: %w", err)
+		}
+	}
+
+	p.schema = rows.assemble()
+	log.Printf("PostgreSQL Executor: Loaded schema with %d schemas", len(p.schema.Schemas))
+	return p.schema, nil
+}
+
+// assemble builds the schema tree from the catalog rows. Objects of schemas
+// or tables the rows do not list are left out.
+func (rows *schemaRows) assemble() *DatabaseSchema {
+	schema := &DatabaseSchema{Schemas: rows.schemas, LoadedAt: time.Now()}
+	if schema.Schemas == nil {
+		schema.Schemas = []SchemaInfo{}
+	}
+
+	schemas := make(map[string]*SchemaInfo, len(schema.Schemas))
+	for i := range schema.Schemas {
+		info := &schema.Schemas[i]
+		info.Tables, info.Functions, info.Sequences = []TableInfo{}, []FunctionInfo{}, []SequenceInfo{}
+		schemas[info.Name] = info
+	}
+
+	for _, relation := range rows.relations {
+		if info, ok := schemas[relation.Schema]; ok {
+			relation.Columns = []ColumnInfo{}
+			info.Tables = append(info.Tables, relation.TableInfo)
+		}
+	}
+	// The tables are all in place, so pointers to them stay valid.
+	tables := make(map[tableObject]*TableInfo)
+	for _, info := range schemas {
+		for i := range info.Tables {
+			tables[tableObject{Schema: info.Name, Table: info.Tables[i].Name}] = &info.Tables[i]
+		}
+	}
+
+	for _, column := range rows.columns {
+		if table, ok := tables[column.tableObject]; ok {
+			table.Columns = append(table.Columns, column.ColumnInfo)
+		}
+	}
+	for _, index := range rows.indexes {
+		if table, ok := tables[index.tableObject]; ok {
+			table.Indexes = append(table.Indexes, index.IndexInfo)
+		}
+	}
+	for _, constraint := range rows.constraints {
+		if table, ok := tables[constraint.tableObject]; ok {
+			table.Constraints = append(table.Constraints, constraint.ConstraintInfo)
+		}
+	}
+	for _, foreignKey := range rows.foreignKeys {
+		if table, ok := tables[foreignKey.tableObject]; ok {
+			table.ForeignKeys = append(table.ForeignKeys, foreignKey.ForeignKeyInfo)
+		}
+	}
+	for _, function := range rows.functions {
+		if info, ok := schemas[function.Schema]; ok {
+			info.Functions = append(info.Functions, function.FunctionInfo)
+		}
+	}
+	for _, sequence := range rows.sequences {
+		if info, ok := schemas[sequence.Schema]; ok {
+			info.Sequences = append(info.Sequences, sequence.SequenceInfo)
+		}
+	}
+
+	return schema
+}
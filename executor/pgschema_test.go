@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// schemaCatalog is what the catalog queries of loadSchema return for a
+// database with a users and an orders table.
+var schemaCatalog = []string{
+	`[{"name": "public", "owner": "postgres", "comment": "standard public schema"}]`,
+	`[{"schema": "public", "name": "orders", "kind": "table", "comment": null},
+	  {"schema": "public", "name": "users", "kind": "table", "comment": "Registered users"},
+	  {"schema": "public", "name": "active_users", "kind": "view", "comment": null},
+	  {"schema": "hidden", "name": "secrets", "kind": "table", "comment": null}]`,
+	`[{"schema": "public", "table": "orders", "name": "id", "type": "bigint", "nullable": false,
+	   "default": null, "identity": "always", "position": 1, "comment": null},
+	  {"schema": "public", "table": "orders", "name": "user_id", "type": "integer", "nullable": true,
+	   "default": null, "identity": null, "position": 2, "comment": null},
+	  {"schema": "public", "table": "users", "name": "id", "type": "integer", "nullable": false,
+	   "default": "nextval('users_id_seq'::regclass)", "identity": null, "position": 1, "comment": null}]`,
+	`[{"schema": "public", "table": "users", "name": "users_pkey", "columns": ["id"], "unique": true,
+	   "primary": true, "method": "btree", "definition": "CREATE UNIQUE INDEX users_pkey ON public.users USING btree (id)"}]`,
+	`[{"schema": "public", "table": "users", "name": "users_pkey", "kind": "primaryKey", "columns": ["id"],
+	   "definition": "PRIMARY KEY (id)"}]`,
+	`[{"schema": "public", "table": "orders", "name": "orders_user_id_fkey", "columns": ["user_id"],
+	   "referencedSchema": "public", "referencedTable": "users", "referencedColumns": ["id"],
+	   "onUpdate": "NO ACTION", "onDelete": "CASCADE"}]`,
+	`[{"schema": "public", "name": "order_total", "kind": "function", "arguments": "order_id bigint",
+	   "result": "numeric", "language": "sql", "comment": null}]`,
+	`[{"schema": "public", "name": "users_id_seq", "dataType": "integer", "start": "1", "increment": "1",
+	   "min": "1", "max": "2147483647", "cycle": false}]`,
+}
+
+func TestPostgreSQLExecutor_Schema(t *testing.T) {
+	ctx := context.Background()
+	var loads atomic.Int32
+	handler := func(query string) []standInResult {
+		if !strings.Contains(query, "pg_catalog.pg_namespace") {
+			return []standInResult{{Tag: "SELECT 0", Columns: textColumns("?column?")}}
+		}
+		loads.Add(1)
+		results := make([]standInResult, len(schemaCatalog))
+		for i, rows := range schemaCatalog {
+			results[i] = standInResult{Columns: textColumns("coalesce"), Rows: [][]*string{textRow(rows)}, Tag: "SELECT 1"}
+		}
+		return results
+	}
+	executor := newStandInExecutor(t, DefaultExecutorOptions(), handler)
+
+	schema, err := executor.Schema(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	t.Run("should build the schema tree", func(t *testing.T) {
+		if len(schema.Schemas) != 1 || schema.Schemas[0].Name != "public" {
+			t.Fatalf("Expected the public schema, got %+v", schema.Schemas)
+		}
+		public := schema.Schemas[0]
+		if len(public.Tables) != 3 || len(public.Functions) != 1 || len(public.Sequences) != 1 {
+			t.Fatalf("Expected 3 tables, 1 function and 1 sequence, got %+v", public)
+		}
+
+		orders, users, view := public.Tables[0], public.Tables[1], public.Tables[2]
+		if len(orders.Columns) != 2 || orders.Columns[0].Identity != "always" || !orders.Columns[1].Nullable {
+			t.Errorf("Expected the columns of orders, got %+v", orders.Columns)
+		}
+		if len(orders.ForeignKeys) != 1 || orders.ForeignKeys[0].ReferencedTable != "users" || orders.ForeignKeys[0].OnDelete != "CASCADE" {
+			t.Errorf("Expected the foreign key of orders, got %+v", orders.ForeignKeys)
+		}
+		if users.Comment != "Registered users" || users.Columns[0].Default != "nextval('users_id_seq'::regclass)" {
+			t.Errorf("Expected the comment and column default of users, got %+v", users)
+		}
+		if len(users.Indexes) != 1 || !users.Indexes[0].Primary || len(users.Constraints) != 1 || users.Constraints[0].Kind != ConstraintPrimaryKey {
+			t.Errorf("Expected the primary key of users, got %+v %+v", users.Indexes, users.Constraints)
+		}
+		if view.Kind != TableKindView || view.Columns == nil {
+			t.Errorf("Expected a view with an empty column list, got %+v", view)
+		}
+		if public.Sequences[0].Max != "2147483647" {
+			t.Errorf("Expected the sequence maximum as a string, got %+v", public.Sequences[0])
+		}
+	})
+
+	t.Run("should cache the schema until it is refreshed", func(t *testing.T) {
+		if cached, _ := executor.Schema(ctx); cached != schema || loads.Load() != 1 {
+			t.Fatalf("Expected the cached schema, got %d loads", loads.Load())
+		}
+		refreshed, err := executor.RefreshSchema(ctx)
+		if err != nil || refreshed == schema || loads.Load() != 2 {
+			t.Fatalf("Expected the schema to be reloaded, got %v and %d loads", err, loads.Load())
+		}
+
+		executor.SetConfig(executor.Config())
+		if executor.schema != nil {
+			t.Fatal("Expected a new connection to drop the cached schema")
+		}
+	})
+}
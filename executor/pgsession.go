@@ -0,0 +1,250 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// sandboxSavepoint is the savepoint that a sandboxed run inside an open
+// session transaction is rolled back to.
+const sandboxSavepoint = "codezone_sandbox"
+
+// transactionStatus returns the transaction state of conn as last reported
+// by the server.
+func transactionStatus(conn *pgconn.PgConn) TransactionStatus {
+	switch conn.TxStatus() {
+	case 'T':
+		return TransactionActive
+	case 'E':
+		return TransactionFailed
+	}
+	return TransactionIdle
+}
+
+// acquireConn returns the connection for a run, with the session limits of
+// the options applied, and the function that gives it back. In session mode
+// this is the session's connection, which is kept until the session ends.
+func (p *PostgreSQLExecutor) acquireConn(ctx context.Context) (*pgxpool.Conn, func(), error) {
+	if !p.sessionMode {
+		conn, err := p.pool.Acquire(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := p.applyLimits(ctx, conn); err != nil {
+			conn.Release()
+			return nil, nil, err
+		}
+		return conn, conn.Release, nil
+	}
+
+	if p.session != nil && p.session.Conn().IsClosed() {
+		lost := transactionStatus(p.session.Conn().PgConn())
+		p.session.Release()
+		p.session = nil
+		log.Println("PostgreSQL Executor: Session connection was lost")
+		if lost != TransactionIdle {
+			return nil, nil, fmt.Errorf("the session's connection was lost and its open transaction was rolled back")
+		}
+	}
+	if p.session == nil {
+		conn, err := p.pool.Acquire(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.session = conn
+		log.Println("PostgreSQL Executor: Session connection acquired")
+	}
+	if err := p.applyLimits(ctx, p.session); err != nil {
+		return nil, nil, err
+	}
+	return p.session, func() {}, nil
+}
+
+// beginSandbox starts the transaction that a sandboxed run is rolled back
+// with, or a savepoint if conn is already in a transaction. It returns the
+// function that rolls the run back. If that fails, the connection is closed,
+// so that nothing the run did can be committed later.
+func beginSandbox(ctx context.Context, conn *pgxpool.Conn) (func(), error) {
+	pgConn := conn.Conn().PgConn()
+	begin, rollback := "BEGIN", "ROLLBACK"
+	if pgConn.TxStatus() != 'I' {
+		begin = "SAVEPOINT " + sandboxSavepoint
+		rollback = "ROLLBACK TO SAVEPOINT " + sandboxSavepoint + "; RELEASE SAVEPOINT " + sandboxSavepoint
+	}
+	if _, err := pgConn.Exec(ctx, begin).ReadAll(); err != nil {
+		return nil, fmt.Errorf("failed to start sandbox transaction: %w", err)
+	}
+
+	return func() {
+		// The run's context may be done by now.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := pgConn.Exec(ctx, rollback).ReadAll(); err != nil {
+			log.Printf("PostgreSQL Executor: Failed to roll back sandbox, closing connection: %v", err)
+			pgConn.Close(ctx)
+		}
+	}, nil
+}
+
+// sandboxViolation returns an error for the first statement that would end
+// or start a transaction, which would take a sandboxed run out of its
+// transaction.
+func sandboxViolation(statements []sqlStatement) error {
+	for i, statement := range statements {
+		keywords := make([]string, 0, 2)
+		for _, token := range statement.Tokens {
+			if token.significant() {
+				keywords = append(keywords, token.keyword())
+				if len(keywords) == 2 {
+					break
+				}
+			}
+		}
+		keywords = append(keywords, "", "")
+
+		switch keywords[0] {
+		case "BEGIN", "START", "COMMIT", "END", "ABORT":
+		case "ROLLBACK":
+			if keywords[1] == "TO" {
+				continue
+			}
+		case "PREPARE":
+			if keywords[1] != "TRANSACTION" {
+				continue
+			}
+		default:
+			continue
+		}
+		return fmt.Errorf("statement %d (%s) controls the transaction, which sandbox mode does not allow; every run is rolled back",
+			i+1, statementSummary(statement.Text))
+	}
+	return nil
+}
+
+// SessionStatus reports the session and sandbox modes, and the state of the
+// session's transaction.
+func (p *PostgreSQLExecutor) SessionStatus() SessionStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.sessionStatus()
+}
+
+// sessionStatus is SessionStatus for callers that hold p.mu.
+func (p *PostgreSQLExecutor) sessionStatus() SessionStatus {
+	status := SessionStatus{Session: p.sessionMode, Sandbox: p.sandbox}
+	if p.config != nil {
+		status.ReadOnly, status.SafeMode = p.config.ReadOnly, p.config.SafeMode
+	}
+	if p.session != nil && !p.session.Conn().IsClosed() {
+		status.Connected = true
+		status.Transaction = transactionStatus(p.session.Conn().PgConn())
+	}
+	return status
+}
+
+// SetSessionMode turns session mode on or off. In session mode every run
+// uses the same dedicated connection, so transactions, temporary tables and
+// settings made with SET carry over from one run to the next. Session mode
+// cannot be turned off while the session has an open transaction.
+func (p *PostgreSQLExecutor) SetSessionMode(enabled bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if status := p.sessionStatus(); !enabled && status.Connected && status.Transaction != TransactionIdle {
+		return fmt.Errorf("the session has an open transaction; commit or roll it back first")
+	}
+	p.sessionMode = enabled
+	if !enabled {
+		p.closeCursor()
+		p.closeSession()
+	}
+	log.Printf("PostgreSQL Executor: Session mode %s", onOff(enabled))
+	return nil
+}
+
+// SetSandbox turns sandbox mode on or off. In sandbox mode every run happens
+// in a transaction that is rolled back when the run ends, or, inside an open
+// session transaction, rolled back to a savepoint.
+func (p *PostgreSQLExecutor) SetSandbox(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sandbox = enabled
+	log.Printf("PostgreSQL Executor: Sandbox mode %s", onOff(enabled))
+}
+
+// Commit commits the session's open transaction. A failed transaction cannot
+// be committed; PostgreSQL rolls it back instead, which is reported as an
+// error.
+func (p *PostgreSQLExecutor) Commit(ctx context.Context) (SessionStatus, error) {
+	return p.endTransaction(ctx, "COMMIT")
+}
+
+// Rollback rolls back the session's open transaction.
+func (p *PostgreSQLExecutor) Rollback(ctx context.Context) (SessionStatus, error) {
+	return p.endTransaction(ctx, "ROLLBACK")
+}
+
+func (p *PostgreSQLExecutor) endTransaction(ctx context.Context, command string) (SessionStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.session == nil || p.session.Conn().IsClosed() {
+		return p.sessionStatus(), fmt.Errorf("no session is open")
+	}
+	// An open result of the session would hold up the command.
+	p.closeCursor()
+
+	pgConn := p.session.Conn().PgConn()
+	previous := transactionStatus(pgConn)
+	if previous == TransactionIdle {
+		return p.sessionStatus(), fmt.Errorf("no transaction is open")
+	}
+
+	results, err := pgConn.Exec(ctx, command).ReadAll()
+	if err != nil {
+		return p.sessionStatus(), fmt.Errorf("failed to run %s: %w", command, err)
+	}
+	log.Printf("PostgreSQL Executor: Session transaction ended with %s", command)
+
+	if command == "COMMIT" && len(results) > 0 && results[0].CommandTag.String() == "ROLLBACK" {
+		return p.sessionStatus(), fmt.Errorf("the transaction had failed and was rolled back instead of committed")
+	}
+	return p.sessionStatus(), nil
+}
+
+// closeSession gives back the session's connection, rolling back its open
+// transaction. The caller must hold p.mu.
+func (p *PostgreSQLExecutor) closeSession() {
+	if p.session == nil {
+		return
+	}
+
+	if conn := p.session.Conn(); !conn.IsClosed() && conn.PgConn().TxStatus() != 'I' {
+		log.Println("PostgreSQL Executor: Rolling back the session's open transaction")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if _, err := conn.PgConn().Exec(ctx, "ROLLBACK").ReadAll(); err != nil {
+			log.Printf("PostgreSQL Executor: Failed to roll back session transaction: %v", err)
+		}
+		cancel()
+	}
+	p.session.Release()
+	p.session = nil
+	log.Println("PostgreSQL Executor: Session connection released")
+}
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,182 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// transactionLog records the queries a transactionHandler answered.
+type transactionLog struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (l *transactionLog) contains(query string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, q := range l.queries {
+		if q == query {
+			return true
+		}
+	}
+	return false
+}
+
+// handler answers transaction control statements with the
+// transaction status they leave, "SELECT fail" with an error, and everything
+// else as an insert.
+func (l *transactionLog) handler(query string) []standInResult {
+	l.mu.Lock()
+	l.queries = append(l.queries, query)
+	l.mu.Unlock()
+
+	switch {
+	case query == "BEGIN":
+		return []standInResult{{Tag: "BEGIN", TxStatus: 'T'}}
+	case query == "COMMIT":
+		return []standInResult{{Tag: "COMMIT", TxStatus: 'I'}}
+	case query == "ROLLBACK":
+		return []standInResult{{Tag: "ROLLBACK", TxStatus: 'I'}}
+	case strings.HasPrefix(query, "SAVEPOINT"):
+		return []standInResult{{Tag: "SAVEPOINT"}}
+	case strings.HasPrefix(query, "ROLLBACK TO SAVEPOINT"):
+		return []standInResult{{Tag: "ROLLBACK", TxStatus: 'T'}, {Tag: "RELEASE"}}
+	case query == "SELECT fail":
+		return []standInResult{{Err: &pgproto3.ErrorResponse{Severity: "ERROR", Code: "22012", Message: "division by zero"}}}
+	}
+	return []standInResult{{Tag: "INSERT 0 1"}}
+}
+
+func TestPostgreSQLExecutor_Session(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should keep a transaction open across runs in session mode", func(t *testing.T) {
+		log := &transactionLog{}
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), log.handler)
+		executor.SetSessionMode(true)
+
+		result, err := executor.Execute(ctx, "BEGIN; INSERT INTO t VALUES (1)", "")
+		if err != nil || result.ExitCode != 0 {
+			t.Fatalf("Expected the run to succeed, got %v %q", err, result.Error)
+		}
+		if result.Transaction != TransactionActive {
+			t.Fatalf("Expected an open transaction, got %q", result.Transaction)
+		}
+		session := executor.session
+
+		result, _ = executor.Execute(ctx, "INSERT INTO t VALUES (2)", "")
+		if result.Transaction != TransactionActive || executor.session != session {
+			t.Fatalf("Expected the run to use the session's open transaction, got %q", result.Transaction)
+		}
+		if err := executor.SetSessionMode(false); err == nil {
+			t.Fatal("Expected an error turning off session mode with an open transaction")
+		}
+
+		status, err := executor.Commit(ctx)
+		if err != nil {
+			t.Fatalf("Expected the commit to succeed, got %v", err)
+		}
+		if !status.Session || !status.Connected || status.Transaction != TransactionIdle || !log.contains("COMMIT") {
+			t.Fatalf("Expected an idle session after the commit, got %+v", status)
+		}
+		if _, err := executor.Rollback(ctx); err == nil || !strings.Contains(err.Error(), "no transaction is open") {
+			t.Fatalf("Expected an error rolling back without a transaction, got %v", err)
+		}
+	})
+
+	t.Run("should report a failed transaction and roll it back", func(t *testing.T) {
+		log := &transactionLog{}
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), log.handler)
+		executor.SetSessionMode(true)
+
+		result, _ := executor.Execute(ctx, "BEGIN; SELECT fail", "")
+		if result.Transaction != TransactionFailed {
+			t.Fatalf("Expected a failed transaction, got %q", result.Transaction)
+		}
+
+		status, err := executor.Rollback(ctx)
+		if err != nil || status.Transaction != TransactionIdle {
+			t.Fatalf("Expected the rollback to end the transaction, got %+v %v", status, err)
+		}
+		if err := executor.SetSessionMode(false); err != nil {
+			t.Fatalf("Expected session mode to turn off, got %v", err)
+		}
+		if executor.SessionStatus().Connected {
+			t.Fatal("Expected the session's connection to be released")
+		}
+	})
+
+	t.Run("should warn when a run leaves a transaction open outside session mode", func(t *testing.T) {
+		log := &transactionLog{}
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), log.handler)
+
+		result, _ := executor.Execute(ctx, "BEGIN; INSERT INTO t VALUES (1)", "")
+		if result.Transaction != "" {
+			t.Errorf("Expected no transaction status outside session mode, got %q", result.Transaction)
+		}
+		if len(result.Diagnostics) != 1 || result.Diagnostics[0].Severity != SeverityWarning ||
+			!strings.Contains(result.Diagnostics[0].Message, "rolled back") {
+			t.Fatalf("Expected a rollback warning, got %+v", result.Diagnostics)
+		}
+	})
+}
+
+func TestPostgreSQLExecutor_Sandbox(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should roll back every run", func(t *testing.T) {
+		log := &transactionLog{}
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), log.handler)
+		executor.SetSandbox(true)
+
+		result, err := executor.Execute(ctx, "INSERT INTO t VALUES (1)", "")
+		if err != nil || result.ExitCode != 0 {
+			t.Fatalf("Expected the run to succeed, got %v %q", err, result.Error)
+		}
+		if !log.contains("BEGIN") || !log.contains("ROLLBACK") {
+			t.Fatalf("Expected the run to be rolled back, got %v", log.queries)
+		}
+		if len(result.Diagnostics) != 0 {
+			t.Errorf("Expected no warnings, got %+v", result.Diagnostics)
+		}
+	})
+
+	t.Run("should refuse transaction control statements", func(t *testing.T) {
+		log := &transactionLog{}
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), log.handler)
+		executor.SetSandbox(true)
+
+		for _, code := range []string{"COMMIT", "INSERT INTO t VALUES (1); END", "begin", "PREPARE TRANSACTION 'x'"} {
+			result, _ := executor.Execute(ctx, code, "")
+			if result.ExitCode != ExitCodePostgresQueryError || !strings.Contains(result.Error, "sandbox") {
+				t.Errorf("Expected %q to be refused, got %d %q", code, result.ExitCode, result.Error)
+			}
+		}
+		if log.contains("INSERT INTO t VALUES (1)") {
+			t.Error("Expected refused runs not to reach the server")
+		}
+		if err := sandboxViolation(splitSQLStatements("SAVEPOINT a; ROLLBACK TO a; PREPARE q AS SELECT 1")); err != nil {
+			t.Errorf("Expected savepoints and prepared statements to be allowed, got %v", err)
+		}
+	})
+
+	t.Run("should roll back to a savepoint inside a session transaction", func(t *testing.T) {
+		log := &transactionLog{}
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), log.handler)
+		executor.SetSessionMode(true)
+		executor.Execute(ctx, "BEGIN", "")
+		executor.SetSandbox(true)
+
+		result, _ := executor.Execute(ctx, "SELECT fail", "")
+		if result.Transaction != TransactionActive {
+			t.Fatalf("Expected the session transaction to stay open, got %q", result.Transaction)
+		}
+		if !log.contains("SAVEPOINT "+sandboxSavepoint) || executor.SessionStatus().Transaction != TransactionActive {
+			t.Fatalf("Expected the run to be rolled back to its savepoint, got %v", log.queries)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// setTLSServerName overrides the name used for SNI and, in verify-full mode,
+// for checking the server certificate, on every connection attempt.
+func setTLSServerName(config *pgconn.Config, serverName string) {
+	if config.TLSConfig != nil {
+		config.TLSConfig.ServerName = serverName
+	}
+	for _, fallback := range config.Fallbacks {
+		if fallback.TLSConfig != nil {
+			fallback.TLSConfig.ServerName = serverName
+		}
+	}
+}
+
+// explainTLSError adds an explanation and a hint to certificate and TLS
+// errors, which pgx reports in terms of crypto/x509 internals. Other errors are
+// returned unchanged.
+func explainTLSError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return fmt.Errorf("server certificate is not signed by a trusted certificate authority; set the root certificate (sslrootcert) to the CA that issued it: %w", err)
+	case errors.As(err, &hostnameErr):
+		return fmt.Errorf("server certificate is not valid for %q (valid for %s); connect using one of those names or set the TLS server name: %w",
+			hostnameErr.Host, certificateNames(hostnameErr.Certificate), err)
+	case errors.As(err, &invalidCert) && invalidCert.Reason == x509.Expired:
+		return fmt.Errorf("server certificate has expired or is not yet valid; check the server certificate and the system clock: %w", err)
+	case errors.As(err, &invalidCert):
+		return fmt.Errorf("server certificate was rejected: %w", err)
+	}
+
+	// Alerts from the server are only available as text.
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "remote error: tls: certificate required"),
+		strings.Contains(message, "remote error: tls: bad certificate"):
+		return fmt.Errorf("server requires a valid client certificate; set the client certificate and key (sslcert, sslkey): %w", err)
+	case strings.Contains(message, "remote error: tls: unknown certificate authority"):
+		return fmt.Errorf("server does not trust the issuer of the client certificate: %w", err)
+	case strings.Contains(message, "server refused TLS connection"):
+		return fmt.Errorf("server does not support TLS; use sslmode disable or prefer: %w", err)
+	case strings.Contains(message, "unable to find sslpassword"):
+		return fmt.Errorf("client key is encrypted; provide its passphrase (sslpassword): %w", err)
+	case strings.Contains(message, "unable to decrypt key"):
+		return errors.New("client key passphrase (sslpassword) is incorrect")
+	case strings.Contains(message, "unable to read sslkey"), strings.Contains(message, "unable to read cert"):
+		return fmt.Errorf("client certificate or key file could not be read: %w", err)
+	case strings.Contains(message, "failed to decode sslkey"), strings.Contains(message, "unable to load cert"):
+		return fmt.Errorf("client certificate or key is not a valid PEM file, or the key is in a format that is not supported (encrypted keys must be PKCS#1): %w", err)
+	case strings.Contains(message, "unable to add CA to cert pool"):
+		return fmt.Errorf("root certificate file (sslrootcert) contains no valid PEM certificates: %w", err)
+	}
+	return err
+}
+
+// certificateNames lists the host names and addresses a certificate is valid for.
+func certificateNames(cert *x509.Certificate) string {
+	if cert == nil {
+		return "no names"
+	}
+
+	var names []string
+	names = append(names, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	if len(names) == 0 {
+		if cert.Subject.CommonName != "" {
+			return "common name " + cert.Subject.CommonName + " only, without subject alternative names"
+		}
+		return "no names"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,227 @@
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *rsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "codezone test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue creates a certificate for names, which may be host names or IP
+// addresses.
+func (ca *testCA) issue(t *testing.T, usage x509.ExtKeyUsage, names ...string) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "postgres"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// startTLSPostgres starts a stand-in server that requires TLS with
+// serverCert. When clientCAs is set, clients must present a certificate it
+// issued. It returns the server's port.
+func startTLSPostgres(t *testing.T, serverCert tls.Certificate, clientCAs *x509.CertPool) int {
+	t.Helper()
+
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	if clientCAs != nil {
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return startPostgresStandIn(t, tlsConfig, nil)
+}
+
+func writeTestFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func serverCertificate(t *testing.T, ca *testCA, names ...string) tls.Certificate {
+	t.Helper()
+
+	key, certPEM := ca.issue(t, x509.ExtKeyUsageServerAuth, names...)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("Failed to load server certificate: %v", err)
+	}
+	return cert
+}
+
+func connectStandIn(t *testing.T, config *PostgreSQLConfig) error {
+	t.Helper()
+
+	executor := NewPostgreSQLExecutor(ExecutorOptions{})
+	executor.config = config
+	t.Cleanup(func() { executor.Cleanup() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return executor.ensureConnection(ctx)
+}
+
+func TestPostgreSQLExecutor_TLS(t *testing.T) {
+	clearPGEnv(t)
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	rootCert := writeTestFile(t, dir, "root.crt", ca.pem)
+
+	t.Run("should verify the server against the root certificate", func(t *testing.T) {
+		port := startTLSPostgres(t, serverCertificate(t, ca, "localhost", "127.0.0.1"), nil)
+
+		err := connectStandIn(t, &PostgreSQLConfig{
+			Host: "127.0.0.1", Port: port, Username: "postgres", Database: "postgres",
+			SSLMode: "verify-full", SSLRootCert: rootCert,
+		})
+		if err != nil {
+			t.Fatalf("Expected to connect, got %v", err)
+		}
+	})
+
+	t.Run("should explain an untrusted server certificate", func(t *testing.T) {
+		port := startTLSPostgres(t, serverCertificate(t, ca, "127.0.0.1"), nil)
+		otherRoot := writeTestFile(t, dir, "other.crt", newTestCA(t).pem)
+
+		err := connectStandIn(t, &PostgreSQLConfig{
+			Host: "127.0.0.1", Port: port, Username: "postgres",
+			SSLMode: "verify-ca", SSLRootCert: otherRoot,
+		})
+		if err == nil || !strings.Contains(err.Error(), "not signed by a trusted certificate authority") {
+			t.Fatalf("Expected an untrusted certificate error, got %v", err)
+		}
+	})
+
+	t.Run("should explain a host name mismatch and accept a server name override", func(t *testing.T) {
+		port := startTLSPostgres(t, serverCertificate(t, ca, "db.internal"), nil)
+		config := &PostgreSQLConfig{
+			Host: "127.0.0.1", Port: port, Username: "postgres",
+			SSLMode: "verify-full", SSLRootCert: rootCert,
+		}
+
+		err := connectStandIn(t, config)
+		if err == nil || !strings.Contains(err.Error(), `not valid for "127.0.0.1" (valid for db.internal)`) {
+			t.Fatalf("Expected a host name mismatch error, got %v", err)
+		}
+
+		config.SSLServerName = "db.internal"
+		if err := connectStandIn(t, config); err != nil {
+			t.Fatalf("Expected to connect with the server name override, got %v", err)
+		}
+	})
+
+	t.Run("should authenticate with an encrypted client key", func(t *testing.T) {
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(ca.cert)
+		port := startTLSPostgres(t, serverCertificate(t, ca, "127.0.0.1"), clientCAs)
+
+		key, certPEM := ca.issue(t, x509.ExtKeyUsageClientAuth)
+		// sslpassword only supports legacy encrypted PKCS#1 keys, as created by openssl genrsa -aes256.
+		block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key), []byte("passphrase"), x509.PEMCipherAES256)
+		if err != nil {
+			t.Fatalf("Failed to encrypt client key: %v", err)
+		}
+		config := &PostgreSQLConfig{
+			Host: "127.0.0.1", Port: port, Username: "postgres",
+			SSLMode: "verify-full", SSLRootCert: rootCert,
+		}
+
+		err = connectStandIn(t, config)
+		if err == nil || !strings.Contains(err.Error(), "requires a valid client certificate") {
+			t.Fatalf("Expected a client certificate error, got %v", err)
+		}
+
+		config.SSLCert = writeTestFile(t, dir, "client.crt", certPEM)
+		config.SSLKey = writeTestFile(t, dir, "client.key", pem.EncodeToMemory(block))
+		config.SSLPassword = "wrong"
+		err = connectStandIn(t, config)
+		if err == nil || !strings.Contains(err.Error(), "passphrase (sslpassword) is incorrect") {
+			t.Fatalf("Expected an incorrect passphrase error, got %v", err)
+		}
+
+		config.SSLPassword = "passphrase"
+		if err := connectStandIn(t, config); err != nil {
+			t.Fatalf("Expected to connect with the client certificate, got %v", err)
+		}
+	})
+
+	t.Run("should add TLS settings to a connection URI", func(t *testing.T) {
+		config := &PostgreSQLConfig{
+			ConnectionString: "postgres://u@127.0.0.1:5432/app?sslmode=verify-full",
+			SSLRootCert:      rootCert,
+		}
+		settings, err := parseConnSettings(config.connString())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if settings["sslrootcert"] != rootCert || settings["sslmode"] != "verify-full" {
+			t.Fatalf("Expected sslrootcert to be added, got %v", settings)
+		}
+	})
+}
@@ -0,0 +1,311 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// decodeValue decodes a raw column value into a value that keeps everything
+// the server sent and can be passed through convertValue to JSON:
+//
+//   - booleans and integer and floating point types decode to Go values
+//   - json and jsonb are parsed, with numbers kept as json.Number
+//   - arrays decode to nested slices of decoded elements
+//   - bytea decodes to []byte
+//   - every other type, including numeric, date and time types, intervals,
+//     network addresses, ranges and types of extensions, keeps PostgreSQL's
+//     text representation
+func decodeValue(typeMap *pgtype.Map, field pgconn.FieldDescription, raw []byte) (interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	dataType, known := typeMap.TypeForOID(field.DataTypeOID)
+	if field.Format != pgtype.TextFormatCode {
+		if known {
+			return dataType.Codec.DecodeValue(typeMap, field.DataTypeOID, field.Format, raw)
+		}
+		return append([]byte(nil), raw...), nil
+	}
+
+	switch field.DataTypeOID {
+	case pgtype.BoolOID, pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID, pgtype.OIDOID,
+		pgtype.Float4OID, pgtype.Float8OID, pgtype.ByteaOID:
+		return dataType.Codec.DecodeValue(typeMap, field.DataTypeOID, field.Format, raw)
+
+	case pgtype.JSONOID, pgtype.JSONBOID:
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+
+	if known {
+		if arrayCodec, ok := dataType.Codec.(*pgtype.ArrayCodec); ok {
+			delimiter := byte(',')
+			if field.DataTypeOID == pgtype.BoxArrayOID {
+				delimiter = ';'
+			}
+			elements, err := parseTextArray(string(raw), delimiter)
+			if err != nil {
+				return nil, err
+			}
+			elementField := pgconn.FieldDescription{DataTypeOID: arrayCodec.ElementType.OID, Format: pgtype.TextFormatCode}
+			return decodeArrayElements(typeMap, elementField, elements)
+		}
+	}
+
+	return string(raw), nil
+}
+
+// decodeArrayElements decodes the elements returned by parseTextArray.
+func decodeArrayElements(typeMap *pgtype.Map, field pgconn.FieldDescription, elements []interface{}) ([]interface{}, error) {
+	values := make([]interface{}, len(elements))
+	for i, element := range elements {
+		var err error
+		switch element := element.(type) {
+		case []interface{}:
+			values[i], err = decodeArrayElements(typeMap, field, element)
+		case string:
+			values[i], err = decodeValue(typeMap, field, []byte(element))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// parseTextArray parses the text representation of an array, such as
+// {{1,2},{NULL,"a \"b\""}}, into nested slices of strings, with nil for NULL
+// elements. A leading dimension decoration such as [0:1]= is skipped.
+func parseTextArray(src string, delimiter byte) ([]interface{}, error) {
+	if strings.HasPrefix(src, "[") {
+		if eq := strings.IndexByte(src, '='); eq >= 0 {
+			src = src[eq+1:]
+		}
+	}
+
+	pos := 0
+	elements, err := parseTextArrayLevel(src, &pos, delimiter)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(src[pos:]) != "" {
+		return nil, fmt.Errorf("invalid array %q: unexpected text after the closing brace", src)
+	}
+	return elements, nil
+}
+
+func parseTextArrayLevel(src string, pos *int, delimiter byte) ([]interface{}, error) {
+	skipSpace := func() {
+		for *pos < len(src) && (src[*pos] == ' ' || src[*pos] == '\t' || src[*pos] == '\n' || src[*pos] == '\r') {
+			*pos++
+		}
+	}
+
+	skipSpace()
+	if *pos >= len(src) || src[*pos] != '{' {
+		return nil, fmt.Errorf("invalid array %q: expected '{' at offset %d", src, *pos)
+	}
+	*pos++
+
+	elements := []interface{}{}
+	skipSpace()
+	if *pos < len(src) && src[*pos] == '}' {
+		*pos++
+		return elements, nil
+	}
+
+	for {
+		skipSpace()
+		if *pos >= len(src) {
+			return nil, fmt.Errorf("invalid array %q: unexpected end", src)
+		}
+
+		switch src[*pos] {
+		case '{':
+			nested, err := parseTextArrayLevel(src, pos, delimiter)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, nested)
+
+		case '"':
+			var element strings.Builder
+			*pos++
+			for {
+				if *pos >= len(src) {
+					return nil, fmt.Errorf("invalid array %q: unterminated quoted element", src)
+				}
+				c := src[*pos]
+				*pos++
+				if c == '"' {
+					break
+				}
+				if c == '\\' && *pos < len(src) {
+					c = src[*pos]
+					*pos++
+				}
+				element.WriteByte(c)
+			}
+			elements = append(elements, element.String())
+
+		default:
+			start := *pos
+			for *pos < len(src) && src[*pos] != delimiter && src[*pos] != '}' {
+				*pos++
+			}
+			element := strings.TrimSpace(src[start:*pos])
+			if strings.EqualFold(element, "NULL") {
+				elements = append(elements, nil)
+			} else {
+				elements = append(elements, element)
+			}
+		}
+
+		skipSpace()
+		if *pos >= len(src) {
+			return nil, fmt.Errorf("invalid array %q: unexpected end", src)
+		}
+		switch src[*pos] {
+		case delimiter:
+			*pos++
+		case '}':
+			*pos++
+			return elements, nil
+		default:
+			return nil, fmt.Errorf("invalid array %q: unexpected %q at offset %d", src, src[*pos], *pos)
+		}
+	}
+}
+
+// describeColumns returns the metadata of result columns. Type names of types
+// the connection's type map does not know, such as enums and types of
+// extensions, are left for lookupColumnDetails to fill in.
+func describeColumns(typeMap *pgtype.Map, fieldDescriptions []pgconn.FieldDescription) []SQLColumn {
+	columns := make([]SQLColumn, len(fieldDescriptions))
+	for i, fd := range fieldDescriptions {
+		columns[i] = SQLColumn{
+			Name:        fd.Name,
+			TypeOID:     fd.DataTypeOID,
+			TableOID:    fd.TableOID,
+			TableColumn: int(fd.TableAttributeNumber),
+		}
+		if dataType, ok := typeMap.TypeForOID(fd.DataTypeOID); ok {
+			columns[i].TypeName = dataType.Name
+			if _, isArray := dataType.Codec.(*pgtype.ArrayCodec); isArray {
+				columns[i].TypeName = strings.TrimPrefix(dataType.Name, "_") + "[]"
+			}
+		}
+	}
+	return columns
+}
+
+// lookupColumnDetails fills in the type names describeColumns left out, from
+// typeNames or the catalog, and whether the columns read straight from a table
+// can be NULL. It only reads the catalog when the connection is idle or in a
+// healthy transaction, so it never disturbs the user's session; details it
+// cannot look up are left out.
+func (p *PostgreSQLExecutor) lookupColumnDetails(ctx context.Context, conn *pgx.Conn, columns []SQLColumn) {
+	var unknownTypes []string
+	var tableColumns []string
+	for i := range columns {
+		column := &columns[i]
+		if column.TypeName == "" {
+			if name, ok := p.typeNames[column.TypeOID]; ok {
+				column.TypeName = name
+			} else {
+				unknownTypes = append(unknownTypes, strconv.FormatUint(uint64(column.TypeOID), 10))
+			}
+		}
+		if column.TableOID != 0 && column.TableColumn > 0 {
+			tableColumns = append(tableColumns, fmt.Sprintf("(%d, %d)", column.TableOID, column.TableColumn))
+		}
+	}
+	if len(unknownTypes) == 0 && len(tableColumns) == 0 {
+		return
+	}
+	if status := conn.PgConn().TxStatus(); status != 'I' && status != 'T' {
+		return
+	}
+
+	if len(unknownTypes) > 0 {
+		rows, err := catalogRows(ctx, conn, fmt.Sprintf(`SELECT t.oid, coalesce(e.typname || '[]', t.typname)
+FROM pg_catalog.pg_type t
+LEFT JOIN pg_catalog.pg_type e ON t.typcategory = 'A' AND e.oid = t.typelem
+WHERE t.oid IN (%s)`, strings.Join(unknownTypes, ", ")), 2)
+		if err != nil {
+			log.Printf("PostgreSQL Executor: Failed to look up column types: %v", err)
+			return
+		}
+		if p.typeNames == nil {
+			p.typeNames = make(map[uint32]string)
+		}
+		for _, row := range rows {
+			oid, err := strconv.ParseUint(string(row[0]), 10, 32)
+			if err == nil {
+				p.typeNames[uint32(oid)] = string(row[1])
+			}
+		}
+		for i := range columns {
+			if columns[i].TypeName == "" {
+				columns[i].TypeName = p.typeNames[columns[i].TypeOID]
+			}
+		}
+	}
+
+	if len(tableColumns) > 0 {
+		rows, err := catalogRows(ctx, conn, fmt.Sprintf(`SELECT attrelid, attnum, attnotnull
+FROM pg_catalog.pg_attribute
+WHERE (attrelid, attnum) IN (%s)`, strings.Join(tableColumns, ", ")), 3)
+		if err != nil {
+			log.Printf("PostgreSQL Executor: Failed to look up column nullability: %v", err)
+			return
+		}
+		notNull := make(map[string]bool)
+		for _, row := range rows {
+			notNull[string(row[0])+"."+string(row[1])] = string(row[2]) == "t"
+		}
+		for i := range columns {
+			column := &columns[i]
+			if isNotNull, ok := notNull[fmt.Sprintf("%d.%d", column.TableOID, column.TableColumn)]; ok {
+				nullable := !isNotNull
+				column.Nullable = &nullable
+			}
+		}
+	}
+}
+
+// catalogRows runs a catalog query and returns its rows in text format,
+// leaving out rows with fewer than columns values.
+func catalogRows(ctx context.Context, conn *pgx.Conn, sql string, columns int) ([][][]byte, error) {
+	results, err := conn.PgConn().Exec(ctx, sql).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var rows [][][]byte
+	for _, result := range results {
+		for _, row := range result.Rows {
+			if len(row) >= columns {
+				rows = append(rows, row)
+			}
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,131 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestDecodeValue(t *testing.T) {
+	executor := NewPostgreSQLExecutor(DefaultExecutorOptions())
+	typeMap := pgtype.NewMap()
+
+	testCases := []struct {
+		name     string
+		oid      uint32
+		raw      string
+		expected string // JSON of the converted value
+	}{
+		{"numeric as an exact string", pgtype.NumericOID, "123456789012345678901234.5678", `"123456789012345678901234.5678"`},
+		{"integer", pgtype.Int4OID, "42", `42`},
+		{"bigint beyond JavaScript's range", pgtype.Int8OID, "9223372036854775807", `"9223372036854775807"`},
+		{"float NaN", pgtype.Float8OID, "NaN", `"NaN"`},
+		{"jsonb with an exact number", pgtype.JSONBOID, `{"big": 12345678901234567890, "list": [1.50, "x"]}`, `{"big":12345678901234567890,"list":[1.50,"x"]}`},
+		{"bytea as hex", pgtype.ByteaOID, `\x68656c6c6f`, `"\\x68656c6c6f"`},
+		{"multi-dimensional array", pgtype.Int4ArrayOID, "{{1,2},{3,NULL}}", `[[1,2],[3,null]]`},
+		{"array with quoted elements", pgtype.TextArrayOID, `{"a, b","say \"hi\"",NULL,"NULL"}`, `["a, b","say \"hi\"",null,"NULL"]`},
+		{"numeric array", pgtype.NumericArrayOID, "[0:1]={1.50,2}", `["1.50","2"]`},
+		{"interval as text", pgtype.IntervalOID, "1 year 2 mons 04:05:06", `"1 year 2 mons 04:05:06"`},
+		{"inet as text", pgtype.InetOID, "192.168.0.1/24", `"192.168.0.1/24"`},
+		{"range as text", pgtype.Int4rangeOID, "[1,10)", `"[1,10)"`},
+		{"timestamp as text", pgtype.TimestamptzOID, "2024-01-02 03:04:05.123456+02", `"2024-01-02 03:04:05.123456+02"`},
+		{"unknown type as text", 99999, "happy", `"happy"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run("should decode "+tc.name, func(t *testing.T) {
+			field := pgconn.FieldDescription{DataTypeOID: tc.oid, Format: pgtype.TextFormatCode}
+			value, err := decodeValue(typeMap, field, []byte(tc.raw))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			data, err := json.Marshal(executor.convertValue(value))
+			if err != nil {
+				t.Fatalf("Expected a JSON-safe value, got %v", err)
+			}
+			if string(data) != tc.expected {
+				t.Fatalf("Expected %s, got %s", tc.expected, data)
+			}
+		})
+	}
+
+	t.Run("should reject malformed arrays", func(t *testing.T) {
+		for _, raw := range []string{"{1,2", `{"a}`, "{1}x"} {
+			field := pgconn.FieldDescription{DataTypeOID: pgtype.Int4ArrayOID, Format: pgtype.TextFormatCode}
+			if _, err := decodeValue(typeMap, field, []byte(raw)); err == nil {
+				t.Errorf("Expected an error for %q", raw)
+			}
+		}
+	})
+}
+
+func TestPostgreSQLExecutor_ColumnInfo(t *testing.T) {
+	var mu sync.Mutex
+	typeLookups := 0
+	handler := func(query string) []standInResult {
+		switch {
+		case query == "SELECT id, mood, note, tags FROM people":
+			return []standInResult{{
+				Columns: []standInColumn{
+					{Name: "id", OID: pgtype.Int8OID, TableOID: 16384, TableColumn: 1},
+					{Name: "mood", OID: 70000, TableOID: 16384, TableColumn: 2},
+					{Name: "note", OID: pgtype.TextOID, TableOID: 16384, TableColumn: 3},
+					{Name: "tags", OID: pgtype.TextArrayOID},
+				},
+				Rows: [][]*string{textRow("1", "happy", "hi", "{a,b}")},
+				Tag:  "SELECT 1",
+			}}
+		case strings.Contains(query, "pg_catalog.pg_type"):
+			mu.Lock()
+			typeLookups++
+			mu.Unlock()
+			return []standInResult{{Columns: textColumns("oid", "typname"), Rows: [][]*string{textRow("70000", "mood")}, Tag: "SELECT 1"}}
+		case strings.Contains(query, "pg_catalog.pg_attribute"):
+			return []standInResult{{
+				Columns: textColumns("attrelid", "attnum", "attnotnull"),
+				Rows:    [][]*string{textRow("16384", "1", "t"), textRow("16384", "2", "f"), textRow("16384", "3", "f")},
+				Tag:     "SELECT 3",
+			}}
+		}
+		return nil
+	}
+	executor := newStandInExecutor(t, DefaultExecutorOptions(), handler)
+
+	for run := 0; run < 2; run++ {
+		result, err := executor.Execute(context.Background(), "SELECT id, mood, note, tags FROM people", "")
+		if err != nil || result.ExitCode != 0 {
+			t.Fatalf("Expected the query to succeed, got %v %q", err, result.Error)
+		}
+
+		columns := result.SQLResult.ColumnInfo
+		if len(columns) != 4 {
+			t.Fatalf("Expected 4 columns, got %+v", columns)
+		}
+		got := make([]string, len(columns))
+		for i, column := range columns {
+			nullable := "?"
+			if column.Nullable != nil {
+				nullable = fmt.Sprint(*column.Nullable)
+			}
+			got[i] = fmt.Sprintf("%s:%s:%s", column.Name, column.TypeName, nullable)
+		}
+		if want := "id:int8:false mood:mood:true note:text:true tags:text[]:?"; strings.Join(got, " ") != want {
+			t.Fatalf("Expected columns %q, got %q", want, strings.Join(got, " "))
+		}
+		if tags, ok := result.SQLResult.Rows[0][3].([]interface{}); !ok || len(tags) != 2 {
+			t.Fatalf("Expected tags as an array, got %#v", result.SQLResult.Rows[0][3])
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if typeLookups != 1 {
+		t.Fatalf("Expected type names to be looked up once, got %d lookups", typeLookups)
+	}
+}
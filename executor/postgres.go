@@ -5,24 +5,49 @@ package executor
 
 import (
 	"context"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"reflect"
+	"math"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	pgxUUID "github.com/vgarvardt/pgx-google-uuid/v5"
 )
 
+// maxFormattedRows is the number of rows written to the plain text output;
+// the full result is still available in SQLQueryResult.
+const maxFormattedRows = 100
+
 type PostgreSQLExecutor struct {
-	options ExecutorOptions
-	pool    *pgxpool.Pool
-	config  *PostgreSQLConfig
-	mu      sync.Mutex
+	options     ExecutorOptions
+	pool        *pgxpool.Pool
+	tunnel      *sshTunnel
+	config      *PostgreSQLConfig
+	cursor      *sqlCursor    // Result of the last query with rows left to fetch
+	session     *pgxpool.Conn // Connection kept for every run in session mode
+	sessionMode bool
+	sandbox     bool
+	typeNames   map[uint32]string             // Names of types looked up in the catalog, by OID
+	schema      *DatabaseSchema               // Objects of the database, loaded on first use
+	notices     noticeCollector               // Notices of the run in progress
+	limits      sessionLimits                 // Limits in effect on the connection of the last run
+	exports     map[uint64]context.CancelFunc // Exports streaming rows without holding mu, by ID
+	exportSeq   uint64
+	mu          sync.Mutex
 }
 
 func NewPostgreSQLExecutor(opts ExecutorOptions) *PostgreSQLExecutor {
@@ -32,11 +57,21 @@ func NewPostgreSQLExecutor(opts ExecutorOptions) *PostgreSQLExecutor {
 }
 
 func (p *PostgreSQLExecutor) Execute(ctx context.Context, code string, input string) (*ExecutionResult, error) {
+	return p.execute(ctx, code, false)
+}
+
+// ExecuteConfirmed runs code like Execute, including the destructive
+// statements that safe mode would hold back. Read-only mode still applies.
+func (p *PostgreSQLExecutor) ExecuteConfirmed(ctx context.Context, code string) (*ExecutionResult, error) {
+	return p.execute(ctx, code, true)
+}
+
+func (p *PostgreSQLExecutor) execute(ctx context.Context, code string, confirmed bool) (*ExecutionResult, error) {
 	start := time.Now()
 
 	if ctx == nil {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel = context.WithTimeout(context.Background(), p.options.timeoutOr(30*time.Second))
 		defer cancel()
 	}
 
@@ -53,8 +88,12 @@ func (p *PostgreSQLExecutor) Execute(ctx context.Context, code string, input str
 		return result, nil
 	}
 
-	sqlCode := p.prepareSQLCode(code)
-	if strings.TrimSpace(sqlCode) == "" {
+	// Rows left over from the previous query are no longer wanted.
+	p.closeCursor()
+
+	sqlCode, sqlOrigins := p.prepareSQLCodeWithOrigins(code)
+	statements := splitSQLStatements(sqlCode)
+	if len(statements) == 0 {
 		result.Error = "No SQL query provided"
 		result.ExitCode = ExitCodePostgresQueryError
 		return result, nil
@@ -66,28 +105,102 @@ func (p *PostgreSQLExecutor) Execute(ctx context.Context, code string, input str
 		return result, nil
 	}
 
-	sqlResult, err := p.executeSQL(ctx, sqlCode)
-	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			result.Error = "Query execution timed out"
-			result.ExitCode = 124
-		} else {
-			result.Error = fmt.Sprintf("SQL execution error: %v", err)
+	if p.sandbox {
+		if err := sandboxViolation(statements); err != nil {
+			result.Error = err.Error()
 			result.ExitCode = ExitCodePostgresQueryError
+			return result, nil
 		}
+	}
+	if findings, readOnly := p.guard(statements, confirmed); len(findings) > 0 {
+		setGuarded(result, findings, readOnly, sqlCode, statements, sqlOrigins)
 		return result, nil
 	}
 
-	result.SQLResult = sqlResult
-	result.Output = p.formatQueryOutput(sqlResult)
-	result.ExitCode = 0
+	p.notices.start()
+	sqlResults, errs, txStatus, err := p.executeScript(ctx, statements)
+	result.Notices = sqlNotices(p.notices.stop(), sqlCode, statements, sqlOrigins)
+	if p.sessionMode {
+		result.Transaction = txStatus
+	} else if txStatus != "" && txStatus != TransactionIdle {
+		result.Diagnostics = append(result.Diagnostics, Diagnostic{
+			Severity: SeverityWarning,
+			Message:  "The transaction this run left open was rolled back when the run ended; turn on session mode to keep transactions open between runs",
+			Source:   "postgres",
+		})
+	}
+	for i := range sqlResults {
+		sqlResults[i].Line = statementLine(sqlCode, statements[i].Offset, sqlOrigins)
+	}
+	if len(sqlResults) > 0 {
+		result.SQLResults = sqlResults
+		result.SQLResult = lastResultSet(sqlResults)
+		result.Output = p.formatScriptOutput(sqlResults)
+	}
+	if len(result.Notices) > 0 {
+		result.Output = formatNotices(result.Notices) + result.Output
+	}
+
+	failed := 0
+	for i, statementErr := range errs {
+		if statementErr == nil {
+			continue
+		}
+		if failed == 0 {
+			err = statementErr
+			if len(statements) > 1 {
+				err = fmt.Errorf("statement %d of %d (line %d): %w", i+1, len(statements), sqlResults[i].Line, statementErr)
+			}
+		}
+		failed++
+		if diagnostic := postgresDiagnostic(statementErr, sqlCode, statements[i].Offset, sqlOrigins); diagnostic != nil {
+			result.Diagnostics = append(result.Diagnostics, *diagnostic)
+		}
+	}
 
 	result.Duration = time.Since(start)
 	result.DurationString = formatDuration(result.Duration)
 
+	if err != nil {
+		setQueryError(ctx, result, err, p.limits)
+		if result.ExitCode == ExitCodePostgresQueryError && failed > 1 {
+			result.Error += fmt.Sprintf(" (%d more statements failed)", failed-1)
+		}
+		return result, nil
+	}
+
+	result.ExitCode = 0
 	return result, nil
 }
 
+// setQueryError reports err, returned by a query run with ctx on a connection
+// with limits in effect, on result, along with its SQLSTATE and the time limit
+// that stopped the query, if any. result.Duration must be set.
+func setQueryError(ctx context.Context, result *ExecutionResult, err error, limits sessionLimits) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		result.SQLState = pgErr.Code
+	}
+
+	// The run's own cancellation also reaches the server as a cancel request,
+	// so it is ruled out before the server's limits.
+	if wasCancelled(ctx) || ctx.Err() == context.Canceled {
+		result.Error = "Query execution cancelled"
+		result.ExitCode = ExitCodeCancelled
+	} else if ctx.Err() == context.DeadlineExceeded {
+		result.Limit = LimitRunTimeout
+		result.Error = "Query execution timed out"
+		result.ExitCode = ExitCodeTimeout
+	} else if limit := serverLimit(pgErr, limits, result.Duration); limit != "" {
+		result.Limit = limit
+		result.Error = fmt.Sprintf("Query stopped by %s (SQLSTATE %s): %s", limit, pgErr.Code, pgErr.Message)
+		result.ExitCode = ExitCodeTimeout
+	} else {
+		result.Error = fmt.Sprintf("SQL execution error: %v", err)
+		result.ExitCode = ExitCodePostgresQueryError
+	}
+}
+
 func (p *PostgreSQLExecutor) ensureConnection(ctx context.Context) error {
 	if p.pool != nil {
 		log.Println("PostgreSQL Executor: Testing existing connection pool")
@@ -96,8 +209,7 @@ func (p *PostgreSQLExecutor) ensureConnection(ctx context.Context) error {
 			return nil
 		}
 		log.Println("PostgreSQL Executor: Existing connection pool is unhealthy, closing it")
-		p.pool.Close()
-		p.pool = nil
+		p.closePool()
 	}
 
 	if p.config == nil {
@@ -105,24 +217,49 @@ func (p *PostgreSQLExecutor) ensureConnection(ctx context.Context) error {
 		return fmt.Errorf("no PostgreSQL configuration provided")
 	}
 
-	log.Printf("PostgreSQL Executor: Building connection string for %s:%d/%s",
-		p.config.Host, p.config.Port, p.config.Database)
-
-	connStr := p.buildConnectionString()
-
 	log.Println("PostgreSQL Executor: Parsing connection configuration")
 
-	poolConfig, err := pgxpool.ParseConfig(connStr)
+	poolConfig, err := p.config.poolConfig()
 	if err != nil {
 		log.Printf("PostgreSQL Executor: Invalid connection configuration: %v", err)
-		return fmt.Errorf("invalid connection configuration: %w", err)
+		return err
+	}
+
+	// Send a server-side cancel request when a query's context is done instead of
+	// only dropping the connection, so the backend stops working on it.
+	poolConfig.ConnConfig.BuildContextWatcherHandler = func(conn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{
+			Conn:          conn,
+			DeadlineDelay: 5 * time.Second,
+		}
+	}
+
+	// Notices such as the output of RAISE NOTICE are returned with the run.
+	poolConfig.ConnConfig.OnNotice = p.notices.add
+
+	if p.config.ReadOnly {
+		poolConfig.ConnConfig.RuntimeParams["default_transaction_read_only"] = "on"
 	}
 
 	poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
 		pgxUUID.Register(conn.TypeMap())
+		// The server's settings; applyLimits sets the configured limits.
+		conn.PgConn().CustomData()[limitsKey] = sessionLimits{}
 		return nil
 	}
 
+	if p.config.SSHTunnel != nil {
+		log.Printf("PostgreSQL Executor: Opening SSH tunnel through %s", p.config.SSHTunnel.Host)
+		tunnel, err := openSSHTunnel(ctx, p.config.SSHTunnel)
+		if err != nil {
+			log.Printf("PostgreSQL Executor: Failed to open SSH tunnel: %v", err)
+			return err
+		}
+		p.tunnel = tunnel
+		poolConfig.ConnConfig.DialFunc = tunnel.DialContext
+		poolConfig.ConnConfig.LookupFunc = tunnel.LookupHost
+	}
+
 	poolConfig.MaxConns = 5
 	poolConfig.MinConns = 1
 	poolConfig.MaxConnLifetime = time.Hour
@@ -134,14 +271,16 @@ func (p *PostgreSQLExecutor) ensureConnection(ctx context.Context) error {
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		log.Printf("PostgreSQL Executor: Failed to create connection pool: %v", err)
-		return fmt.Errorf("failed to create connection pool: %w", err)
+		p.closePool()
+		return fmt.Errorf("failed to create connection pool: %w", explainTLSError(err))
 	}
 
 	log.Println("PostgreSQL Executor: Testing new connection pool with ping")
 	if err := pool.Ping(ctx); err != nil {
 		log.Printf("PostgreSQL Executor: Failed to ping database: %v", err)
 		pool.Close()
-		return fmt.Errorf("failed to ping database: %w", err)
+		p.closePool()
+		return fmt.Errorf("failed to ping database: %w", explainTLSError(err))
 	}
 
 	log.Println("PostgreSQL Executor: Connection pool created and tested successfully")
@@ -150,110 +289,314 @@ func (p *PostgreSQLExecutor) ensureConnection(ctx context.Context) error {
 }
 
 func (p *PostgreSQLExecutor) buildConnectionString() string {
-	sslMode := p.config.SSLMode
-	if sslMode == "" {
-		sslMode = "prefer"
+	return p.config.connString()
+}
+
+// executeScript runs statements in order on one connection, so that session
+// state such as SET, temporary tables and transactions carries over from one
+// statement to the next. It returns a result for each statement it ran, and
+// the error of each one, which is nil for those that succeeded. After a
+// failure it stops, unless SQLOptions.ContinueOnError is set. The returned
+// error is set when no statement could be run.
+//
+// The rows of the last statement are read a page at a time: when there are
+// more than fit on the first page, the statement is left open as p.cursor,
+// which keeps the connection until it is closed.
+//
+// The returned status is the transaction state the run leaves the connection
+// in; for sandboxed runs, which are rolled back, it is the state before the run.
+func (p *PostgreSQLExecutor) executeScript(ctx context.Context, statements []sqlStatement) ([]SQLQueryResult, []error, TransactionStatus, error) {
+	conn, release, err := p.acquireConn(ctx)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	status := transactionStatus(conn.Conn().PgConn())
+	if p.sandbox {
+		rollback, err := beginSandbox(ctx, conn)
+		if err != nil {
+			release()
+			return nil, nil, status, err
+		}
+		releaseConn := release
+		release = func() {
+			rollback()
+			releaseConn()
+		}
+	}
+	defer func() {
+		if p.cursor == nil {
+			release()
+		}
+	}()
+
+	results := make([]SQLQueryResult, 0, len(statements))
+	errs := make([]error, 0, len(statements))
+	for i, statement := range statements {
+		p.notices.setStatement(i)
+		paged := i == len(statements)-1 && p.options.MaxOutputs > 0
+		result, cursor, err := p.executeSQL(ctx, conn, statement.Text, paged)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		if cursor != nil {
+			cursor.release = release
+			cursor.statement = i
+			p.cursor = cursor
+		}
+		results = append(results, *result)
+		errs = append(errs, err)
+
+		if err != nil && (!p.options.SQL.ContinueOnError || ctx.Err() != nil || conn.Conn().IsClosed()) {
+			break
+		}
 	}
 
-	return fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=%s",
-		p.config.Host,
-		p.config.Port,
-		p.config.Database,
-		p.config.Username,
-		p.config.Password,
-		sslMode,
-	)
+	if !p.sandbox {
+		status = transactionStatus(conn.Conn().PgConn())
+	}
+	return results, errs, status, nil
 }
 
-func (p *PostgreSQLExecutor) executeSQL(ctx context.Context, sqlCode string) (*SQLQueryResult, error) {
+// executeSQL runs a single statement. The result describes as much as was
+// received when the statement fails.
+//
+// When paged is set, only the first page of rows is read. If more rows
+// follow, the statement is left running under a context of its own and the
+// returned cursor, which takes over conn, reads the rest.
+func (p *PostgreSQLExecutor) executeSQL(ctx context.Context, conn *pgxpool.Conn, sqlCode string, paged bool) (*SQLQueryResult, *sqlCursor, error) {
 	queryStart := time.Now()
 
-	queryType := p.detectQueryType(sqlCode)
-
 	result := &SQLQueryResult{
-		QueryType:     queryType,
-		ExecutionTime: 0,
+		Statement:   sqlCode,
+		QueryType:   p.detectQueryType(sqlCode),
+		ReturnsRows: statementReturnsRows(tokenizeSQL(sqlCode)),
+	}
+	defer func() {
+		result.ExecutionTime = time.Since(queryStart)
+	}()
+
+	var statementCtx context.Context
+	var cancel context.CancelFunc
+	stop := func() bool { return true }
+	if paged {
+		// Until the first page is read, ctx still cancels the statement.
+		statementCtx, cancel = context.WithCancel(context.Background())
+		stop = context.AfterFunc(ctx, cancel)
+	} else {
+		statementCtx, cancel = context.WithCancel(ctx)
+	}
+	defer stop()
+
+	// Rows over MaxOutputs are not read if the statement can be cancelled
+	// without losing anything: it writes nothing and is not part of a
+	// transaction, which the cancellation would abort.
+	var stopRows func()
+	stoppedEarly := false
+	if conn.Conn().PgConn().TxStatus() == 'I' && readOnlyReason(significantTokens(tokenizeSQL(sqlCode))) == "" {
+		stopRows = func() {
+			stoppedEarly = true
+			cancel()
+		}
 	}
 
-	if p.isSelectQuery(queryType) {
-		rows, err := p.pool.Query(ctx, sqlCode)
-		if err != nil {
-			return nil, err
+	// The simple query protocol runs any statement as written. pgx's own
+	// simple protocol mode would look for parameters inside dollar-quoted
+	// function bodies.
+	typeMap := conn.Conn().TypeMap()
+	multiReader := conn.Conn().PgConn().Exec(statementCtx, sqlCode)
+	for multiReader.NextResult() {
+		reader := multiReader.ResultReader()
+		if !paged || len(reader.FieldDescriptions()) == 0 {
+			if err := p.readResult(typeMap, reader, result, stopRows); err != nil {
+				multiReader.Close()
+				cancel()
+				return result, nil, err
+			}
+			continue
 		}
-		defer rows.Close()
 
-		fieldDescriptions := rows.FieldDescriptions()
-		columns := make([]string, len(fieldDescriptions))
-		for i, fd := range fieldDescriptions {
-			columns[i] = string(fd.Name)
+		p.describeResult(typeMap, reader.FieldDescriptions(), result)
+		cursor := &sqlCursor{
+			handle:      uuid.NewString(),
+			conn:        conn,
+			multiReader: multiReader,
+			reader:      reader,
+			cancel:      cancel,
 		}
-		result.Columns = columns
-
-		var allRows [][]interface{}
-		for rows.Next() {
-			values, err := rows.Values()
-			if err != nil {
-				return nil, err
+		rows, err := p.readPage(cursor, p.options.MaxOutputs)
+		result.Rows = rows
+		result.RowsAffected = cursor.fetched
+		if err != nil {
+			cursor.stop()
+			return result, nil, err
+		}
+		if !cursor.done {
+			if !stop() {
+				cursor.stop()
+				return result, nil, ctx.Err()
 			}
+			result.ResultHandle = cursor.handle
+			result.HasMore = true
+			p.lookupColumnDetailsInPool(ctx, result.ColumnInfo)
+			return result, cursor, nil
+		}
+		result.CommandTag = cursor.commandTag
+	}
+	err := multiReader.Close()
+	cancel()
+	if stoppedEarly && ctx.Err() == nil && statementCancelled(err) {
+		err = nil
+	}
+	if err != nil {
+		return result, nil, err
+	}
+	if len(result.ColumnInfo) > 0 {
+		p.lookupColumnDetails(ctx, conn.Conn(), result.ColumnInfo)
+	}
+	return result, nil, nil
+}
 
-			row := make([]interface{}, len(values))
-			for i, val := range values {
-				row[i] = p.convertValue(val)
-			}
-			allRows = append(allRows, row)
+// readResult reads one result set or command completion into result.
+//
+// Rows over MaxOutputs are not returned. If stopRows is set, it is called to
+// cancel the statement instead of reading them, and how many there were is
+// not known; otherwise they are read without being decoded.
+func (p *PostgreSQLExecutor) readResult(typeMap *pgtype.Map, reader *pgconn.ResultReader, result *SQLQueryResult, stopRows func()) error {
+	fieldDescriptions := reader.FieldDescriptions()
+	if len(fieldDescriptions) == 0 {
+		commandTag, err := reader.Close()
+		if err != nil {
+			return err
 		}
 
-		if err := rows.Err(); err != nil {
-			return nil, err
+		result.CommandTag = commandTag.String()
+		result.ReturnsRows = false
+		result.RowsAffected = commandTag.RowsAffected()
+		result.Columns = []string{"Rows Affected"}
+		result.Rows = [][]interface{}{{result.RowsAffected}}
+		return nil
+	}
+
+	p.describeResult(typeMap, fieldDescriptions, result)
+
+	var allRows [][]interface{}
+	truncated := false
+	for reader.NextRow() {
+		if p.options.MaxOutputs > 0 && len(allRows) >= p.options.MaxOutputs {
+			truncated = true
+			break
 		}
 
-		result.Rows = allRows
-		result.RowsAffected = int64(len(allRows))
-	} else {
-		commandTag, err := p.pool.Exec(ctx, sqlCode)
+		row, err := p.decodeRow(typeMap, fieldDescriptions, reader.Values())
 		if err != nil {
-			return nil, err
+			reader.Close()
+			return err
 		}
+		allRows = append(allRows, row)
+	}
+	result.Rows = allRows
+	result.RowsAffected = int64(len(allRows))
+	result.Truncated = truncated
 
+	if truncated && stopRows != nil {
+		stopRows()
+		if _, err := reader.Close(); !statementCancelled(err) {
+			return err
+		}
+		return nil
+	}
+
+	commandTag, err := reader.Close()
+	result.CommandTag = commandTag.String()
+	if truncated {
 		result.RowsAffected = commandTag.RowsAffected()
-		result.Columns = []string{"Rows Affected"}
-		result.Rows = [][]interface{}{{result.RowsAffected}}
+		result.DroppedRows = result.RowsAffected - int64(len(allRows))
+	}
+	return err
+}
+
+// statementCancelled reports whether err is the end of a statement that was
+// cancelled, or nil.
+func statementCancelled(err error) bool {
+	var pgErr *pgconn.PgError
+	return err == nil || errors.Is(err, context.Canceled) || (errors.As(err, &pgErr) && pgErr.Code == "57014")
+}
+
+// describeResult sets the columns of a result set.
+func (p *PostgreSQLExecutor) describeResult(typeMap *pgtype.Map, fieldDescriptions []pgconn.FieldDescription, result *SQLQueryResult) {
+	columns := make([]string, len(fieldDescriptions))
+	for i, fd := range fieldDescriptions {
+		columns[i] = fd.Name
 	}
+	result.Columns = columns
+	result.ColumnInfo = describeColumns(typeMap, fieldDescriptions)
+	result.ReturnsRows = true
+}
 
-	result.ExecutionTime = time.Since(queryStart)
-	return result, nil
+// decodeRow decodes and converts the raw values of a row.
+func (p *PostgreSQLExecutor) decodeRow(typeMap *pgtype.Map, fieldDescriptions []pgconn.FieldDescription, values [][]byte) ([]interface{}, error) {
+	row := make([]interface{}, len(values))
+	for i, raw := range values {
+		val, err := decodeValue(typeMap, fieldDescriptions[i], raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode column %s: %w", fieldDescriptions[i].Name, err)
+		}
+		row[i] = p.convertValue(val)
+	}
+	return row, nil
+}
+
+// lastResultSet picks the result shown as the execution's SQLResult: the last
+// statement that returned rows, or else the last statement.
+func lastResultSet(results []SQLQueryResult) *SQLQueryResult {
+	for i := len(results) - 1; i >= 0; i-- {
+		if results[i].ReturnsRows {
+			return &results[i]
+		}
+	}
+	return &results[len(results)-1]
+}
+
+// statementLine returns the line in the user's code where the statement at
+// offset in the prepared sqlCode starts.
+func statementLine(sqlCode string, offset int, sqlMap []sqlLineOrigin) int {
+	line := strings.Count(sqlCode[:offset], "\n") + 1
+	if line > len(sqlMap) {
+		return 0
+	}
+	return sqlMap[line-1].line
 }
 
+// detectQueryType names a statement by its leading keyword, or "OTHER" for
+// statements that are not told apart.
 func (p *PostgreSQLExecutor) detectQueryType(sqlCode string) string {
-	trimmed := strings.TrimSpace(strings.ToUpper(sqlCode))
-
-	switch {
-	case strings.HasPrefix(trimmed, "SELECT"):
-		return "SELECT"
-	case strings.HasPrefix(trimmed, "INSERT"):
-		return "INSERT"
-	case strings.HasPrefix(trimmed, "UPDATE"):
-		return "UPDATE"
-	case strings.HasPrefix(trimmed, "DELETE"):
-		return "DELETE"
-	case strings.HasPrefix(trimmed, "CREATE"):
-		return "CREATE"
-	case strings.HasPrefix(trimmed, "DROP"):
-		return "DROP"
-	case strings.HasPrefix(trimmed, "ALTER"):
-		return "ALTER"
-	case strings.HasPrefix(trimmed, "WITH"):
-		return "WITH"
+	switch keyword := leadingKeyword(tokenizeSQL(sqlCode)); keyword {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "DROP", "ALTER", "WITH",
+		"EXPLAIN", "SHOW", "VALUES", "TABLE":
+		return keyword
 	default:
 		return "OTHER"
 	}
 }
 
+// isSelectQuery reports whether statements of queryType are queries that
+// return rows. Data-modifying statements return rows only with a RETURNING
+// clause, which statementReturnsRows tells from the statement itself.
 func (p *PostgreSQLExecutor) isSelectQuery(queryType string) bool {
-	trimmed := strings.TrimSpace(strings.ToUpper(queryType))
-	return strings.HasPrefix(trimmed, "SELECT") || strings.HasPrefix(trimmed, "WITH")
+	switch strings.TrimSpace(strings.ToUpper(queryType)) {
+	case "SELECT", "WITH", "EXPLAIN", "SHOW", "VALUES", "TABLE":
+		return true
+	}
+	return false
 }
 
+// maxSafeInteger is the largest integer JavaScript numbers hold exactly.
+const maxSafeInteger = 1<<53 - 1
+
+// convertValue turns a decoded value into one that survives the trip to the
+// frontend as JSON without losing anything: integers JavaScript cannot hold
+// exactly and non-finite floats become strings, bytes become a "\x" hex
+// string as PostgreSQL shows bytea, and times keep their fractional seconds.
 func (p *PostgreSQLExecutor) convertValue(val interface{}) interface{} {
 	if val == nil {
 		return nil
@@ -262,43 +605,139 @@ func (p *PostgreSQLExecutor) convertValue(val interface{}) interface{} {
 	switch v := val.(type) {
 	case uuid.UUID:
 		return v.String()
+	case [16]byte:
+		return uuid.UUID(v).String()
 	case []byte:
-		return string(v)
+		return `\x` + hex.EncodeToString(v)
 	case time.Time:
-		return v.Format(time.RFC3339)
-	case pgx.Rows:
-		return "[nested result]"
-	default:
-		rv := reflect.ValueOf(val)
-		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
-			return fmt.Sprintf("%v", val)
+		return v.Format(time.RFC3339Nano)
+	case int64:
+		if v > maxSafeInteger || v < -maxSafeInteger {
+			return strconv.FormatInt(v, 10)
+		}
+		return v
+	case float32:
+		return p.convertValue(float64(v))
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN"
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		}
+		return v
+	case json.Number:
+		return v
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, element := range v {
+			values[i] = p.convertValue(element)
 		}
+		return values
+	case map[string]interface{}:
+		values := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			values[key] = p.convertValue(element)
+		}
+		return values
+	case driver.Valuer:
+		// pgtype values such as Numeric and Interval, from binary results.
+		if value, err := v.Value(); err == nil {
+			return p.convertValue(value)
+		}
+		return fmt.Sprintf("%v", val)
+	case fmt.Stringer:
+		return v.String()
+	default:
 		return val
 	}
 }
 
+// formatValue formats a converted value for the plain text output.
+func formatValue(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case []interface{}, map[string]interface{}:
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprintf("%v", val)
+}
+
+// sqlLineOrigin records where a line of prepared SQL came from in the user's code.
+type sqlLineOrigin struct {
+	line   int // 1-based line in the user's code
+	indent int // Leading whitespace characters removed from the line
+}
+
 func (p *PostgreSQLExecutor) prepareSQLCode(code string) string {
-	lines := strings.Split(code, "\n")
+	sqlCode, _ := p.prepareSQLCodeWithOrigins(code)
+	return sqlCode
+}
+
+// prepareSQLCodeWithOrigins is prepareSQLCode that also returns, for each
+// prepared line, its origin in the user's code.
+//
+// Comments are blanked out with spaces so that the columns of the remaining
+// code do not move, then lines are trimmed and blank lines dropped. Strings,
+// quoted identifiers and dollar-quoted bodies are kept as written, including
+// the whitespace of lines they span.
+func (p *PostgreSQLExecutor) prepareSQLCodeWithOrigins(code string) (string, []sqlLineOrigin) {
+	var blanked strings.Builder
+	// literalBreaks holds the indexes of the line breaks that are part of a literal.
+	literalBreaks := make(map[int]bool)
+	lineBreak := 0
+
+	for _, token := range tokenizeSQL(code) {
+		text := token.Text
+		switch token.Kind {
+		case sqlTokenComment:
+			text = strings.Map(func(r rune) rune {
+				if r == '\n' {
+					return r
+				}
+				return ' '
+			}, text)
+		case sqlTokenString, sqlTokenQuotedIdent, sqlTokenDollarString:
+			for i := range strings.Count(text, "\n") {
+				literalBreaks[lineBreak+i] = true
+			}
+		}
+		blanked.WriteString(text)
+		lineBreak += strings.Count(text, "\n")
+	}
+
 	var cleanLines []string
+	var origins []sqlLineOrigin
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	for i, rawLine := range strings.Split(blanked.String(), "\n") {
+		startsInLiteral := i > 0 && literalBreaks[i-1]
+		endsInLiteral := literalBreaks[i]
 
-		if line == "" || strings.HasPrefix(line, "--") {
-			continue
+		line := rawLine
+		indent := 0
+		if !startsInLiteral {
+			line = strings.TrimLeftFunc(line, unicode.IsSpace)
+			indent = utf8.RuneCountInString(rawLine) - utf8.RuneCountInString(line)
 		}
-
-		if idx := strings.Index(line, "--"); idx != -1 {
-			line = strings.TrimSpace(line[:idx])
-			if line == "" {
-				continue
-			}
+		if !endsInLiteral {
+			line = strings.TrimRightFunc(line, unicode.IsSpace)
+		}
+		if line == "" && !startsInLiteral && !endsInLiteral {
+			continue
 		}
 
 		cleanLines = append(cleanLines, line)
+		origins = append(origins, sqlLineOrigin{line: i + 1, indent: indent})
 	}
 
-	return strings.Join(cleanLines, "\n")
+	return strings.Join(cleanLines, "\n"), origins
 }
 
 func (p *PostgreSQLExecutor) formatQueryOutput(sqlResult *SQLQueryResult) string {
@@ -311,7 +750,7 @@ func (p *PostgreSQLExecutor) formatQueryOutput(sqlResult *SQLQueryResult) string
 	output.WriteString(fmt.Sprintf("Query Type: %s\n", sqlResult.QueryType))
 	output.WriteString(fmt.Sprintf("Execution Time: %s\n", formatDuration(sqlResult.ExecutionTime)))
 
-	if p.isSelectQuery(sqlResult.QueryType) {
+	if sqlResult.ReturnsRows || p.isSelectQuery(sqlResult.QueryType) {
 		output.WriteString(fmt.Sprintf("Rows Returned: %d\n\n", len(sqlResult.Rows)))
 
 		if len(sqlResult.Rows) > 0 && len(sqlResult.Columns) > 0 {
@@ -320,24 +759,32 @@ func (p *PostgreSQLExecutor) formatQueryOutput(sqlResult *SQLQueryResult) string
 			output.WriteString(strings.Repeat("-", len(strings.Join(sqlResult.Columns, " | "))))
 			output.WriteString("\n")
 
-			for i := 0; i < len(sqlResult.Rows); i++ {
+			shown := min(len(sqlResult.Rows), maxFormattedRows)
+			for i := 0; i < shown; i++ {
 				row := sqlResult.Rows[i]
 				stringRow := make([]string, len(row))
 				for j, val := range row {
-					if val == nil {
-						stringRow[j] = "NULL"
-					} else {
-						stringRow[j] = fmt.Sprintf("%v", val)
-					}
+					stringRow[j] = formatValue(val)
 				}
 				output.WriteString(strings.Join(stringRow, " | "))
 				output.WriteString("\n")
 			}
 
-			if len(sqlResult.Rows) > 100 {
-				output.WriteString(fmt.Sprintf("... and %d more rows\n", len(sqlResult.Rows)-100))
+			if len(sqlResult.Rows) > shown {
+				output.WriteString(fmt.Sprintf("... and %d more rows\n", len(sqlResult.Rows)-shown))
 			}
 		}
+
+		if sqlResult.HasMore {
+			output.WriteString(fmt.Sprintf("\nShowing the first %d rows; more rows are available\n", len(sqlResult.Rows)))
+		}
+
+		if sqlResult.Truncated && sqlResult.DroppedRows > 0 {
+			output.WriteString(fmt.Sprintf("\nResult truncated: %d more rows were not returned (limit %d)\n",
+				sqlResult.DroppedRows, len(sqlResult.Rows)))
+		} else if sqlResult.Truncated {
+			output.WriteString(fmt.Sprintf("\nResult truncated: more rows were not read (limit %d)\n", len(sqlResult.Rows)))
+		}
 	} else {
 		output.WriteString(fmt.Sprintf("Rows Affected: %d\n", sqlResult.RowsAffected))
 	}
@@ -345,16 +792,73 @@ func (p *PostgreSQLExecutor) formatQueryOutput(sqlResult *SQLQueryResult) string
 	return output.String()
 }
 
+// formatScriptOutput formats the results of a script. A single statement is
+// formatted as by formatQueryOutput; longer scripts get a header per statement.
+func (p *PostgreSQLExecutor) formatScriptOutput(results []SQLQueryResult) string {
+	if len(results) == 1 && results[0].Error == "" {
+		return p.formatQueryOutput(&results[0])
+	}
+
+	var output strings.Builder
+	for i := range results {
+		if i > 0 {
+			output.WriteString("\n")
+		}
+		result := &results[i]
+		output.WriteString(fmt.Sprintf("-- Statement %d", i+1))
+		if result.Line > 0 {
+			output.WriteString(fmt.Sprintf(" (line %d)", result.Line))
+		}
+		output.WriteString(fmt.Sprintf(": %s\n", statementSummary(result.Statement)))
+
+		if result.Error != "" {
+			output.WriteString(fmt.Sprintf("Error: %s\n", result.Error))
+			continue
+		}
+		output.WriteString(p.formatQueryOutput(result))
+	}
+	return output.String()
+}
+
+// statementSummary shortens a statement to its first line, for headers.
+func statementSummary(statement string) string {
+	const maxLength = 60
+
+	summary, _, multiline := strings.Cut(statement, "\n")
+	summary = strings.TrimSpace(summary)
+	if utf8.RuneCountInString(summary) > maxLength {
+		summary = string([]rune(summary)[:maxLength])
+		multiline = true
+	}
+	if multiline {
+		summary += " ..."
+	}
+	return summary
+}
+
 func (p *PostgreSQLExecutor) SetConfig(config *PostgreSQLConfig) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	p.config = config
+	p.closePool()
+}
 
-	if p.pool != nil {
-		p.pool.Close()
-		p.pool = nil
-	}
+// setOptions changes the options of later runs, keeping the connection pool
+// and the session.
+func (p *PostgreSQLExecutor) setOptions(opts ExecutorOptions) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.options = opts
+}
+
+// Config returns the current connection configuration, or nil if none is set.
+func (p *PostgreSQLExecutor) Config() *PostgreSQLConfig {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.config
 }
 
 func (p *PostgreSQLExecutor) CreatePgPool(ctx context.Context, config *PostgreSQLConfig) error {
@@ -373,9 +877,8 @@ func (p *PostgreSQLExecutor) CreatePgPool(ctx context.Context, config *PostgreSQ
 
 	if p.pool != nil {
 		log.Println("PostgreSQL Executor: Closing existing connection pool")
-		p.pool.Close()
-		p.pool = nil
 	}
+	p.closePool()
 
 	err := p.ensureConnection(ctx)
 	if err != nil {
@@ -452,8 +955,13 @@ func (p *PostgreSQLExecutor) IsConnected() bool {
 }
 
 func (p *PostgreSQLExecutor) isAvailableInternal() bool {
-	return p.config != nil &&
-		p.config.Host != "" &&
+	if p.config == nil {
+		return false
+	}
+	if p.config.ConnectionString != "" || p.config.Service != "" {
+		return true
+	}
+	return p.config.Host != "" &&
 		p.config.Database != "" &&
 		p.config.Username != ""
 }
@@ -466,12 +974,34 @@ func (p *PostgreSQLExecutor) Cleanup() error {
 
 	if p.pool != nil {
 		log.Println("PostgreSQL Executor: Closing connection pool")
-		p.pool.Close()
-		p.pool = nil
+		p.closePool()
 		log.Println("PostgreSQL Executor: Connection pool closed successfully")
 	} else {
 		log.Println("PostgreSQL Executor: No active connection pool to close")
+		p.closePool()
 	}
 
 	return nil
 }
+
+// closePool closes the open result, the session, the connection pool and the
+// SSH tunnel it dials through. The caller must hold p.mu.
+func (p *PostgreSQLExecutor) closePool() {
+	p.closeCursor()
+	p.closeSession()
+	// Closing the pool waits for the connections of running exports.
+	for _, cancel := range p.exports {
+		cancel()
+	}
+	if p.pool != nil {
+		p.pool.Close()
+		p.pool = nil
+	}
+	p.typeNames = nil
+	p.schema = nil
+	if p.tunnel != nil {
+		p.tunnel.Close()
+		p.tunnel = nil
+		log.Println("PostgreSQL Executor: SSH tunnel closed")
+	}
+}
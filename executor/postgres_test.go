@@ -6,11 +6,15 @@ package executor
 import (
 	"context"
 	"log"
+	"math"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
 )
 
 // Test configuration for PostgreSQL (can be overridden with env vars)
@@ -145,7 +149,13 @@ func TestPostgreSQLExecutor_QueryTypeDetection(t *testing.T) {
 		{"CREATE query", "CREATE TABLE test (id INT)", "CREATE"},
 		{"DROP query", "DROP TABLE test", "DROP"},
 		{"ALTER query", "ALTER TABLE users ADD COLUMN email VARCHAR(100)", "ALTER"},
-		{"Unknown query", "EXPLAIN SELECT * FROM users", "OTHER"},
+		{"EXPLAIN query", "EXPLAIN SELECT * FROM users", "EXPLAIN"},
+		{"SHOW query", "SHOW search_path", "SHOW"},
+		{"VALUES query", "VALUES (1), (2)", "VALUES"},
+		{"TABLE query", "TABLE users", "TABLE"},
+		{"Parenthesized query", "(SELECT 1) UNION (SELECT 2)", "SELECT"},
+		{"Query after a comment", "/* report */ -- users\nSELECT * FROM users", "SELECT"},
+		{"Unknown query", "VACUUM users", "OTHER"},
 	}
 
 	for _, tc := range testCases {
@@ -170,6 +180,10 @@ func TestPostgreSQLExecutor_IsSelectQuery(t *testing.T) {
 		{"  SELECT  ", true},
 		{"WITH", true},
 		{"with", true},
+		{"EXPLAIN", true},
+		{"SHOW", true},
+		{"VALUES", true},
+		{"TABLE", true},
 		{"INSERT", false},
 		{"UPDATE", false},
 		{"DELETE", false},
@@ -224,6 +238,26 @@ func TestPostgreSQLExecutor_PrepareSQLCode(t *testing.T) {
 			-- Comment 2`,
 			"",
 		},
+		{
+			"Comment markers inside literals",
+			`SELECT '--not a comment', "a--b", E'it\'s -- here' -- comment`,
+			`SELECT '--not a comment', "a--b", E'it\'s -- here'`,
+		},
+		{
+			"Block comments",
+			"/* header\n   /* nested */ still a comment */\nSELECT 1 /* inline */ + 2",
+			"SELECT 1              + 2",
+		},
+		{
+			"Dollar-quoted body",
+			"CREATE FUNCTION f() RETURNS text AS $$\n  -- kept\n  SELECT 'x'\n$$ LANGUAGE sql",
+			"CREATE FUNCTION f() RETURNS text AS $$\n  -- kept\n  SELECT 'x'\n$$ LANGUAGE sql",
+		},
+		{
+			"Multi-line string",
+			"  SELECT 'line 1\n\n    line 3'  ",
+			"SELECT 'line 1\n\n    line 3'",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -247,10 +281,14 @@ func TestPostgreSQLExecutor_ConvertValue(t *testing.T) {
 		{"nil value", nil, nil},
 		{"string value", "hello", "hello"},
 		{"int value", 42, 42},
-		{"byte slice", []byte("hello"), "hello"},
+		{"byte slice", []byte("hello"), `\x68656c6c6f`},
+		{"safe integer", int64(1<<53 - 1), int64(1<<53 - 1)},
 		{"time value", time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC), "2023-01-01T12:00:00Z"},
 		{"bool value", true, true},
 		{"float value", 3.14, 3.14},
+		{"large integer", int64(1<<53 + 1), "9007199254740993"},
+		{"NaN", math.NaN(), "NaN"},
+		{"infinity", math.Inf(-1), "-Infinity"},
 	}
 
 	for _, tc := range testCases {
@@ -263,6 +301,59 @@ func TestPostgreSQLExecutor_ConvertValue(t *testing.T) {
 	}
 }
 
+func TestPostgreSQLExecutor_FormatQueryOutput(t *testing.T) {
+	executor := NewPostgreSQLExecutor(DefaultExecutorOptions())
+
+	makeRows := func(n int) [][]interface{} {
+		rows := make([][]interface{}, n)
+		for i := range rows {
+			rows[i] = []interface{}{i}
+		}
+		return rows
+	}
+
+	t.Run("should not report more rows when all were printed", func(t *testing.T) {
+		output := executor.formatQueryOutput(&SQLQueryResult{
+			QueryType: "SELECT",
+			Columns:   []string{"id"},
+			Rows:      makeRows(3),
+		})
+
+		if strings.Contains(output, "more rows") {
+			t.Errorf("Expected no 'more rows' message, got:\n%s", output)
+		}
+	})
+
+	t.Run("should print at most 100 rows", func(t *testing.T) {
+		output := executor.formatQueryOutput(&SQLQueryResult{
+			QueryType: "SELECT",
+			Columns:   []string{"id"},
+			Rows:      makeRows(150),
+		})
+
+		if !strings.Contains(output, "... and 50 more rows") {
+			t.Errorf("Expected '... and 50 more rows', got:\n%s", output)
+		}
+		if strings.Contains(output, "\n100\n") {
+			t.Errorf("Expected row 100 to be omitted from text output")
+		}
+	})
+
+	t.Run("should report truncated results", func(t *testing.T) {
+		output := executor.formatQueryOutput(&SQLQueryResult{
+			QueryType:   "SELECT",
+			Columns:     []string{"id"},
+			Rows:        makeRows(10),
+			Truncated:   true,
+			DroppedRows: 25,
+		})
+
+		if !strings.Contains(output, "25 more rows were not returned") {
+			t.Errorf("Expected truncation notice, got:\n%s", output)
+		}
+	})
+}
+
 func TestPostgreSQLExecutor_ExecuteWithoutConnection(t *testing.T) {
 	executor := NewPostgreSQLExecutor(DefaultExecutorOptions())
 
@@ -323,6 +414,157 @@ func TestPostgreSQLExecutor_ExecuteWithoutConnection(t *testing.T) {
 	})
 }
 
+// scriptHandler answers the statements used by the script tests.
+func scriptHandler(query string) []standInResult {
+	switch query {
+	case "CREATE TABLE t (id int)":
+		return []standInResult{{Tag: "CREATE TABLE"}}
+	case "INSERT INTO t VALUES (1), (2) RETURNING id":
+		return []standInResult{{
+			Columns: []standInColumn{{Name: "id", OID: 23}},
+			Rows:    [][]*string{textRow("1"), textRow("2")},
+			Tag:     "INSERT 0 2",
+		}}
+	case "SELECT note FROM t":
+		return []standInResult{{Columns: textColumns("note"), Rows: [][]*string{{nil}}, Tag: "SELECT 1"}}
+	case "SELECT broken":
+		return []standInResult{{Err: &pgproto3.ErrorResponse{
+			Severity: "ERROR", Code: "42703", Message: `column "broken" does not exist`, Position: 8,
+		}}}
+	}
+	return []standInResult{{Tag: "SELECT 0", Columns: textColumns("?column?")}}
+}
+
+func TestPostgreSQLExecutor_Scripts(t *testing.T) {
+	t.Run("should return a result per statement", func(t *testing.T) {
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), scriptHandler)
+
+		result, err := executor.Execute(context.Background(), `CREATE TABLE t (id int);
+INSERT INTO t VALUES (1), (2) RETURNING id;
+-- no rows from here on
+CREATE TABLE t (id int);`, "")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.ExitCode != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", result.ExitCode, result.Error)
+		}
+		if len(result.SQLResults) != 3 {
+			t.Fatalf("Expected 3 results, got %d", len(result.SQLResults))
+		}
+
+		created, inserted := result.SQLResults[0], result.SQLResults[1]
+		if created.CommandTag != "CREATE TABLE" || created.ReturnsRows || created.Line != 1 {
+			t.Errorf("Unexpected CREATE result %+v", created)
+		}
+		if inserted.CommandTag != "INSERT 0 2" || !inserted.ReturnsRows || inserted.Line != 2 {
+			t.Errorf("Unexpected INSERT result %+v", inserted)
+		}
+		if len(inserted.Rows) != 2 || inserted.Rows[1][0] != int32(2) {
+			t.Errorf("Expected the returned ids as integers, got %v", inserted.Rows)
+		}
+		if result.SQLResults[2].Line != 4 {
+			t.Errorf("Expected the last statement on line 4, got %d", result.SQLResults[2].Line)
+		}
+		if result.SQLResult == nil || result.SQLResult.Statement != inserted.Statement {
+			t.Errorf("Expected SQLResult to be the last result set, got %+v", result.SQLResult)
+		}
+		if !strings.Contains(result.Output, "-- Statement 2 (line 2): INSERT INTO t") {
+			t.Errorf("Expected a header per statement, got:\n%s", result.Output)
+		}
+	})
+
+	t.Run("should stop at the first error", func(t *testing.T) {
+		executor := newStandInExecutor(t, DefaultExecutorOptions(), scriptHandler)
+
+		result, err := executor.Execute(context.Background(), "SELECT note FROM t;\n  SELECT broken;\nCREATE TABLE t (id int)", "")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.ExitCode != ExitCodePostgresQueryError {
+			t.Fatalf("Expected exit code %d, got %d", ExitCodePostgresQueryError, result.ExitCode)
+		}
+		if len(result.SQLResults) != 2 || result.SQLResults[1].Error == "" {
+			t.Fatalf("Expected the script to stop after the failing statement, got %+v", result.SQLResults)
+		}
+		if result.SQLResults[0].Rows[0][0] != nil {
+			t.Errorf("Expected NULL to be nil, got %v", result.SQLResults[0].Rows[0][0])
+		}
+		if !strings.Contains(result.Error, "statement 2 of 3 (line 2)") {
+			t.Errorf("Expected the failing statement in the error, got %q", result.Error)
+		}
+		if len(result.Diagnostics) != 1 || result.Diagnostics[0].Line != 2 || result.Diagnostics[0].Column != 10 {
+			t.Errorf("Expected a diagnostic at 2:10, got %+v", result.Diagnostics)
+		}
+	})
+
+	t.Run("should continue after errors when configured", func(t *testing.T) {
+		opts := DefaultExecutorOptions()
+		opts.SQL.ContinueOnError = true
+		executor := newStandInExecutor(t, opts, scriptHandler)
+
+		result, err := executor.Execute(context.Background(), "SELECT broken; SELECT broken; CREATE TABLE t (id int)", "")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(result.SQLResults) != 3 || result.SQLResults[2].CommandTag != "CREATE TABLE" {
+			t.Fatalf("Expected all statements to run, got %+v", result.SQLResults)
+		}
+		if result.ExitCode != ExitCodePostgresQueryError || !strings.Contains(result.Error, "1 more statements failed") {
+			t.Errorf("Expected the failures to be reported, got %d: %q", result.ExitCode, result.Error)
+		}
+	})
+
+	t.Run("should stop reading rows over MaxOutputs before the last statement", func(t *testing.T) {
+		clearPGEnv(t)
+		cancelled := make(chan struct{})
+		var once sync.Once
+		port := listenPostgresStandIn(t, nil, func(query string) []standInResult {
+			results := seriesHandler(query)
+			switch query {
+			case "SELECT n FROM series":
+				// A statement that would go on sending rows until cancelled.
+				results[0].Cancel = cancelled
+			case "INSERT INTO t SELECT n FROM series RETURNING n":
+				results = seriesHandler("SELECT n FROM series")
+				results[0].Tag = "INSERT 0 25"
+			}
+			return results
+		}, func() { once.Do(func() { close(cancelled) }) })
+
+		opts := DefaultExecutorOptions()
+		opts.MaxOutputs = 10
+		executor := NewPostgreSQLExecutor(opts)
+		executor.SetConfig(&PostgreSQLConfig{
+			Host: "127.0.0.1", Port: port, Database: "app", Username: "postgres", SSLMode: "disable",
+		})
+		t.Cleanup(func() { executor.Cleanup() })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		result, _ := executor.Execute(ctx, "SELECT n FROM series; SELECT 1", "")
+		if result.ExitCode != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", result.ExitCode, result.Error)
+		}
+		first := result.SQLResults[0]
+		if len(first.Rows) != 10 || !first.Truncated || first.DroppedRows != 0 {
+			t.Fatalf("Expected the statement to be stopped after 10 rows, got rows=%d truncated=%v dropped=%d",
+				len(first.Rows), first.Truncated, first.DroppedRows)
+		}
+		if !strings.Contains(result.Output, "more rows were not read (limit 10)") {
+			t.Errorf("Expected the output to mention the unread rows, got:\n%s", result.Output)
+		}
+
+		// Cancelling would undo the insert, so its rows are read to the end.
+		result, _ = executor.Execute(ctx, "INSERT INTO t SELECT n FROM series RETURNING n; SELECT 1", "")
+		inserted := result.SQLResults[0]
+		if len(inserted.Rows) != 10 || !inserted.Truncated || inserted.DroppedRows != 15 {
+			t.Fatalf("Expected 15 rows read without being returned, got rows=%d truncated=%v dropped=%d",
+				len(inserted.Rows), inserted.Truncated, inserted.DroppedRows)
+		}
+	})
+}
+
 // Integration tests - require live PostgreSQL
 func TestPostgreSQLExecutor_Integration(t *testing.T) {
 	if !isPostgreSQLAvailable() {
@@ -386,6 +628,69 @@ func TestPostgreSQLExecutor_Integration(t *testing.T) {
 		}
 	})
 
+	t.Run("should page rows over MaxOutputs", func(t *testing.T) {
+		opts := DefaultExecutorOptions()
+		opts.MaxOutputs = 10
+		limited := NewPostgreSQLExecutor(opts)
+		limited.SetConfig(config)
+		defer limited.Cleanup()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		result, err := limited.Execute(ctx, "SELECT generate_series(1, 25) AS n", "")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.SQLResult == nil {
+			t.Fatalf("Expected SQLResult to be set. Error: %s", result.Error)
+		}
+
+		if len(result.SQLResult.Rows) != 10 || !result.SQLResult.HasMore {
+			t.Fatalf("Expected a first page of 10 rows, got %d (hasMore=%v)",
+				len(result.SQLResult.Rows), result.SQLResult.HasMore)
+		}
+
+		page, err := limited.FetchRows(ctx, result.SQLResult.ResultHandle, 100)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(page.Rows) != 15 || page.HasMore {
+			t.Errorf("Expected the remaining 15 rows, got %d (hasMore=%v)", len(page.Rows), page.HasMore)
+		}
+	})
+
+	t.Run("should truncate rows over MaxOutputs before the last statement", func(t *testing.T) {
+		opts := DefaultExecutorOptions()
+		opts.MaxOutputs = 10
+		limited := NewPostgreSQLExecutor(opts)
+		limited.SetConfig(config)
+		defer limited.Cleanup()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		result, err := limited.Execute(ctx, "SELECT generate_series(1, 25) AS n; SELECT 1", "")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(result.SQLResults) != 2 {
+			t.Fatalf("Expected 2 results. Error: %s", result.Error)
+		}
+
+		// The rest of the rows are not read, so their number is unknown.
+		first := result.SQLResults[0]
+		if len(first.Rows) != 10 || !first.Truncated || first.DroppedRows != 0 {
+			t.Errorf("Expected 10 rows and the rest unread, got rows=%d truncated=%v dropped=%d",
+				len(first.Rows), first.Truncated, first.DroppedRows)
+		}
+		if result.ExitCode != 0 {
+			t.Errorf("Expected the stopped statement not to fail the run, got %s", result.Error)
+		}
+	})
+
 	t.Run("should handle invalid SQL", func(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -0,0 +1,162 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ExecutorFactory creates a fresh executor configured with opts.
+type ExecutorFactory func(opts ExecutorOptions) Executor
+
+// LanguageRegistration describes a language that ExecutionManager can run.
+type LanguageRegistration struct {
+	Language    Language
+	DisplayName string
+	Extensions  []string // File extensions, with the leading dot
+	New         ExecutorFactory
+
+	// Available reports whether the language can run on this machine, for
+	// example whether its toolchain is installed. Nil means always available.
+	Available func() bool
+	// Version probes the toolchain or engine version. Optional.
+	Version func() string
+}
+
+// LanguageDescriptor is the public view of a registered language.
+type LanguageDescriptor struct {
+	Language    Language `json:"language"`
+	DisplayName string   `json:"displayName"`
+	Extensions  []string `json:"extensions"`
+	Available   bool     `json:"available"`
+	Version     string   `json:"version,omitempty"`
+}
+
+var (
+	registryMu    sync.RWMutex
+	registrations []LanguageRegistration
+)
+
+func init() {
+	MustRegister(LanguageRegistration{
+		Language:    TypeScript,
+		DisplayName: "TypeScript",
+		Extensions:  []string{".ts", ".js", ".mjs"},
+		New:         func(opts ExecutorOptions) Executor { return NewTypeScriptExecutor(opts) },
+		Version:     typeScriptEngineVersion,
+	})
+	MustRegister(LanguageRegistration{
+		Language:    Go,
+		DisplayName: "Go",
+		Extensions:  []string{".go"},
+		New:         func(opts ExecutorOptions) Executor { return NewGoExecutor(opts) },
+		Available:   goInstalled,
+		Version:     GoVersion,
+	})
+	MustRegister(LanguageRegistration{
+		Language:    PostgreSQL,
+		DisplayName: "PostgreSQL",
+		Extensions:  []string{".sql"},
+		New:         func(opts ExecutorOptions) Executor { return NewPostgreSQLExecutor(opts) },
+	})
+}
+
+// Register adds a language so that execution managers created afterwards can
+// run it. Languages are listed in the order they were registered.
+func Register(reg LanguageRegistration) error {
+	if reg.Language == "" {
+		return fmt.Errorf("language registration is missing a language")
+	}
+	if reg.New == nil {
+		return fmt.Errorf("language registration for %s is missing a factory", reg.Language)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, existing := range registrations {
+		if existing.Language == reg.Language {
+			return fmt.Errorf("language %s is already registered", reg.Language)
+		}
+	}
+
+	if reg.DisplayName == "" {
+		reg.DisplayName = string(reg.Language)
+	}
+	registrations = append(registrations, reg)
+	return nil
+}
+
+// MustRegister is like Register but panics if the registration is invalid.
+func MustRegister(reg LanguageRegistration) {
+	if err := Register(reg); err != nil {
+		panic(err)
+	}
+}
+
+// Registration returns the registration for lang.
+func Registration(lang Language) (LanguageRegistration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	for _, reg := range registrations {
+		if reg.Language == lang {
+			return reg, true
+		}
+	}
+	return LanguageRegistration{}, false
+}
+
+// Registrations returns every registered language in registration order.
+func Registrations() []LanguageRegistration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return append([]LanguageRegistration(nil), registrations...)
+}
+
+// LanguageForExtension finds the registered language that handles files with
+// the given extension, such as ".go".
+func LanguageForExtension(ext string) (Language, bool) {
+	ext = strings.ToLower(ext)
+	for _, reg := range Registrations() {
+		for _, candidate := range reg.Extensions {
+			if candidate == ext {
+				return reg.Language, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Describe probes the registration's availability and version.
+func (reg LanguageRegistration) Describe() LanguageDescriptor {
+	descriptor := LanguageDescriptor{
+		Language:    reg.Language,
+		DisplayName: reg.DisplayName,
+		Extensions:  reg.Extensions,
+		Available:   reg.Available == nil || reg.Available(),
+	}
+	if descriptor.Available && reg.Version != nil {
+		descriptor.Version = reg.Version()
+	}
+	return descriptor
+}
+
+// GoVersion returns the version of the installed Go toolchain, such as
+// "1.22.4", or an empty string when Go is not installed.
+func GoVersion() string {
+	output, err := ExecCommand([]string{"go", "version"})
+	if err != nil {
+		return ""
+	}
+	// Expected format: "go version go1.22.4 linux/amd64"
+	parts := strings.Fields(strings.TrimSpace(output))
+	if len(parts) < 3 {
+		return ""
+	}
+	return strings.TrimPrefix(parts[2], "go")
+}
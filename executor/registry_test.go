@@ -0,0 +1,116 @@
+package executor
+
+import (
+	"context"
+	"testing"
+)
+
+type echoExecutor struct{}
+
+func (e *echoExecutor) Execute(ctx context.Context, code string, input string) (*ExecutionResult, error) {
+	return &ExecutionResult{Output: code, Language: "echo"}, nil
+}
+func (e *echoExecutor) Language() Language { return "echo" }
+func (e *echoExecutor) IsAvailable() bool  { return true }
+func (e *echoExecutor) Cleanup() error     { return nil }
+
+func TestRegistry_BuiltinLanguages(t *testing.T) {
+	manager := NewExecutionManager(DefaultExecutorOptions())
+	defer manager.Cleanup()
+
+	languages := manager.GetSupportedLanguages()
+	if len(languages) < 3 {
+		t.Fatalf("Expected at least 3 languages, got %d", len(languages))
+	}
+
+	expected := []Language{TypeScript, Go, PostgreSQL}
+	for i, lang := range expected {
+		if languages[i].Language != lang {
+			t.Errorf("Expected language %d to be %s, got %s", i, lang, languages[i].Language)
+		}
+	}
+
+	if languages[0].DisplayName != "TypeScript" || languages[0].Version == "" {
+		t.Errorf("Unexpected TypeScript descriptor %+v", languages[0])
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	t.Run("should reject invalid registrations", func(t *testing.T) {
+		if err := Register(LanguageRegistration{Language: "broken"}); err == nil {
+			t.Error("Expected error for registration without factory")
+		}
+
+		err := Register(LanguageRegistration{
+			Language: Go,
+			New:      func(opts ExecutorOptions) Executor { return NewGoExecutor(opts) },
+		})
+		if err == nil {
+			t.Error("Expected error for duplicate registration")
+		}
+	})
+
+	t.Run("should run a registered language", func(t *testing.T) {
+		// The registry is global, so only register once when tests are repeated.
+		if _, ok := Registration("echo"); !ok {
+			err := Register(LanguageRegistration{
+				Language:   "echo",
+				Extensions: []string{".echo"},
+				New:        func(opts ExecutorOptions) Executor { return &echoExecutor{} },
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+
+		manager := NewExecutionManager(DefaultExecutorOptions())
+		defer manager.Cleanup()
+
+		result, err := manager.Execute(ExecutionConfig{Code: "hello", Language: "echo"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Output != "hello" {
+			t.Errorf("Expected output hello, got %q", result.Output)
+		}
+
+		languages := manager.GetSupportedLanguages()
+		last := languages[len(languages)-1]
+		if last.Language != "echo" || last.DisplayName != "echo" || !last.Available {
+			t.Errorf("Unexpected descriptor %+v", last)
+		}
+
+		if lang, ok := LanguageForExtension(".ECHO"); !ok || lang != "echo" {
+			t.Errorf("Expected .ECHO to map to echo, got %q", lang)
+		}
+
+		if err := manager.RefreshExecutor("echo"); err != nil {
+			t.Errorf("Expected refresh to succeed, got %v", err)
+		}
+	})
+}
+
+func TestExecutionManager_RefreshExecutor(t *testing.T) {
+	manager := NewExecutionManager(DefaultExecutorOptions())
+	defer manager.Cleanup()
+
+	t.Run("should keep PostgreSQL configuration", func(t *testing.T) {
+		config := &PostgreSQLConfig{Host: "localhost", Port: 5432, Database: "db", Username: "user"}
+		manager.GetExecutor(PostgreSQL).(*PostgreSQLExecutor).SetConfig(config)
+
+		if err := manager.RefreshExecutor(PostgreSQL); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		refreshed := manager.GetExecutor(PostgreSQL).(*PostgreSQLExecutor)
+		if refreshed.Config() != config {
+			t.Error("Expected refreshed executor to keep its configuration")
+		}
+	})
+
+	t.Run("should fail for unknown languages", func(t *testing.T) {
+		if err := manager.RefreshExecutor("cobol"); err == nil {
+			t.Error("Expected error for unknown language")
+		}
+	})
+}
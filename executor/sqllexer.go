@@ -0,0 +1,405 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type sqlTokenKind int
+
+const (
+	sqlTokenWhitespace   sqlTokenKind = iota
+	sqlTokenComment                   // -- line comment or /* block comment */, which may nest
+	sqlTokenWord                      // Keyword or unquoted identifier
+	sqlTokenQuotedIdent               // "identifier" or U&"identifier"
+	sqlTokenString                    // '...', E'...', B'...', X'...', N'...' or U&'...'
+	sqlTokenDollarString              // $$...$$ or $tag$...$tag$
+	sqlTokenNumber                    // Numeric literal
+	sqlTokenParam                     // Positional parameter such as $1
+	sqlTokenSemicolon                 // Statement terminator
+	sqlTokenPunct                     // Operator or other punctuation, one character at a time
+)
+
+// sqlToken is a lexical token of PostgreSQL source. Offset is the byte offset
+// of Text in the source; the tokens of a source concatenate back to it.
+type sqlToken struct {
+	Kind   sqlTokenKind
+	Text   string
+	Offset int
+}
+
+// significant reports whether the token is more than whitespace or a comment.
+func (t sqlToken) significant() bool {
+	return t.Kind != sqlTokenWhitespace && t.Kind != sqlTokenComment
+}
+
+// keyword returns the upper-cased text of a word token, or "" for other tokens.
+func (t sqlToken) keyword() string {
+	if t.Kind != sqlTokenWord {
+		return ""
+	}
+	return strings.ToUpper(t.Text)
+}
+
+// tokenizeSQL splits src into tokens following PostgreSQL's lexical rules,
+// assuming standard_conforming_strings is on. Unterminated strings and
+// comments extend to the end of src.
+func tokenizeSQL(src string) []sqlToken {
+	var tokens []sqlToken
+	for pos := 0; pos < len(src); {
+		kind, end := scanSQLToken(src, pos)
+		tokens = append(tokens, sqlToken{Kind: kind, Text: src[pos:end], Offset: pos})
+		pos = end
+	}
+	return tokens
+}
+
+// scanSQLToken returns the kind and end offset of the token starting at pos.
+func scanSQLToken(src string, pos int) (sqlTokenKind, int) {
+	r, width := utf8.DecodeRuneInString(src[pos:])
+	rest := src[pos+width:]
+
+	switch {
+	case unicode.IsSpace(r):
+		end := pos + width
+		for end < len(src) {
+			r, width := utf8.DecodeRuneInString(src[end:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			end += width
+		}
+		return sqlTokenWhitespace, end
+
+	case r == '-' && strings.HasPrefix(rest, "-"):
+		if newline := strings.IndexByte(src[pos:], '\n'); newline >= 0 {
+			return sqlTokenComment, pos + newline
+		}
+		return sqlTokenComment, len(src)
+
+	case r == '/' && strings.HasPrefix(rest, "*"):
+		return sqlTokenComment, scanBlockComment(src, pos)
+
+	case r == '\'':
+		return sqlTokenString, scanQuoted(src, pos+1, '\'', false)
+
+	case r == '"':
+		return sqlTokenQuotedIdent, scanQuoted(src, pos+1, '"', false)
+
+	case (r == 'e' || r == 'E') && strings.HasPrefix(rest, "'"):
+		return sqlTokenString, scanQuoted(src, pos+2, '\'', true)
+
+	case strings.ContainsRune("bBxXnN", r) && strings.HasPrefix(rest, "'"):
+		return sqlTokenString, scanQuoted(src, pos+2, '\'', false)
+
+	case (r == 'u' || r == 'U') && strings.HasPrefix(rest, "&'"):
+		return sqlTokenString, scanQuoted(src, pos+3, '\'', false)
+
+	case (r == 'u' || r == 'U') && strings.HasPrefix(rest, `&"`):
+		return sqlTokenQuotedIdent, scanQuoted(src, pos+3, '"', false)
+
+	case r == '$':
+		if len(rest) > 0 && isDigit(rest[0]) {
+			end := pos + 1
+			for end < len(src) && isDigit(src[end]) {
+				end++
+			}
+			return sqlTokenParam, end
+		}
+		if tag, ok := dollarTag(src, pos); ok {
+			if closing := strings.Index(src[pos+len(tag):], tag); closing >= 0 {
+				return sqlTokenDollarString, pos + len(tag) + closing + len(tag)
+			}
+			return sqlTokenDollarString, len(src)
+		}
+		return sqlTokenPunct, pos + width
+
+	case isIdentStart(r):
+		end := pos + width
+		for end < len(src) {
+			r, width := utf8.DecodeRuneInString(src[end:])
+			if !isIdentStart(r) && !unicode.IsDigit(r) && r != '$' {
+				break
+			}
+			end += width
+		}
+		return sqlTokenWord, end
+
+	case isDigit(byte(r)) || (r == '.' && len(rest) > 0 && isDigit(rest[0])):
+		return sqlTokenNumber, scanNumber(src, pos)
+
+	case r == ';':
+		return sqlTokenSemicolon, pos + 1
+	}
+
+	return sqlTokenPunct, pos + width
+}
+
+// scanBlockComment returns the end of the possibly nested block comment
+// starting at pos.
+func scanBlockComment(src string, pos int) int {
+	depth := 0
+	for i := pos; i < len(src)-1; i++ {
+		switch {
+		case src[i] == '/' && src[i+1] == '*':
+			depth++
+			i++
+		case src[i] == '*' && src[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(src)
+}
+
+// scanQuoted returns the end of a quoted token whose content starts at pos. A
+// doubled quote stands for itself; with backslashEscapes, so does a quote
+// preceded by a backslash.
+func scanQuoted(src string, pos int, quote byte, backslashEscapes bool) int {
+	for i := pos; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(src) && src[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(src)
+}
+
+// dollarTag returns the opening tag of a dollar-quoted string at pos, such as
+// "$$" or "$body$".
+func dollarTag(src string, pos int) (string, bool) {
+	for i := pos + 1; i < len(src); {
+		r, width := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case r == '$':
+			return src[pos : i+1], true
+		case isIdentStart(r), i > pos+1 && unicode.IsDigit(r):
+			i += width
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func scanNumber(src string, pos int) int {
+	end := pos
+	if strings.HasPrefix(src[pos:], "0x") || strings.HasPrefix(src[pos:], "0X") ||
+		strings.HasPrefix(src[pos:], "0o") || strings.HasPrefix(src[pos:], "0O") ||
+		strings.HasPrefix(src[pos:], "0b") || strings.HasPrefix(src[pos:], "0B") {
+		end += 2
+		for end < len(src) && (isHexDigit(src[end]) || src[end] == '_') {
+			end++
+		}
+		return end
+	}
+
+	for end < len(src) && (isDigit(src[end]) || src[end] == '_') {
+		end++
+	}
+	// A fraction, but not the start of "..".
+	if end < len(src) && src[end] == '.' && !strings.HasPrefix(src[end:], "..") {
+		end++
+		for end < len(src) && (isDigit(src[end]) || src[end] == '_') {
+			end++
+		}
+	}
+	if end < len(src) && (src[end] == 'e' || src[end] == 'E') {
+		exp := end + 1
+		if exp < len(src) && (src[exp] == '+' || src[exp] == '-') {
+			exp++
+		}
+		if exp < len(src) && isDigit(src[exp]) {
+			end = exp
+			for end < len(src) && isDigit(src[end]) {
+				end++
+			}
+		}
+	}
+	return end
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || r >= utf8.RuneSelf && r != utf8.RuneError
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isHexDigit(b byte) bool {
+	return isDigit(b) || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
+
+// sqlStatement is one statement of a script.
+type sqlStatement struct {
+	Text   string     // From the first to the last significant token, without the semicolon
+	Offset int        // Byte offset of Text in the script
+	Tokens []sqlToken // Tokens of Text, with offsets relative to the script
+}
+
+// splitSQLStatements splits a script at top-level semicolons. Statements with
+// nothing but whitespace and comments are left out. Semicolons inside the
+// BEGIN ATOMIC ... END body of CREATE FUNCTION and CREATE PROCEDURE do not
+// end the statement.
+func splitSQLStatements(script string) []sqlStatement {
+	var statements []sqlStatement
+	var current []sqlToken
+	// Depth of BEGIN ATOMIC ... END blocks, and of CASE ... END inside them.
+	atomicDepth := 0
+
+	flush := func() {
+		first, last := -1, -1
+		for i, token := range current {
+			if token.significant() {
+				if first < 0 {
+					first = i
+				}
+				last = i
+			}
+		}
+		if first >= 0 {
+			tokens := current[first : last+1]
+			start := tokens[0].Offset
+			end := tokens[len(tokens)-1].Offset + len(tokens[len(tokens)-1].Text)
+			statements = append(statements, sqlStatement{
+				Text:   script[start:end],
+				Offset: start,
+				Tokens: tokens,
+			})
+		}
+		current = nil
+		atomicDepth = 0
+	}
+
+	for _, token := range tokenizeSQL(script) {
+		if token.Kind == sqlTokenSemicolon && atomicDepth == 0 {
+			flush()
+			continue
+		}
+		current = append(current, token)
+
+		switch token.keyword() {
+		case "ATOMIC":
+			if previousKeyword(current[:len(current)-1]) == "BEGIN" && firstKeyword(current) == "CREATE" {
+				atomicDepth++
+			}
+		case "CASE":
+			if atomicDepth > 0 {
+				atomicDepth++
+			}
+		case "END":
+			if atomicDepth > 0 {
+				atomicDepth--
+			}
+		}
+	}
+	flush()
+
+	return statements
+}
+
+// firstKeyword returns the first significant token of tokens as a keyword.
+func firstKeyword(tokens []sqlToken) string {
+	for _, token := range tokens {
+		if token.significant() {
+			return token.keyword()
+		}
+	}
+	return ""
+}
+
+// previousKeyword returns the last significant token of tokens as a keyword.
+func previousKeyword(tokens []sqlToken) string {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].significant() {
+			return tokens[i].keyword()
+		}
+	}
+	return ""
+}
+
+// leadingKeyword returns the first keyword of a statement, looking past
+// opening parentheses as in "(SELECT ...) UNION ...".
+func leadingKeyword(tokens []sqlToken) string {
+	for _, token := range tokens {
+		if !token.significant() || token.Text == "(" {
+			continue
+		}
+		return token.keyword()
+	}
+	return ""
+}
+
+// mainKeyword returns the keyword of the statement that determines what a
+// statement does: the leading keyword, or for WITH queries, the keyword of
+// the statement that follows the common table expressions.
+func mainKeyword(tokens []sqlToken) string {
+	keyword := leadingKeyword(tokens)
+	if keyword != "WITH" {
+		return keyword
+	}
+
+	depth := 0
+	for _, token := range tokens {
+		switch token.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth != 0 {
+			continue
+		}
+		switch keyword := token.keyword(); keyword {
+		case "SELECT", "VALUES", "TABLE", "INSERT", "UPDATE", "DELETE", "MERGE":
+			return keyword
+		}
+	}
+	return keyword
+}
+
+// hasTopLevelKeyword reports whether keyword appears in tokens outside of
+// parentheses.
+func hasTopLevelKeyword(tokens []sqlToken, keyword string) bool {
+	depth := 0
+	for _, token := range tokens {
+		switch {
+		case token.Text == "(":
+			depth++
+		case token.Text == ")":
+			depth--
+		case depth == 0 && token.keyword() == keyword:
+			return true
+		}
+	}
+	return false
+}
+
+// statementReturnsRows reports whether a statement returns rows: queries,
+// SHOW, EXPLAIN and FETCH, and data-modifying statements with a RETURNING
+// clause.
+func statementReturnsRows(tokens []sqlToken) bool {
+	switch mainKeyword(tokens) {
+	case "SELECT", "VALUES", "TABLE", "SHOW", "EXPLAIN", "FETCH":
+		return true
+	case "INSERT", "UPDATE", "DELETE", "MERGE":
+		return hasTopLevelKeyword(tokens, "RETURNING")
+	}
+	return false
+}
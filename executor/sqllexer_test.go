@@ -0,0 +1,141 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenizeSQL(t *testing.T) {
+	t.Run("should reassemble into the source", func(t *testing.T) {
+		src := "SELECT E'it\\'s', $$a;b$$, \"x\"\"y\" /* /* nested */ */ FROM t -- done\n;"
+		var rebuilt strings.Builder
+		for _, token := range tokenizeSQL(src) {
+			rebuilt.WriteString(token.Text)
+		}
+		if rebuilt.String() != src {
+			t.Fatalf("Expected %q, got %q", src, rebuilt.String())
+		}
+	})
+
+	testCases := []struct {
+		name string
+		src  string
+		kind sqlTokenKind
+	}{
+		{"standard string with doubled quote", "'it''s'", sqlTokenString},
+		{"escape string", `E'it\'s'`, sqlTokenString},
+		{"bit string", "B'1010'", sqlTokenString},
+		{"unicode string", "U&'d\\0061t'", sqlTokenString},
+		{"quoted identifier", `"my ""table"""`, sqlTokenQuotedIdent},
+		{"dollar quote", "$$ SELECT ';' $$", sqlTokenDollarString},
+		{"tagged dollar quote", "$fn$ $$ inner $$ $fn$", sqlTokenDollarString},
+		{"nested block comment", "/* a /* b */ c */", sqlTokenComment},
+		{"line comment", "-- a 'comment'", sqlTokenComment},
+		{"parameter", "$12", sqlTokenParam},
+		{"number", "1.5e-3", sqlTokenNumber},
+		{"identifier with dollar", "foo$bar", sqlTokenWord},
+	}
+
+	for _, tc := range testCases {
+		t.Run("should read a "+tc.name+" as one token", func(t *testing.T) {
+			tokens := tokenizeSQL(tc.src)
+			if len(tokens) != 1 || tokens[0].Kind != tc.kind {
+				t.Fatalf("Expected one token of kind %d, got %+v", tc.kind, tokens)
+			}
+		})
+	}
+
+	t.Run("should not treat a backslash as an escape in standard strings", func(t *testing.T) {
+		tokens := tokenizeSQL(`'C:\' ;`)
+		if tokens[0].Text != `'C:\'` || tokens[len(tokens)-1].Kind != sqlTokenSemicolon {
+			t.Fatalf("Expected the string to end at the second quote, got %+v", tokens)
+		}
+	})
+}
+
+func TestSplitSQLStatements(t *testing.T) {
+	t.Run("should split at top-level semicolons only", func(t *testing.T) {
+		script := `CREATE TABLE t (note text);
+INSERT INTO t VALUES ('a;b'), (E'c\';d'); -- trailing; comment
+/* block; comment */
+CREATE FUNCTION f() RETURNS text AS $$ SELECT 'x;y' $$ LANGUAGE sql;;
+SELECT "semi;colon" FROM t`
+
+		statements := splitSQLStatements(script)
+		want := []string{
+			"CREATE TABLE t (note text)",
+			`INSERT INTO t VALUES ('a;b'), (E'c\';d')`,
+			"CREATE FUNCTION f() RETURNS text AS $$ SELECT 'x;y' $$ LANGUAGE sql",
+			`SELECT "semi;colon" FROM t`,
+		}
+		if len(statements) != len(want) {
+			t.Fatalf("Expected %d statements, got %d: %+v", len(want), len(statements), statements)
+		}
+		for i, statement := range statements {
+			if statement.Text != want[i] {
+				t.Errorf("Statement %d: expected %q, got %q", i+1, want[i], statement.Text)
+			}
+			if script[statement.Offset:statement.Offset+len(statement.Text)] != statement.Text {
+				t.Errorf("Statement %d: offset %d does not point at its text", i+1, statement.Offset)
+			}
+		}
+	})
+
+	t.Run("should keep BEGIN ATOMIC bodies together", func(t *testing.T) {
+		script := `CREATE FUNCTION g(x int) RETURNS int LANGUAGE sql
+BEGIN ATOMIC
+  SELECT CASE WHEN x > 0 THEN 1 ELSE 0 END;
+  SELECT x;
+END;
+BEGIN;
+SELECT 1;
+END`
+
+		statements := splitSQLStatements(script)
+		if len(statements) != 4 {
+			t.Fatalf("Expected 4 statements, got %d: %+v", len(statements), statements)
+		}
+		if !strings.HasSuffix(statements[0].Text, "SELECT x;\nEND") {
+			t.Fatalf("Expected the function body in the first statement, got %q", statements[0].Text)
+		}
+		if statements[1].Text != "BEGIN" || statements[3].Text != "END" {
+			t.Fatalf("Expected transaction statements to be split, got %+v", statements[1:])
+		}
+	})
+
+	t.Run("should skip statements without code", func(t *testing.T) {
+		if statements := splitSQLStatements(" ; -- nothing\n/* here */ ;"); len(statements) != 0 {
+			t.Fatalf("Expected no statements, got %+v", statements)
+		}
+	})
+}
+
+func TestStatementReturnsRows(t *testing.T) {
+	testCases := []struct {
+		statement string
+		expected  bool
+	}{
+		{"SELECT 1", true},
+		{"(SELECT 1) UNION (SELECT 2)", true},
+		{"VALUES (1)", true},
+		{"TABLE users", true},
+		{"SHOW search_path", true},
+		{"EXPLAIN ANALYZE DELETE FROM users", true},
+		{"INSERT INTO users (name) VALUES ('a') RETURNING id", true},
+		{"INSERT INTO users (name) VALUES ('returning')", false},
+		{"DELETE FROM users", false},
+		{"WITH moved AS (DELETE FROM a RETURNING *) INSERT INTO b SELECT * FROM moved", false},
+		{"WITH moved AS (DELETE FROM a RETURNING *) INSERT INTO b SELECT * FROM moved RETURNING id", true},
+		{"WITH recent AS MATERIALIZED (SELECT 1) SELECT * FROM recent", true},
+		{"CREATE TABLE t AS SELECT 1", false},
+		{"SET search_path = public", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.statement, func(t *testing.T) {
+			if result := statementReturnsRows(tokenizeSQL(tc.statement)); result != tc.expected {
+				t.Errorf("Expected statementReturnsRows(%q) = %v, got %v", tc.statement, tc.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package executor
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshKeepAliveInterval is how often the jump host is pinged, so that idle
+// tunnels are not dropped by firewalls and dead ones are noticed.
+const sshKeepAliveInterval = 30 * time.Second
+
+// sshTunnel is an SSH connection to a jump host that database connections are
+// dialed through.
+type sshTunnel struct {
+	client    *ssh.Client
+	agentConn net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// openSSHTunnel connects to the jump host in config, checking its host key
+// against the known hosts file and authenticating with the key file or the
+// SSH agent.
+func openSSHTunnel(ctx context.Context, config *SSHTunnelConfig) (*sshTunnel, error) {
+	if config.Host == "" || config.User == "" {
+		return nil, fmt.Errorf("SSH tunnel requires a host and a user")
+	}
+
+	port := config.Port
+	if port == 0 {
+		port = 22
+	}
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(port))
+
+	knownHostsFile, hostKeyCallback, err := knownHostsCallback(config.KnownHostsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, agentConn, err := sshAuthMethod(config)
+	if err != nil {
+		return nil, err
+	}
+	closeAgent := func() {
+		if agentConn != nil {
+			agentConn.Close()
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		closeAgent()
+		return nil, fmt.Errorf("failed to connect to SSH host %s: %w", addr, err)
+	}
+
+	// The SSH handshake does not take a context; abort it by closing the
+	// connection instead.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, &ssh.ClientConfig{
+		User:            config.User,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: hostKeyCallback,
+	})
+	if !stop() && err == nil {
+		sshConn.Close()
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		closeAgent()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("failed to connect to SSH host %s: %w", addr, ctxErr)
+		}
+		return nil, explainSSHError(err, addr, config.User, knownHostsFile)
+	}
+
+	tunnel := &sshTunnel{
+		client:    ssh.NewClient(sshConn, chans, reqs),
+		agentConn: agentConn,
+		done:      make(chan struct{}),
+	}
+	go tunnel.keepAlive()
+
+	log.Printf("PostgreSQL Executor: SSH tunnel established through %s", addr)
+	return tunnel, nil
+}
+
+// DialContext opens a connection to addr from the jump host. It matches
+// pgconn.DialFunc.
+func (t *sshTunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := t.client.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s through the SSH tunnel: %w", addr, err)
+	}
+	return conn, nil
+}
+
+// LookupHost leaves host names to be resolved by the jump host, which may
+// know names that are not resolvable locally. It matches pgconn.LookupFunc.
+func (t *sshTunnel) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return []string{host}, nil
+}
+
+// Close closes the SSH connection, and with it every connection dialed
+// through the tunnel.
+func (t *sshTunnel) Close() error {
+	var err error
+	t.closeOnce.Do(func() {
+		close(t.done)
+		err = t.client.Close()
+		if t.agentConn != nil {
+			t.agentConn.Close()
+		}
+	})
+	return err
+}
+
+func (t *sshTunnel) keepAlive() {
+	ticker := time.NewTicker(sshKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			if _, _, err := t.client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				log.Printf("PostgreSQL Executor: SSH tunnel keepalive failed, closing tunnel: %v", err)
+				t.Close()
+				return
+			}
+		}
+	}
+}
+
+// knownHostsCallback checks host keys against path, or ~/.ssh/known_hosts
+// when path is empty.
+func knownHostsCallback(path string) (string, ssh.HostKeyCallback, error) {
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to locate known hosts file: %w", err)
+		}
+		path = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	callback, err := knownhosts.New(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil, fmt.Errorf("known hosts file %s does not exist; connect to the SSH host with ssh once to verify and record its host key", path)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read known hosts file %s: %w", path, err)
+	}
+	return path, callback, nil
+}
+
+// sshAuthMethod authenticates with the key file in config, or with the keys
+// of the running SSH agent. The agent connection, if any, must be closed with
+// the tunnel.
+func sshAuthMethod(config *SSHTunnelConfig) (ssh.AuthMethod, net.Conn, error) {
+	if config.KeyFile != "" {
+		signer, err := loadSSHKey(config.KeyFile, config.KeyPassphrase)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ssh.PublicKeys(signer), nil, nil
+	}
+
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, nil, fmt.Errorf("no SSH key file set and no SSH agent is running (SSH_AUTH_SOCK is not set)")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to SSH agent: %w", err)
+	}
+	return ssh.PublicKeysCallback(agent.NewClient(conn).Signers), conn, nil
+}
+
+func loadSSHKey(path, passphrase string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if passphrase == "" {
+			return nil, fmt.Errorf("SSH key %s is encrypted; provide its passphrase", path)
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, fmt.Errorf("SSH key passphrase is incorrect")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// explainSSHError rewords host key and authentication failures.
+func explainSSHError(err error, addr, user, knownHostsFile string) error {
+	var keyErr *knownhosts.KeyError
+	var revokedErr *knownhosts.RevokedError
+
+	switch {
+	case errors.As(err, &keyErr) && len(keyErr.Want) == 0:
+		return fmt.Errorf("SSH host %s is not in %s; connect with ssh once to verify and record its host key", addr, knownHostsFile)
+	case errors.As(err, &keyErr):
+		return fmt.Errorf("host key of SSH host %s does not match %s (line %d); the host may have been reinstalled, or the connection may be intercepted",
+			addr, keyErr.Want[0].Filename, keyErr.Want[0].Line)
+	case errors.As(err, &revokedErr):
+		return fmt.Errorf("host key of SSH host %s has been revoked in %s", addr, knownHostsFile)
+	case strings.Contains(err.Error(), "unable to authenticate"):
+		return fmt.Errorf("SSH authentication as %s on %s failed; check the key file or the keys loaded in the SSH agent", user, addr)
+	}
+	return fmt.Errorf("failed to connect to SSH host %s: %w", addr, err)
+}
@@ -0,0 +1,331 @@
+package executor
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSSHServer is an in-process SSH jump host that forwards direct-tcpip
+// channels, resolving host names with its hosts map.
+type testSSHServer struct {
+	addr    string
+	hostKey ssh.Signer
+	hosts   map[string]string
+
+	mu     sync.Mutex
+	open   int
+	dialed []string
+	closed chan struct{}
+}
+
+func newTestSSHSigner(t *testing.T) (ssh.Signer, ed25519.PrivateKey) {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate SSH key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("Failed to create SSH signer: %v", err)
+	}
+	return signer, key
+}
+
+// startTestSSHServer starts a jump host that accepts the given user key.
+func startTestSSHServer(t *testing.T, userKey ssh.PublicKey, hosts map[string]string) *testSSHServer {
+	t.Helper()
+
+	hostKey, _ := newTestSSHSigner(t)
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == "tunnel" && string(key.Marshal()) == string(userKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &testSSHServer{
+		addr:    listener.Addr().String(),
+		hostKey: hostKey,
+		hosts:   hosts,
+		closed:  make(chan struct{}, 16),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn, config)
+		}
+	}()
+	return server
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	s.mu.Lock()
+	s.open++
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.open--
+		s.mu.Unlock()
+		s.closed <- struct{}{}
+	}()
+
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+
+		var target struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+			newChannel.Reject(ssh.Prohibited, "invalid request")
+			continue
+		}
+		s.mu.Lock()
+		s.dialed = append(s.dialed, target.Host)
+		s.mu.Unlock()
+
+		host, ok := s.hosts[target.Host]
+		if !ok {
+			newChannel.Reject(ssh.ConnectionFailed, "unknown host")
+			continue
+		}
+		upstream, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, channelReqs, err := newChannel.Accept()
+		if err != nil {
+			upstream.Close()
+			continue
+		}
+		go ssh.DiscardRequests(channelReqs)
+		go func() {
+			io.Copy(channel, upstream)
+			channel.CloseWrite()
+		}()
+		go func() {
+			io.Copy(upstream, channel)
+			upstream.Close()
+		}()
+	}
+	serverConn.Wait()
+}
+
+func (s *testSSHServer) openConnections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.open
+}
+
+// knownHosts writes a known_hosts file that trusts key for the server.
+func (s *testSSHServer) knownHosts(t *testing.T, key ssh.PublicKey) string {
+	t.Helper()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(s.addr)}, key)
+	return writeTestFile(t, t.TempDir(), "known_hosts", []byte(line+"\n"))
+}
+
+func (s *testSSHServer) tunnelConfig(t *testing.T) *SSHTunnelConfig {
+	t.Helper()
+
+	host, port, _ := net.SplitHostPort(s.addr)
+	portNumber, _ := strconv.Atoi(port)
+	return &SSHTunnelConfig{Host: host, Port: portNumber, User: "tunnel"}
+}
+
+func writeSSHKey(t *testing.T, key ed25519.PrivateKey, passphrase string) string {
+	t.Helper()
+
+	var block *pem.Block
+	var err error
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(key, "")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatalf("Failed to marshal SSH key: %v", err)
+	}
+	return writeTestFile(t, t.TempDir(), "id_ed25519", pem.EncodeToMemory(block))
+}
+
+func TestPostgreSQLExecutor_SSHTunnel(t *testing.T) {
+	clearPGEnv(t)
+	userSigner, userKey := newTestSSHSigner(t)
+	dbPort := startPostgresStandIn(t, nil, nil)
+
+	newConfig := func(tunnel *SSHTunnelConfig) *PostgreSQLConfig {
+		// db.internal is only resolvable by the jump host.
+		return &PostgreSQLConfig{
+			Host: "db.internal", Port: dbPort, Database: "app", Username: "postgres",
+			SSLMode: "disable", SSHTunnel: tunnel,
+		}
+	}
+
+	t.Run("should connect through the jump host and close the tunnel on cleanup", func(t *testing.T) {
+		server := startTestSSHServer(t, userSigner.PublicKey(), map[string]string{"db.internal": "127.0.0.1"})
+		tunnel := server.tunnelConfig(t)
+		tunnel.KnownHostsFile = server.knownHosts(t, server.hostKey.PublicKey())
+		tunnel.KeyFile = writeSSHKey(t, userKey, "")
+
+		executor := NewPostgreSQLExecutor(ExecutorOptions{})
+		executor.config = newConfig(tunnel)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := executor.ensureConnection(ctx); err != nil {
+			t.Fatalf("Expected to connect through the tunnel, got %v", err)
+		}
+		if server.openConnections() != 1 {
+			t.Fatalf("Expected 1 SSH connection, got %d", server.openConnections())
+		}
+		server.mu.Lock()
+		dialed := strings.Join(server.dialed, ",")
+		server.mu.Unlock()
+		if !strings.HasPrefix(dialed, "db.internal") {
+			t.Fatalf("Expected the jump host to resolve db.internal, got %q", dialed)
+		}
+
+		executor.Cleanup()
+		select {
+		case <-server.closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the SSH connection to be closed on cleanup")
+		}
+		if executor.tunnel != nil {
+			t.Fatal("Expected the tunnel to be released")
+		}
+	})
+
+	t.Run("should authenticate with the SSH agent", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("SSH agent sockets are Unix sockets")
+		}
+		server := startTestSSHServer(t, userSigner.PublicKey(), map[string]string{"db.internal": "127.0.0.1"})
+		tunnel := server.tunnelConfig(t)
+		tunnel.KnownHostsFile = server.knownHosts(t, server.hostKey.PublicKey())
+
+		keyring := agent.NewKeyring()
+		if err := keyring.Add(agent.AddedKey{PrivateKey: userKey}); err != nil {
+			t.Fatalf("Failed to add key to agent: %v", err)
+		}
+		socket := filepath.Join(t.TempDir(), "agent.sock")
+		listener, err := net.Listen("unix", socket)
+		if err != nil {
+			t.Fatalf("Failed to listen on agent socket: %v", err)
+		}
+		defer listener.Close()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				go agent.ServeAgent(keyring, conn)
+			}
+		}()
+		t.Setenv("SSH_AUTH_SOCK", socket)
+
+		if err := connectStandIn(t, newConfig(tunnel)); err != nil {
+			t.Fatalf("Expected to connect with the agent, got %v", err)
+		}
+	})
+
+	t.Run("should reject unknown and changed host keys", func(t *testing.T) {
+		server := startTestSSHServer(t, userSigner.PublicKey(), map[string]string{"db.internal": "127.0.0.1"})
+		tunnel := server.tunnelConfig(t)
+		tunnel.KeyFile = writeSSHKey(t, userKey, "")
+
+		tunnel.KnownHostsFile = writeTestFile(t, t.TempDir(), "known_hosts", nil)
+		err := connectStandIn(t, newConfig(tunnel))
+		if err == nil || !strings.Contains(err.Error(), "is not in") {
+			t.Fatalf("Expected an unknown host error, got %v", err)
+		}
+
+		otherKey, _ := newTestSSHSigner(t)
+		tunnel.KnownHostsFile = server.knownHosts(t, otherKey.PublicKey())
+		err = connectStandIn(t, newConfig(tunnel))
+		if err == nil || !strings.Contains(err.Error(), "does not match") {
+			t.Fatalf("Expected a changed host key error, got %v", err)
+		}
+		if server.openConnections() != 0 {
+			t.Fatal("Expected no SSH connection to be established")
+		}
+
+		tunnel.KnownHostsFile = filepath.Join(t.TempDir(), "missing")
+		err = connectStandIn(t, newConfig(tunnel))
+		if err == nil || !strings.Contains(err.Error(), "does not exist") {
+			t.Fatalf("Expected a missing known hosts error, got %v", err)
+		}
+	})
+
+	t.Run("should explain key problems", func(t *testing.T) {
+		server := startTestSSHServer(t, userSigner.PublicKey(), map[string]string{"db.internal": "127.0.0.1"})
+		tunnel := server.tunnelConfig(t)
+		tunnel.KnownHostsFile = server.knownHosts(t, server.hostKey.PublicKey())
+		tunnel.KeyFile = writeSSHKey(t, userKey, "secret")
+
+		err := connectStandIn(t, newConfig(tunnel))
+		if err == nil || !strings.Contains(err.Error(), "is encrypted") {
+			t.Fatalf("Expected an encrypted key error, got %v", err)
+		}
+
+		tunnel.KeyPassphrase = "wrong"
+		err = connectStandIn(t, newConfig(tunnel))
+		if err == nil || !strings.Contains(err.Error(), "passphrase is incorrect") {
+			t.Fatalf("Expected an incorrect passphrase error, got %v", err)
+		}
+
+		tunnel.KeyPassphrase = "secret"
+		if err := connectStandIn(t, newConfig(tunnel)); err != nil {
+			t.Fatalf("Expected to connect with the passphrase, got %v", err)
+		}
+
+		_, otherKey := newTestSSHSigner(t)
+		tunnel.KeyFile = writeSSHKey(t, otherKey, "")
+		tunnel.KeyPassphrase = ""
+		err = connectStandIn(t, newConfig(tunnel))
+		if err == nil || !strings.Contains(err.Error(), "SSH authentication as tunnel") {
+			t.Fatalf("Expected an authentication error, got %v", err)
+		}
+	})
+}
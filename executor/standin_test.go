@@ -0,0 +1,227 @@
+package executor
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// standInColumn describes a result column. Values are sent in text format.
+type standInColumn struct {
+	Name        string
+	OID         uint32
+	TableOID    uint32 // Set for columns read straight from a table
+	TableColumn uint16
+}
+
+// standInResult is the server's answer to one statement.
+type standInResult struct {
+	Columns []standInColumn // Nil for statements that return no rows
+	Rows    [][]*string     // Nil values are NULL
+	Tag     string
+	Err     *pgproto3.ErrorResponse   // Ends the answer to the query
+	Notices []pgproto3.NoticeResponse // Sent before the statement's rows or error
+
+	TxStatus byte // Transaction status the statement leaves behind; zero keeps the current one
+
+	// If set, the statement keeps running after its rows are sent until
+	// Cancel is closed, and then fails as cancelled.
+	Cancel <-chan struct{}
+}
+
+// standInHandler answers a simple query. No results means an empty query.
+type standInHandler func(query string) []standInResult
+
+// textColumns returns text columns with the given names.
+func textColumns(names ...string) []standInColumn {
+	columns := make([]standInColumn, len(names))
+	for i, name := range names {
+		columns[i] = standInColumn{Name: name, OID: 25}
+	}
+	return columns
+}
+
+// textRow returns a row of non-NULL values.
+func textRow(values ...string) []*string {
+	row := make([]*string, len(values))
+	for i := range values {
+		row[i] = &values[i]
+	}
+	return row
+}
+
+// startPostgresStandIn starts a stand-in PostgreSQL server that trusts any
+// user and answers simple queries with handler, or with an empty response
+// when handler is nil. With tlsConfig it accepts only TLS connections,
+// otherwise only plain ones. It returns the server's port.
+func startPostgresStandIn(t *testing.T, tlsConfig *tls.Config, handler standInHandler) int {
+	t.Helper()
+	return listenPostgresStandIn(t, tlsConfig, handler, nil)
+}
+
+// listenPostgresStandIn is startPostgresStandIn with onCancel called for
+// every cancel request the server receives.
+func listenPostgresStandIn(t *testing.T, tlsConfig *tls.Config, handler standInHandler, onCancel func()) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go servePostgresStandIn(conn, tlsConfig, handler, onCancel)
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func servePostgresStandIn(conn net.Conn, tlsConfig *tls.Config, handler standInHandler, onCancel func()) {
+	defer conn.Close()
+
+	backend := pgproto3.NewBackend(conn, conn)
+	startup, err := backend.ReceiveStartupMessage()
+	if err != nil {
+		return
+	}
+
+	if _, ok := startup.(*pgproto3.SSLRequest); ok {
+		if tlsConfig == nil {
+			if _, err := conn.Write([]byte{'N'}); err != nil {
+				return
+			}
+		} else {
+			if _, err := conn.Write([]byte{'S'}); err != nil {
+				return
+			}
+			tlsConn := tls.Server(conn, tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			backend = pgproto3.NewBackend(tlsConn, tlsConn)
+		}
+		if startup, err = backend.ReceiveStartupMessage(); err != nil {
+			return
+		}
+	} else if tlsConfig != nil {
+		return
+	}
+	if _, ok := startup.(*pgproto3.CancelRequest); ok && onCancel != nil {
+		onCancel()
+		return
+	}
+	if _, ok := startup.(*pgproto3.StartupMessage); !ok {
+		return
+	}
+
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.ParameterStatus{Name: "server_version", Value: "16.0"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	if err := backend.Flush(); err != nil {
+		return
+	}
+
+	txStatus := byte('I')
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+		switch msg := msg.(type) {
+		case *pgproto3.Query:
+			var results []standInResult
+			if handler != nil {
+				results = handler(msg.String)
+			}
+			txStatus = sendStandInResults(backend, results, txStatus)
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: txStatus})
+			if err := backend.Flush(); err != nil {
+				return
+			}
+		case *pgproto3.Terminate:
+			return
+		}
+	}
+}
+
+// sendStandInResults sends results and returns the transaction status they
+// leave behind. An error inside a transaction fails it, as on a real server.
+func sendStandInResults(backend *pgproto3.Backend, results []standInResult, txStatus byte) byte {
+	if len(results) == 0 {
+		backend.Send(&pgproto3.EmptyQueryResponse{})
+		return txStatus
+	}
+
+	for _, result := range results {
+		for i := range result.Notices {
+			backend.Send(&result.Notices[i])
+		}
+		if result.Err != nil {
+			backend.Send(result.Err)
+			if txStatus != 'I' {
+				return 'E'
+			}
+			return txStatus
+		}
+		if result.Columns != nil {
+			fields := make([]pgproto3.FieldDescription, len(result.Columns))
+			for i, column := range result.Columns {
+				fields[i] = pgproto3.FieldDescription{
+					Name: []byte(column.Name), DataTypeOID: column.OID, DataTypeSize: -1, TypeModifier: -1,
+					TableOID: column.TableOID, TableAttributeNumber: column.TableColumn,
+				}
+			}
+			backend.Send(&pgproto3.RowDescription{Fields: fields})
+			for _, row := range result.Rows {
+				values := make([][]byte, len(row))
+				for i, value := range row {
+					if value != nil {
+						values[i] = []byte(*value)
+					}
+				}
+				backend.Send(&pgproto3.DataRow{Values: values})
+			}
+		}
+		if result.Cancel != nil {
+			backend.Flush()
+			<-result.Cancel
+			backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "57014", Message: "canceling statement due to user request"})
+			if txStatus != 'I' {
+				return 'E'
+			}
+			return txStatus
+		}
+		backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(result.Tag)})
+		if result.TxStatus != 0 {
+			txStatus = result.TxStatus
+		}
+	}
+	return txStatus
+}
+
+// newStandInExecutor returns a PostgreSQL executor connected to a stand-in
+// server that answers with handler.
+func newStandInExecutor(t *testing.T, opts ExecutorOptions, handler standInHandler) *PostgreSQLExecutor {
+	t.Helper()
+
+	clearPGEnv(t)
+	port := startPostgresStandIn(t, nil, handler)
+	executor := NewPostgreSQLExecutor(opts)
+	executor.SetConfig(&PostgreSQLConfig{
+		Host: "127.0.0.1", Port: port, Database: "app", Username: "postgres", SSLMode: "disable",
+	})
+	t.Cleanup(func() { executor.Cleanup() })
+	return executor
+}
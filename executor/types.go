@@ -5,6 +5,8 @@ package executor
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"time"
 )
 
@@ -17,32 +19,117 @@ const (
 	PostgreSQL Language = "postgres"
 )
 
+const (
+	ExitCodeTimeout             = 124 // Execution exceeded its timeout
+	ExitCodeCancelled           = 130 // Execution was cancelled by the user
+	ExitCodeMemoryLimitExceeded = 137 // Execution exceeded ExecutorOptions.MemoryMB
+)
+
+// ErrExecutionCancelled is the context cause used when a user cancels an execution.
+var ErrExecutionCancelled = errors.New("execution cancelled")
+
 const (
 	ExitCodeGoNotInstalled       = 150 // Go compiler not found/installed
 	ExitCodePostgresNotAvailable = 151 // PostgreSQL executor not available
 	ExitCodePostgresConnFailed   = 152 // PostgreSQL connection failed
 	ExitCodePostgresQueryError   = 153 // PostgreSQL query execution error
+	ExitCodePostgresUnconfirmed  = 154 // Safe mode held back destructive statements; run again with ExecutionConfig.Confirmed
 	ExitCodeNodeNotAvailable     = 160 // Node.js not available
 )
 
 type ExecutionConfig struct {
+	ExecutionID    string            `json:"executionId,omitempty"`
 	Code           string            `json:"code"`
 	Language       Language          `json:"language"`
 	Timeout        time.Duration     `json:"timeout"`
 	Input          string            `json:"input,omitempty"`
 	PostgreSQLConn *PostgreSQLConfig `json:"postgresqlConn,omitempty"`
+	Stream         bool              `json:"stream,omitempty"`
+	Explain        ExplainMode       `json:"explain,omitempty"`   // Explain the SQL statement instead of running it
+	Confirmed      bool              `json:"confirmed,omitempty"` // Run the destructive SQL statements that safe mode holds back
 }
 
 type ExecutionResult struct {
-	Output         string          `json:"output"`
-	Error          string          `json:"error"`
-	ExitCode       int             `json:"exitCode"`
-	Duration       time.Duration   `json:"duration"`
-	DurationString string          `json:"durationString"`
-	Language       Language        `json:"language"`
-	SQLResult      *SQLQueryResult `json:"sqlResult,omitempty"`
+	ExecutionID    string             `json:"executionId,omitempty"`
+	Output         string             `json:"output"`
+	Error          string             `json:"error"`
+	ExitCode       int                `json:"exitCode"`
+	Duration       time.Duration      `json:"duration"`
+	DurationString string             `json:"durationString"`
+	Language       Language           `json:"language"`
+	SQLResult      *SQLQueryResult    `json:"sqlResult,omitempty"`  // The last result set of a script, or its last statement
+	SQLResults     []SQLQueryResult   `json:"sqlResults,omitempty"` // One per statement run, in order
+	Truncation     *OutputTruncation  `json:"truncation,omitempty"` // Set when output went over MaxOutputs or MaxOutputBytes
+	Diagnostics    []Diagnostic       `json:"diagnostics,omitempty"`
+	Transaction    TransactionStatus  `json:"transaction,omitempty"` // State of the PostgreSQL session after the run, in session mode
+	Plan           *QueryPlan         `json:"plan,omitempty"`        // Set for runs in explain mode
+	Notices        []SQLNotice        `json:"notices,omitempty"`     // Messages the PostgreSQL server sent during the run, in order
+	SQLState       string             `json:"sqlState,omitempty"`    // SQLSTATE of the error that ended a PostgreSQL run
+	Limit          SQLLimit           `json:"limit,omitempty"`       // Set when a PostgreSQL run was stopped by a time limit
+	Guarded        []GuardedStatement `json:"guarded,omitempty"`     // Statements read-only or safe mode kept from running; none of the run was executed
+}
+
+// GuardedStatement is a statement that a PostgreSQL connection's read-only or
+// safe mode kept from running.
+type GuardedStatement struct {
+	Statement int    `json:"statement"`      // Index of the statement in the script
+	Line      int    `json:"line,omitempty"` // Line of the statement in the user's code
+	Text      string `json:"text"`           // Start of the statement
+	Reason    string `json:"reason"`         // What the statement would do, e.g. "DELETE without WHERE removes every row"
+}
+
+// SQLLimit names the time limit that stopped a PostgreSQL run.
+type SQLLimit string
+
+const (
+	LimitRunTimeout               SQLLimit = "timeout"                             // The run's own timeout; the server was asked to cancel the statement
+	LimitStatementTimeout         SQLLimit = "statement_timeout"                   // SQLOptions.StatementTimeout
+	LimitLockTimeout              SQLLimit = "lock_timeout"                        // SQLOptions.LockTimeout
+	LimitIdleInTransactionTimeout SQLLimit = "idle_in_transaction_session_timeout" // SQLOptions.IdleInTransactionTimeout; the server closed the connection
+)
+
+type DiagnosticSeverity string
+
+const (
+	SeverityError   DiagnosticSeverity = "error"
+	SeverityWarning DiagnosticSeverity = "warning"
+)
+
+// Diagnostic points at a problem in the code as the user wrote it.
+// Line and Column are 1-based; they are 0 when the position is unknown or
+// falls outside the user's code.
+type Diagnostic struct {
+	Severity DiagnosticSeverity `json:"severity"`
+	Line     int                `json:"line"`
+	Column   int                `json:"column"`
+	Message  string             `json:"message"`
+	Source   string             `json:"source"` // go, esbuild, v8, goja or postgres
 }
 
+// OutputTruncation describes output that was dropped because it went over the
+// configured limits.
+type OutputTruncation struct {
+	DroppedLines int `json:"droppedLines"`
+	DroppedBytes int `json:"droppedBytes"`
+}
+
+type OutputStream string
+
+const (
+	Stdout OutputStream = "stdout"
+	Stderr OutputStream = "stderr"
+)
+
+// OutputChunk is a piece of program output emitted while an execution is still running.
+type OutputChunk struct {
+	ExecutionID string       `json:"executionId"`
+	Stream      OutputStream `json:"stream"`
+	Data        string       `json:"data"`
+}
+
+// OutputFunc receives incremental output from a running program.
+type OutputFunc func(stream OutputStream, data string)
+
 type Executor interface {
 	Execute(ctx context.Context, code string, input string) (*ExecutionResult, error)
 	Language() Language
@@ -50,33 +137,415 @@ type Executor interface {
 	Cleanup() error
 }
 
+// StreamingExecutor is implemented by executors that can report output
+// before the program finishes.
+type StreamingExecutor interface {
+	Executor
+	ExecuteStream(ctx context.Context, code string, input string, onOutput OutputFunc) (*ExecutionResult, error)
+}
+
 type ExecutorOptions struct {
-	Timeout    time.Duration
-	MemoryMB   int
-	MaxOutputs int
+	Timeout        time.Duration
+	MemoryMB       int
+	MaxOutputs     int // Output lines, or result rows for SQL, kept per execution
+	MaxOutputBytes int // Output bytes kept per execution
+	SQL            SQLOptions
+}
+
+// SQLOptions configure how the PostgreSQL executor runs scripts.
+type SQLOptions struct {
+	ContinueOnError bool // Run the remaining statements after one fails
+
+	// Limits the server enforces on each connection; zero keeps the server's
+	// own setting.
+	StatementTimeout         time.Duration // statement_timeout: how long a statement may run
+	LockTimeout              time.Duration // lock_timeout: how long a statement may wait for a lock
+	IdleInTransactionTimeout time.Duration // idle_in_transaction_session_timeout: how long an open transaction may sit idle
+}
+
+// timeoutOr returns the configured timeout, or fallback when none is set.
+func (o ExecutorOptions) timeoutOr(fallback time.Duration) time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return fallback
+}
+
+// merge returns o with every non-zero field of override applied.
+func (o ExecutorOptions) merge(override ExecutorOptions) ExecutorOptions {
+	if override.Timeout > 0 {
+		o.Timeout = override.Timeout
+	}
+	if override.MemoryMB > 0 {
+		o.MemoryMB = override.MemoryMB
+	}
+	if override.MaxOutputs > 0 {
+		o.MaxOutputs = override.MaxOutputs
+	}
+	if override.MaxOutputBytes > 0 {
+		o.MaxOutputBytes = override.MaxOutputBytes
+	}
+	o.SQL = o.SQL.merge(override.SQL)
+	return o
+}
+
+// merge returns o with every non-zero field of override applied.
+func (o SQLOptions) merge(override SQLOptions) SQLOptions {
+	if override.ContinueOnError {
+		o.ContinueOnError = true
+	}
+	if override.StatementTimeout > 0 {
+		o.StatementTimeout = override.StatementTimeout
+	}
+	if override.LockTimeout > 0 {
+		o.LockTimeout = override.LockTimeout
+	}
+	if override.IdleInTransactionTimeout > 0 {
+		o.IdleInTransactionTimeout = override.IdleInTransactionTimeout
+	}
+	return o
 }
 
 func DefaultExecutorOptions() ExecutorOptions {
 	return ExecutorOptions{
-		Timeout:    10 * time.Second,
-		MemoryMB:   50,
-		MaxOutputs: 1000,
+		Timeout:        10 * time.Second,
+		MemoryMB:       50,
+		MaxOutputs:     1000,
+		MaxOutputBytes: 1 << 20,
 	}
 }
 
+// PostgreSQLConfig describes a connection either field by field or as a
+// ConnectionString (a postgres:// URI or keyword/value string), which takes
+// precedence over the individual fields except Password. Unset values are
+// resolved like libpq does, from the service file, PG* environment variables
+// and the password file.
 type PostgreSQLConfig struct {
-	Host     string `json:"host"`
+	Host     string `json:"host"` // Host name, IP address or Unix socket directory
 	Port     int    `json:"port"`
 	Database string `json:"database"`
 	Username string `json:"username"`
 	Password string `json:"password"`
 	SSLMode  string `json:"sslMode"`
+
+	Service          string `json:"service,omitempty"`          // Service name from pg_service.conf
+	ConnectionString string `json:"connectionString,omitempty"` // URI or keyword/value string
+
+	// TLS options. Certificate and key values are file paths.
+	SSLRootCert   string `json:"sslRootCert,omitempty"`   // CA bundle to verify the server, or "system"
+	SSLCert       string `json:"sslCert,omitempty"`       // Client certificate
+	SSLKey        string `json:"sslKey,omitempty"`        // Client private key
+	SSLPassword   string `json:"sslPassword,omitempty"`   // Passphrase of an encrypted SSLKey
+	SSLServerName string `json:"sslServerName,omitempty"` // Name sent as SNI and verified in verify-full mode, if not the host
+
+	SSHTunnel *SSHTunnelConfig `json:"sshTunnel,omitempty"` // Reach the database through an SSH jump host
+
+	// Guards against changing the database by accident. A read-only
+	// connection refuses statements that can write and starts its sessions
+	// with default_transaction_read_only; safe mode holds back DROP, ALTER
+	// ... DROP, TRUNCATE, DO blocks, and UPDATE and DELETE without WHERE until
+	// the run is confirmed.
+	ReadOnly bool `json:"readOnly,omitempty"`
+	SafeMode bool `json:"safeMode,omitempty"`
+}
+
+// SSHTunnelConfig describes an SSH jump host. Database connections are opened
+// from the jump host, so the database host is resolved there.
+type SSHTunnelConfig struct {
+	Host           string `json:"host"`
+	Port           int    `json:"port,omitempty"` // Defaults to 22
+	User           string `json:"user"`
+	KeyFile        string `json:"keyFile,omitempty"`        // Private key; the SSH agent is used when empty
+	KeyPassphrase  string `json:"keyPassphrase,omitempty"`  // Passphrase of an encrypted KeyFile
+	KnownHostsFile string `json:"knownHostsFile,omitempty"` // Defaults to ~/.ssh/known_hosts
 }
 
 type SQLQueryResult struct {
+	Statement     string          `json:"statement,omitempty"`
+	Line          int             `json:"line,omitempty"` // Line of the statement in the user's code
 	QueryType     string          `json:"queryType"`
+	CommandTag    string          `json:"commandTag,omitempty"` // As reported by the server, e.g. "INSERT 0 3"
+	Error         string          `json:"error,omitempty"`
+	ReturnsRows   bool            `json:"returnsRows"` // The statement returned a result set, possibly empty
 	Columns       []string        `json:"columns"`
+	ColumnInfo    []SQLColumn     `json:"columnInfo,omitempty"` // Metadata of Columns, for statements that return rows
 	Rows          [][]interface{} `json:"rows"`
 	RowsAffected  int64           `json:"rowsAffected"`
 	ExecutionTime time.Duration   `json:"executionTime"`
+	Truncated     bool            `json:"truncated,omitempty"`    // Rows of a statement before the last went over ExecutorOptions.MaxOutputs
+	DroppedRows   int64           `json:"droppedRows,omitempty"`  // Rows read but not returned; 0 when the rest were not read
+	HasMore       bool            `json:"hasMore,omitempty"`      // More rows can be fetched with ResultHandle
+	ResultHandle  string          `json:"resultHandle,omitempty"` // Identifies the open result for PostgreSQLExecutor.FetchRows
+}
+
+// TransactionStatus is the transaction state of a PostgreSQL session.
+type TransactionStatus string
+
+const (
+	TransactionIdle   TransactionStatus = "idle"          // No transaction is open
+	TransactionActive TransactionStatus = "inTransaction" // A transaction is open
+	TransactionFailed TransactionStatus = "failed"        // A statement failed; the transaction can only be rolled back
+)
+
+// SessionStatus describes how the PostgreSQL executor runs queries.
+type SessionStatus struct {
+	Session     bool              `json:"session"`               // Runs share a dedicated connection, so transactions and session state carry over
+	Sandbox     bool              `json:"sandbox"`               // Every run is rolled back when it ends
+	ReadOnly    bool              `json:"readOnly"`              // The connection refuses statements that can write
+	SafeMode    bool              `json:"safeMode"`              // Destructive statements need confirmation
+	Connected   bool              `json:"connected"`             // The session's connection is open
+	Transaction TransactionStatus `json:"transaction,omitempty"` // Set while the session's connection is open
+}
+
+// SQLPage is a batch of rows fetched from an open result.
+type SQLPage struct {
+	Handle      string          `json:"handle"`
+	Rows        [][]interface{} `json:"rows"`
+	HasMore     bool            `json:"hasMore"`              // More rows can be fetched; false once the result is closed
+	CommandTag  string          `json:"commandTag,omitempty"` // Set once the last row is fetched
+	RowsFetched int64           `json:"rowsFetched"`          // Rows of the result fetched so far, including this page
+	Notices     []SQLNotice     `json:"notices,omitempty"`    // Messages the server sent while this page was read
+}
+
+// SQLNotice is a message the server sent while a statement ran, such as the
+// output of RAISE NOTICE in PL/pgSQL.
+type SQLNotice struct {
+	Severity  string `json:"severity"` // e.g. "NOTICE", "WARNING", "INFO" or "DEBUG"
+	Code      string `json:"code"`     // SQLSTATE
+	Message   string `json:"message"`
+	Detail    string `json:"detail,omitempty"`
+	Hint      string `json:"hint,omitempty"`
+	Where     string `json:"where,omitempty"`    // Context, such as the PL/pgSQL function and line
+	Position  int    `json:"position,omitempty"` // Character in the statement, counting from 1
+	Statement int    `json:"statement"`          // Index in ExecutionResult.SQLResults of the statement that was running
+	Line      int    `json:"line,omitempty"`     // Line in the user's code
+	Column    int    `json:"column,omitempty"`
+}
+
+// SQLColumn describes a result column. Rows hold its values as JSON-safe
+// representations that lose nothing: numeric as an exact decimal string,
+// json and jsonb parsed, arrays as arrays, bytea as a "\x" hex string, and
+// most other types as PostgreSQL's text representation.
+type SQLColumn struct {
+	Name        string `json:"name"`
+	TypeName    string `json:"typeName"` // As in pg_type, e.g. "int4", "numeric" or "text[]"; empty if unknown
+	TypeOID     uint32 `json:"typeOid"`
+	Nullable    *bool  `json:"nullable,omitempty"`    // Nil unless the column is read straight from a table
+	TableOID    uint32 `json:"tableOid,omitempty"`    // Table the column is read from, if any
+	TableColumn int    `json:"tableColumn,omitempty"` // Attribute number of the column in that table
+}
+
+// ExportFormat is a format SQL result sets are exported in.
+type ExportFormat string
+
+const (
+	ExportCSV      ExportFormat = "csv"      // RFC 4180, with CRLF line ends
+	ExportTSV      ExportFormat = "tsv"      // Tab-separated, as Excel copies and pastes cells
+	ExportJSON     ExportFormat = "json"     // An array of objects keyed by column name
+	ExportNDJSON   ExportFormat = "ndjson"   // One object per line
+	ExportMarkdown ExportFormat = "markdown" // A GitHub-flavored Markdown table
+	ExportInsert   ExportFormat = "insert"   // An INSERT statement per row
+)
+
+// ExportQuoting selects which CSV and TSV fields are quoted.
+type ExportQuoting string
+
+const (
+	QuoteMinimal ExportQuoting = "minimal" // Fields with the delimiter, a quote or a line break, and empty strings
+	QuoteAll     ExportQuoting = "all"     // Every field but NULLs
+)
+
+// ExportOptions configure how a SQL result set is exported.
+type ExportOptions struct {
+	Format    ExportFormat  `json:"format"`
+	Delimiter string        `json:"delimiter,omitempty"` // CSV field delimiter, a single character; defaults to a comma
+	Quoting   ExportQuoting `json:"quoting,omitempty"`   // CSV and TSV; defaults to QuoteMinimal
+	NoHeader  bool          `json:"noHeader,omitempty"`  // Leave out the header line of CSV and TSV
+	Null      string        `json:"null,omitempty"`      // Text of NULL in CSV, TSV and Markdown; empty by default
+	Table     string        `json:"table,omitempty"`     // Table of INSERT statements, as written in SQL; defaults to exported_rows
+}
+
+// ExplainMode selects how a SQL statement is explained.
+type ExplainMode string
+
+const (
+	ExplainPlan    ExplainMode = "plan"    // The planner's estimates; the statement does not run
+	ExplainAnalyze ExplainMode = "analyze" // Runs the statement in a transaction that is rolled back, adding actual timings, rows and buffers
+)
+
+// QueryPlan is the execution plan of a statement, as reported by EXPLAIN.
+// Times are in milliseconds.
+type QueryPlan struct {
+	Statement     string          `json:"statement"`
+	Analyzed      bool            `json:"analyzed"`
+	Root          PlanNode        `json:"root"`
+	PlanningTime  float64         `json:"planningTime,omitempty"`
+	ExecutionTime float64         `json:"executionTime,omitempty"` // Set for analyzed plans
+	Raw           json.RawMessage `json:"raw"`                     // The plan as PostgreSQL returned it
+}
+
+// PlanNode is a step of a query plan. Costs are in the planner's arbitrary
+// units, and rows are per loop, as in PostgreSQL's output.
+type PlanNode struct {
+	NodeType           string                 `json:"nodeType"`                     // e.g. "Seq Scan" or "Hash Join"
+	ParentRelationship string                 `json:"parentRelationship,omitempty"` // e.g. "Outer", "Inner" or "SubPlan"
+	RelationName       string                 `json:"relationName,omitempty"`
+	Schema             string                 `json:"schema,omitempty"`
+	Alias              string                 `json:"alias,omitempty"`
+	IndexName          string                 `json:"indexName,omitempty"`
+	JoinType           string                 `json:"joinType,omitempty"`
+	StartupCost        float64                `json:"startupCost"`
+	TotalCost          float64                `json:"totalCost"`
+	PlanRows           float64                `json:"planRows"` // Estimated rows
+	PlanWidth          int                    `json:"planWidth"`
+	Actual             *PlanActual            `json:"actual,omitempty"`  // Set for analyzed plans
+	Buffers            *PlanBuffers           `json:"buffers,omitempty"` // Set for analyzed plans
+	Flags              []PlanFlag             `json:"flags,omitempty"`
+	Details            map[string]interface{} `json:"details,omitempty"` // Other properties PostgreSQL reported, such as "Filter", by their EXPLAIN names
+	Children           []PlanNode             `json:"children,omitempty"`
+}
+
+// PlanActual is what an analyzed plan node did. Loops is 0 for nodes that
+// never ran.
+type PlanActual struct {
+	StartupTime float64 `json:"startupTime"`
+	TotalTime   float64 `json:"totalTime"` // Per loop
+	Rows        float64 `json:"rows"`      // Per loop
+	Loops       float64 `json:"loops"`
+}
+
+// PlanBuffers counts the blocks a plan node and its children used.
+type PlanBuffers struct {
+	SharedHit     int64 `json:"sharedHit"`
+	SharedRead    int64 `json:"sharedRead"`
+	SharedDirtied int64 `json:"sharedDirtied"`
+	SharedWritten int64 `json:"sharedWritten"`
+	LocalHit      int64 `json:"localHit"`
+	LocalRead     int64 `json:"localRead"`
+	TempRead      int64 `json:"tempRead"`
+	TempWritten   int64 `json:"tempWritten"`
+}
+
+type PlanFlagKind string
+
+const (
+	PlanFlagMisestimate PlanFlagKind = "misestimate" // Actual rows are far from the estimate
+	PlanFlagSeqScan     PlanFlagKind = "seqScan"     // The node reads a whole table
+)
+
+// PlanFlag points out a plan node worth a closer look.
+type PlanFlag struct {
+	Kind    PlanFlagKind `json:"kind"`
+	Message string       `json:"message"`
+}
+
+// DatabaseSchema describes the objects of a database that the connected user
+// can see, leaving out PostgreSQL's own schemas.
+type DatabaseSchema struct {
+	Schemas  []SchemaInfo `json:"schemas"`
+	LoadedAt time.Time    `json:"loadedAt"`
+}
+
+type SchemaInfo struct {
+	Name      string         `json:"name"`
+	Owner     string         `json:"owner"`
+	Comment   string         `json:"comment,omitempty"`
+	Tables    []TableInfo    `json:"tables"` // Tables, views, materialized views, partitioned and foreign tables
+	Functions []FunctionInfo `json:"functions"`
+	Sequences []SequenceInfo `json:"sequences"`
+}
+
+type TableKind string
+
+const (
+	TableKindTable            TableKind = "table"
+	TableKindPartitioned      TableKind = "partitionedTable"
+	TableKindView             TableKind = "view"
+	TableKindMaterializedView TableKind = "materializedView"
+	TableKindForeign          TableKind = "foreignTable"
+)
+
+type TableInfo struct {
+	Name        string           `json:"name"`
+	Kind        TableKind        `json:"kind"`
+	Comment     string           `json:"comment,omitempty"`
+	Columns     []ColumnInfo     `json:"columns"`
+	Indexes     []IndexInfo      `json:"indexes,omitempty"`
+	Constraints []ConstraintInfo `json:"constraints,omitempty"` // Primary key, unique, check and exclusion constraints
+	ForeignKeys []ForeignKeyInfo `json:"foreignKeys,omitempty"`
+}
+
+type ColumnInfo struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"` // As PostgreSQL formats it, e.g. "character varying(80)"
+	Nullable bool   `json:"nullable"`
+	Default  string `json:"default,omitempty"`  // Default expression
+	Identity string `json:"identity,omitempty"` // "always" or "by default" for identity columns
+	Position int    `json:"position"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type IndexInfo struct {
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"` // Indexed table columns; expressions are only in Definition
+	Unique     bool     `json:"unique"`
+	Primary    bool     `json:"primary"`
+	Method     string   `json:"method"` // e.g. "btree" or "gin"
+	Definition string   `json:"definition"`
+}
+
+type ConstraintKind string
+
+const (
+	ConstraintPrimaryKey ConstraintKind = "primaryKey"
+	ConstraintUnique     ConstraintKind = "unique"
+	ConstraintCheck      ConstraintKind = "check"
+	ConstraintExclusion  ConstraintKind = "exclusion"
+)
+
+type ConstraintInfo struct {
+	Name       string         `json:"name"`
+	Kind       ConstraintKind `json:"kind"`
+	Columns    []string       `json:"columns"`
+	Definition string         `json:"definition"`
+}
+
+type ForeignKeyInfo struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedSchema  string   `json:"referencedSchema"`
+	ReferencedTable   string   `json:"referencedTable"`
+	ReferencedColumns []string `json:"referencedColumns"`
+	OnUpdate          string   `json:"onUpdate"` // e.g. "NO ACTION" or "CASCADE"
+	OnDelete          string   `json:"onDelete"`
+}
+
+type FunctionKind string
+
+const (
+	FunctionKindFunction  FunctionKind = "function"
+	FunctionKindProcedure FunctionKind = "procedure"
+	FunctionKindAggregate FunctionKind = "aggregate"
+	FunctionKindWindow    FunctionKind = "window"
+)
+
+type FunctionInfo struct {
+	Name      string       `json:"name"`
+	Kind      FunctionKind `json:"kind"`
+	Arguments string       `json:"arguments"` // e.g. "user_id integer, since date"
+	Result    string       `json:"result"`    // e.g. "SETOF orders"; empty for procedures
+	Language  string       `json:"language"`
+	Comment   string       `json:"comment,omitempty"`
+}
+
+// SequenceInfo describes a sequence. Its values are decimal strings, since
+// they can go beyond what JavaScript numbers hold exactly.
+type SequenceInfo struct {
+	Name      string `json:"name"`
+	DataType  string `json:"dataType"`
+	Start     string `json:"start"`
+	Increment string `json:"increment"`
+	Min       string `json:"min"`
+	Max       string `json:"max"`
+	Cycle     bool   `json:"cycle"`
 }
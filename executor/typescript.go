@@ -8,15 +8,19 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/evanw/esbuild/pkg/api"
 	"rogchap.com/v8go"
 )
 
+const heapPollInterval = 10 * time.Millisecond
+
 type TypeScriptExecutor struct {
 	options ExecutorOptions
 	mu      sync.Mutex
@@ -29,11 +33,17 @@ func NewTypeScriptExecutor(opts ExecutorOptions) *TypeScriptExecutor {
 }
 
 func (js *TypeScriptExecutor) Execute(ctx context.Context, code string, input string) (*ExecutionResult, error) {
+	return js.ExecuteStream(ctx, code, input, nil)
+}
+
+// ExecuteStream runs the script and reports console output through onOutput
+// as it is produced. The returned result still holds the full output.
+func (js *TypeScriptExecutor) ExecuteStream(ctx context.Context, code string, input string, onOutput OutputFunc) (*ExecutionResult, error) {
 	start := time.Now()
 
 	if ctx == nil {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		ctx, cancel = context.WithTimeout(context.Background(), js.options.timeoutOr(5*time.Second))
 		defer cancel()
 	}
 
@@ -47,10 +57,11 @@ func (js *TypeScriptExecutor) Execute(ctx context.Context, code string, input st
 	transpileResult := api.Transform(code, api.TransformOptions{
 		Loader:       api.LoaderTS,
 		Format:       api.FormatDefault,
-		Sourcemap:    api.SourceMapNone,
+		Sourcemap:    api.SourceMapExternal,
 		Target:       api.ESNext,
 		MinifySyntax: false,
 	})
+	result.Diagnostics = esbuildDiagnostics(transpileResult.Warnings, SeverityWarning)
 	if len(transpileResult.Errors) > 0 {
 		tsErrors := make([]string, len(transpileResult.Errors))
 		for i, err := range transpileResult.Errors {
@@ -58,11 +69,13 @@ func (js *TypeScriptExecutor) Execute(ctx context.Context, code string, input st
 		}
 		result.Error = "TypeScript transpile error:\n" + strings.Join(tsErrors, "\n")
 		result.ExitCode = 2
+		result.Diagnostics = append(esbuildDiagnostics(transpileResult.Errors, SeverityError), result.Diagnostics...)
 		result.Duration = time.Since(start)
 		result.DurationString = formatDuration(result.Duration)
 		return result, nil
 	}
 	code = string(transpileResult.Code)
+	sourceMap := newJSSourceMap(transpileResult.Map)
 
 	iso := v8go.NewIsolate()
 	defer iso.Dispose()
@@ -71,9 +84,8 @@ func (js *TypeScriptExecutor) Execute(ctx context.Context, code string, input st
 	ctx_v8 := v8go.NewContext(iso, global)
 	defer ctx_v8.Close()
 
-	outputs := make([]string, 0, 10)
-	errors := make([]string, 0, 5)
-	if err := js.setupConsole(ctx_v8, &outputs, &errors); err != nil {
+	out := newConsoleOutput(js.options, onOutput)
+	if err := js.setupConsole(ctx_v8, out); err != nil {
 		result.Error = fmt.Sprintf("Failed to setup console: %v", err)
 		result.ExitCode = 1
 		return result, nil
@@ -94,30 +106,50 @@ func (js *TypeScriptExecutor) Execute(ctx context.Context, code string, input st
 		value, execErr = ctx_v8.RunScript(code, "user_code.js")
 	}()
 
+	var heapExceeded atomic.Bool
+	js.watchHeap(iso, done, &heapExceeded)
+
 	select {
 	case <-done:
-		if execErr != nil {
+		if heapExceeded.Load() {
+			result.Error = fmt.Sprintf("Memory limit exceeded: the script used more than %dMB of heap", js.options.MemoryMB)
+			result.ExitCode = ExitCodeMemoryLimitExceeded
+		} else if execErr != nil {
 			result.Error = execErr.Error()
 			result.ExitCode = 1
+			if diagnostic := js.runtimeDiagnostic(execErr, sourceMap); diagnostic != nil {
+				result.Diagnostics = append(result.Diagnostics, *diagnostic)
+			}
 		} else {
 			if value != nil && !value.IsUndefined() && !value.IsNull() {
-				outputs = append(outputs, value.String())
+				out.write(Stdout, value.String())
 			}
 		}
 
-		result.Output = strings.Join(outputs, "\n")
-		if len(errors) > 0 {
+		result.Output = strings.Join(out.outputs, "\n")
+		if len(out.errors) > 0 {
 			if result.Error != "" {
-				result.Error += "\n" + strings.Join(errors, "\n")
+				result.Error += "\n" + strings.Join(out.errors, "\n")
 			} else {
-				result.Error = strings.Join(errors, "\n")
+				result.Error = strings.Join(out.errors, "\n")
 			}
 		}
 
 	case <-ctx.Done():
-		result.Error = "Execution timed out"
-		result.ExitCode = 124
+		// Stop the script and wait for it to unwind before the isolate is disposed.
+		iso.TerminateExecution()
+		<-done
+
+		result.Output = strings.Join(out.outputs, "\n")
+		if wasCancelled(ctx) {
+			result.Error = "Execution cancelled"
+			result.ExitCode = ExitCodeCancelled
+		} else {
+			result.Error = "Execution timed out"
+			result.ExitCode = ExitCodeTimeout
+		}
 	}
+	result.Truncation = out.limiter.truncation()
 
 	duration := time.Since(start)
 	result.Duration = duration
@@ -125,7 +157,57 @@ func (js *TypeScriptExecutor) Execute(ctx context.Context, code string, input st
 	return result, nil
 }
 
-func (js *TypeScriptExecutor) setupConsole(ctx *v8go.Context, outputs *[]string, errors *[]string) error {
+// runtimeDiagnostic locates an uncaught exception in the user's TypeScript.
+func (js *TypeScriptExecutor) runtimeDiagnostic(err error, sourceMap *jsSourceMap) *Diagnostic {
+	var jsErr *v8go.JSError
+	if !errors.As(err, &jsErr) {
+		return nil
+	}
+
+	line, column, ok := parseJSLocation(jsErr.Location)
+	if !ok {
+		return nil
+	}
+	line, column = sourceMap.userPosition(line, column)
+
+	return &Diagnostic{
+		Severity: SeverityError,
+		Line:     line,
+		Column:   column,
+		Message:  jsErr.Message,
+		Source:   "v8",
+	}
+}
+
+// watchHeap terminates the script once the isolate's used heap grows past
+// ExecutorOptions.MemoryMB, setting exceeded. It stops when done is closed.
+func (js *TypeScriptExecutor) watchHeap(iso *v8go.Isolate, done <-chan struct{}, exceeded *atomic.Bool) {
+	if js.options.MemoryMB <= 0 {
+		return
+	}
+
+	limit := uint64(js.options.MemoryMB) * 1024 * 1024
+
+	go func() {
+		ticker := time.NewTicker(heapPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if iso.GetHeapStatistics().UsedHeapSize > limit {
+					exceeded.Store(true)
+					iso.TerminateExecution()
+					return
+				}
+			}
+		}
+	}()
+}
+
+func (js *TypeScriptExecutor) setupConsole(ctx *v8go.Context, out *consoleOutput) error {
 	console := v8go.NewObjectTemplate(ctx.Isolate())
 
 	logFn := v8go.NewFunctionTemplate(ctx.Isolate(), func(info *v8go.FunctionCallbackInfo) *v8go.Value {
@@ -133,7 +215,7 @@ func (js *TypeScriptExecutor) setupConsole(ctx *v8go.Context, outputs *[]string,
 		for i := 0; i < len(info.Args()); i++ {
 			args[i] = info.Args()[i].String()
 		}
-		*outputs = append(*outputs, strings.Join(args, " "))
+		out.write(Stdout, strings.Join(args, " "))
 		return v8go.Undefined(ctx.Isolate())
 	})
 	console.Set("log", logFn)
@@ -144,7 +226,7 @@ func (js *TypeScriptExecutor) setupConsole(ctx *v8go.Context, outputs *[]string,
 		for i := 0; i < len(info.Args()); i++ {
 			args[i] = info.Args()[i].String()
 		}
-		*errors = append(*errors, strings.Join(args, " "))
+		out.write(Stderr, strings.Join(args, " "))
 		return v8go.Undefined(ctx.Isolate())
 	})
 	console.Set("error", errorFn)
@@ -155,7 +237,7 @@ func (js *TypeScriptExecutor) setupConsole(ctx *v8go.Context, outputs *[]string,
 		for i := 0; i < len(info.Args()); i++ {
 			args[i] = info.Args()[i].String()
 		}
-		*outputs = append(*outputs, strings.Join(args, " "))
+		out.write(Stdout, strings.Join(args, " "))
 		return v8go.Undefined(ctx.Isolate())
 	})
 	console.Set("warn", warnFn)
@@ -170,6 +252,11 @@ func (js *TypeScriptExecutor) setupConsole(ctx *v8go.Context, outputs *[]string,
 	return global.Set("console", consoleObj)
 }
 
+// typeScriptEngineVersion reports the embedded V8 version.
+func typeScriptEngineVersion() string {
+	return "V8 " + v8go.Version()
+}
+
 func (js *TypeScriptExecutor) Language() Language { return TypeScript }
 func (js *TypeScriptExecutor) IsAvailable() bool {
 	return true
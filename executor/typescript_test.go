@@ -293,6 +293,122 @@ func TestJavaScriptExecutor_Execute(t *testing.T) {
 	})
 }
 
+func TestJavaScriptExecutor_ExecuteStream(t *testing.T) {
+	executor := NewTypeScriptExecutor(DefaultExecutorOptions())
+
+	var chunks []OutputChunk
+	code := `console.log("one"); console.error("two"); console.info("three");`
+	result, err := executor.ExecuteStream(context.Background(), code, "", func(stream OutputStream, data string) {
+		chunks = append(chunks, OutputChunk{Stream: stream, Data: data})
+	})
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []OutputChunk{
+		{Stream: Stdout, Data: "one\n"},
+		{Stream: Stderr, Data: "two\n"},
+		{Stream: Stdout, Data: "three\n"},
+	}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d: %v", len(expected), len(chunks), chunks)
+	}
+	for i := range expected {
+		if chunks[i] != expected[i] {
+			t.Errorf("Chunk %d: expected %+v, got %+v", i, expected[i], chunks[i])
+		}
+	}
+
+	if result.Output != "one\nthree" {
+		t.Fatalf("Expected full output in result, got %q", result.Output)
+	}
+}
+
+func TestJavaScriptExecutor_MemoryLimit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Heap limit is enforced through Node.js on Windows")
+	}
+
+	opts := DefaultExecutorOptions()
+	opts.MemoryMB = 32
+	executor := NewTypeScriptExecutor(opts)
+
+	code := `const items = []; while (true) { items.push(new Array(1000).fill("x")); }`
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := executor.Execute(ctx, code, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.ExitCode != ExitCodeMemoryLimitExceeded {
+		t.Fatalf("Expected exit code %d, got %d (%s)", ExitCodeMemoryLimitExceeded, result.ExitCode, result.Error)
+	}
+
+	if !strings.Contains(result.Error, "Memory limit exceeded") {
+		t.Fatalf("Expected memory limit error, got %q", result.Error)
+	}
+}
+
+func TestJavaScriptExecutor_MaxOutputs(t *testing.T) {
+	opts := DefaultExecutorOptions()
+	opts.MaxOutputs = 3
+	executor := NewTypeScriptExecutor(opts)
+
+	code := `for (let i = 0; i < 10; i++) { console.log("line " + i); }`
+	result, err := executor.Execute(context.Background(), code, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if result.Output != "line 0\nline 1\nline 2" {
+		t.Fatalf("Expected first three lines, got %q", result.Output)
+	}
+
+	if result.Truncation == nil || result.Truncation.DroppedLines != 7 {
+		t.Fatalf("Expected 7 dropped lines, got %+v", result.Truncation)
+	}
+}
+
+func TestJavaScriptExecutor_Diagnostics(t *testing.T) {
+	executor := NewTypeScriptExecutor(DefaultExecutorOptions())
+
+	t.Run("should locate transpile errors", func(t *testing.T) {
+		code := "const a: number = 1;\nconst b = ;"
+		result, err := executor.Execute(context.Background(), code, "")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(result.Diagnostics) == 0 {
+			t.Fatalf("Expected diagnostics, got none. Error: %s", result.Error)
+		}
+
+		diagnostic := result.Diagnostics[0]
+		if diagnostic.Severity != SeverityError || diagnostic.Source != "esbuild" || diagnostic.Line != 2 {
+			t.Fatalf("Unexpected diagnostic %+v", diagnostic)
+		}
+	})
+
+	t.Run("should map runtime errors to the TypeScript source", func(t *testing.T) {
+		code := "interface User {\n  name: string\n}\n\nconst user: User = { name: \"a\" };\n(user as any).missing.call();"
+		result, err := executor.Execute(context.Background(), code, "")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(result.Diagnostics) != 1 {
+			t.Fatalf("Expected 1 diagnostic, got %+v. Error: %s", result.Diagnostics, result.Error)
+		}
+
+		if result.Diagnostics[0].Line != 6 {
+			t.Fatalf("Expected error on line 6, got %+v", result.Diagnostics[0])
+		}
+	})
+}
+
 func TestJavaScriptExecutor_Language(t *testing.T) {
 	executor := NewTypeScriptExecutor(DefaultExecutorOptions())
 
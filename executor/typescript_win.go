@@ -7,6 +7,7 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -32,11 +33,17 @@ func NewTypeScriptExecutor(opts ExecutorOptions) *TypeScriptExecutor {
 }
 
 func (js *TypeScriptExecutor) Execute(ctx context.Context, code string, input string) (*ExecutionResult, error) {
+	return js.ExecuteStream(ctx, code, input, nil)
+}
+
+// ExecuteStream runs the script and reports console output through onOutput
+// as it is produced. The returned result still holds the full output.
+func (js *TypeScriptExecutor) ExecuteStream(ctx context.Context, code string, input string, onOutput OutputFunc) (*ExecutionResult, error) {
 	start := time.Now()
 
 	if ctx == nil {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		ctx, cancel = context.WithTimeout(context.Background(), js.options.timeoutOr(5*time.Second))
 		defer cancel()
 	}
 
@@ -50,10 +57,11 @@ func (js *TypeScriptExecutor) Execute(ctx context.Context, code string, input st
 	transpileResult := api.Transform(code, api.TransformOptions{
 		Loader:       api.LoaderTS,
 		Format:       api.FormatDefault,
-		Sourcemap:    api.SourceMapNone,
+		Sourcemap:    api.SourceMapExternal,
 		Target:       api.ESNext,
 		MinifySyntax: false,
 	})
+	result.Diagnostics = esbuildDiagnostics(transpileResult.Warnings, SeverityWarning)
 	if len(transpileResult.Errors) > 0 {
 		tsErrors := make([]string, len(transpileResult.Errors))
 		for i, err := range transpileResult.Errors {
@@ -61,20 +69,23 @@ func (js *TypeScriptExecutor) Execute(ctx context.Context, code string, input st
 		}
 		result.Error = "TypeScript transpile error:\n" + strings.Join(tsErrors, "\n")
 		result.ExitCode = 2
+		result.Diagnostics = append(esbuildDiagnostics(transpileResult.Errors, SeverityError), result.Diagnostics...)
 		result.Duration = time.Since(start)
 		result.DurationString = formatDuration(result.Duration)
 		return result, nil
 	}
 	code = string(transpileResult.Code)
+	sourceMap := newJSSourceMap(transpileResult.Map)
 
 	if js.isNodeAvailable() {
-		nodeResult := js.executeWithNode(ctx, code)
+		nodeResult := js.executeWithNode(ctx, code, onOutput)
+		nodeResult.Diagnostics = append(result.Diagnostics, nodeResult.Diagnostics...)
 		nodeResult.Duration = time.Since(start)
 		nodeResult.DurationString = formatDuration(nodeResult.Duration)
 		return nodeResult, nil
 	}
 
-	gojaResult := js.executeWithGoja(ctx, code)
+	gojaResult := js.executeWithGoja(ctx, code, sourceMap, onOutput)
 
 	if gojaResult.ExitCode != 0 && js.isGojaUnsupportedFeatureError(gojaResult.Error) {
 		result.Error = "Goja failed to execute the code."
@@ -84,21 +95,21 @@ func (js *TypeScriptExecutor) Execute(ctx context.Context, code string, input st
 		return result, nil
 	}
 
+	gojaResult.Diagnostics = append(result.Diagnostics, gojaResult.Diagnostics...)
 	gojaResult.Duration = time.Since(start)
 	gojaResult.DurationString = formatDuration(gojaResult.Duration)
 	return gojaResult, nil
 }
 
-func (js *TypeScriptExecutor) executeWithGoja(ctx context.Context, code string) *ExecutionResult {
+func (js *TypeScriptExecutor) executeWithGoja(ctx context.Context, code string, sourceMap *jsSourceMap, onOutput OutputFunc) *ExecutionResult {
 	result := &ExecutionResult{
 		Language: TypeScript,
 	}
 
 	vm := goja.New()
 
-	outputs := make([]string, 0, 10)
-	errors := make([]string, 0, 5)
-	if err := js.setupConsole(vm, &outputs, &errors); err != nil {
+	out := newConsoleOutput(js.options, onOutput)
+	if err := js.setupConsole(vm, out); err != nil {
 		result.Error = fmt.Sprintf("Failed to setup console: %v", err)
 		result.ExitCode = 1
 		return result
@@ -124,32 +135,72 @@ func (js *TypeScriptExecutor) executeWithGoja(ctx context.Context, code string)
 		if execErr != nil {
 			result.Error = execErr.Error()
 			result.ExitCode = 1
+			if diagnostic := js.runtimeDiagnostic(execErr, sourceMap); diagnostic != nil {
+				result.Diagnostics = append(result.Diagnostics, *diagnostic)
+			}
 		} else {
 			if value != nil {
 				if str := value.String(); str != "undefined" && str != "null" {
-					outputs = append(outputs, str)
+					out.write(Stdout, str)
 				}
 			}
 		}
 
-		result.Output = strings.Join(outputs, "\n")
-		if len(errors) > 0 {
+		result.Output = strings.Join(out.outputs, "\n")
+		if len(out.errors) > 0 {
 			if result.Error != "" {
-				result.Error += "\n" + strings.Join(errors, "\n")
+				result.Error += "\n" + strings.Join(out.errors, "\n")
 			} else {
-				result.Error = strings.Join(errors, "\n")
+				result.Error = strings.Join(out.errors, "\n")
 			}
 		}
 
 	case <-ctx.Done():
-		result.Error = "Execution timed out"
-		result.ExitCode = 124
+		vm.Interrupt(ctx.Err())
+		<-done
+
+		result.Output = strings.Join(out.outputs, "\n")
+		if wasCancelled(ctx) {
+			result.Error = "Execution cancelled"
+			result.ExitCode = ExitCodeCancelled
+		} else {
+			result.Error = "Execution timed out"
+			result.ExitCode = ExitCodeTimeout
+		}
 	}
+	result.Truncation = out.limiter.truncation()
 
 	return result
 }
 
-func (js *TypeScriptExecutor) executeWithNode(ctx context.Context, code string) *ExecutionResult {
+// runtimeDiagnostic locates an uncaught exception in the user's TypeScript.
+func (js *TypeScriptExecutor) runtimeDiagnostic(err error, sourceMap *jsSourceMap) *Diagnostic {
+	var exception *goja.Exception
+	if !errors.As(err, &exception) {
+		return nil
+	}
+
+	stack := exception.Stack()
+	if len(stack) == 0 {
+		return nil
+	}
+
+	position := stack[0].Position()
+	if position.Line == 0 {
+		return nil
+	}
+	line, column := sourceMap.userPosition(position.Line, position.Column)
+
+	return &Diagnostic{
+		Severity: SeverityError,
+		Line:     line,
+		Column:   column,
+		Message:  exception.Value().String(),
+		Source:   "goja",
+	}
+}
+
+func (js *TypeScriptExecutor) executeWithNode(ctx context.Context, code string, onOutput OutputFunc) *ExecutionResult {
 	result := &ExecutionResult{
 		Language: TypeScript,
 	}
@@ -171,24 +222,45 @@ func (js *TypeScriptExecutor) executeWithNode(ctx context.Context, code string)
 		os.Remove(tempFile.Name())
 	}()
 
-	cmd := exec.CommandContext(ctx, "node", tempFile.Name())
+	args := []string{tempFile.Name()}
+	if js.options.MemoryMB > 0 {
+		args = append([]string{fmt.Sprintf("--max-old-space-size=%d", js.options.MemoryMB)}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, "node", args...)
 
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		HideWindow: true,
 	}
+	killProcessTreeOnCancel(cmd)
 
-	output, err := cmd.CombinedOutput()
+	limiter := newOutputLimiter(js.options.MaxOutputs, js.options.MaxOutputBytes)
+	stdout, stderr := newOutputWriters(limiter, onOutput)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+	result.Truncation = limiter.truncation()
 
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
+		if wasCancelled(ctx) {
+			result.Error = "Execution cancelled"
+			result.ExitCode = ExitCodeCancelled
+		} else if ctx.Err() == context.DeadlineExceeded {
 			result.Error = "Execution timed out"
-			result.ExitCode = 124
+			result.ExitCode = ExitCodeTimeout
+		} else if js.options.MemoryMB > 0 && strings.Contains(stderr.String(), "heap out of memory") {
+			result.Output = stdout.String()
+			result.Error = fmt.Sprintf("Memory limit exceeded: the script used more than %dMB of heap", js.options.MemoryMB)
+			result.ExitCode = ExitCodeMemoryLimitExceeded
 		} else {
-			result.Error = string(output)
+			result.Output = stdout.String()
+			result.Error = stderr.String()
 			result.ExitCode = 1
 		}
 	} else {
-		result.Output = string(output)
+		result.Output = stdout.String()
+		result.Error = stderr.String()
 		result.ExitCode = 0
 	}
 
@@ -256,7 +328,7 @@ func isTestBuild() bool {
 		strings.Contains(os.Args[0], "_test")
 }
 
-func (js *TypeScriptExecutor) setupConsole(vm *goja.Runtime, outputs *[]string, errors *[]string) error {
+func (js *TypeScriptExecutor) setupConsole(vm *goja.Runtime, out *consoleOutput) error {
 	console := vm.NewObject()
 
 	logFn := vm.ToValue(func(call goja.FunctionCall) goja.Value {
@@ -264,7 +336,7 @@ func (js *TypeScriptExecutor) setupConsole(vm *goja.Runtime, outputs *[]string,
 		for i, arg := range call.Arguments {
 			args[i] = arg.String()
 		}
-		*outputs = append(*outputs, strings.Join(args, " "))
+		out.write(Stdout, strings.Join(args, " "))
 		return goja.Undefined()
 	})
 	console.Set("log", logFn)
@@ -275,7 +347,7 @@ func (js *TypeScriptExecutor) setupConsole(vm *goja.Runtime, outputs *[]string,
 		for i, arg := range call.Arguments {
 			args[i] = arg.String()
 		}
-		*errors = append(*errors, strings.Join(args, " "))
+		out.write(Stderr, strings.Join(args, " "))
 		return goja.Undefined()
 	})
 	console.Set("error", errorFn)
@@ -286,7 +358,7 @@ func (js *TypeScriptExecutor) setupConsole(vm *goja.Runtime, outputs *[]string,
 		for i, arg := range call.Arguments {
 			args[i] = arg.String()
 		}
-		*outputs = append(*outputs, strings.Join(args, " "))
+		out.write(Stdout, strings.Join(args, " "))
 		return goja.Undefined()
 	})
 	console.Set("warn", warnFn)
@@ -298,6 +370,16 @@ func (js *TypeScriptExecutor) setupConsole(vm *goja.Runtime, outputs *[]string,
 	return nil
 }
 
+// typeScriptEngineVersion reports the Node.js version used for scripts, or
+// the embedded engine when Node.js is not installed.
+func typeScriptEngineVersion() string {
+	output, err := ExecCommand([]string{"node", "--version"})
+	if err != nil {
+		return "goja"
+	}
+	return "Node.js " + strings.TrimSpace(output)
+}
+
 func (js *TypeScriptExecutor) Language() Language { return TypeScript }
 func (js *TypeScriptExecutor) IsAvailable() bool {
 	return true
@@ -1,10 +1,35 @@
 package executor
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// ErrMemoryLimitExceeded is returned when a process is killed for using more
+// memory than CommandOptions.MemoryMB allows.
+var ErrMemoryLimitExceeded = errors.New("memory limit exceeded")
+
+// CommandOptions tunes a process started by ExecCommandContextStream.
+type CommandOptions struct {
+	Env            []string // Extra "KEY=value" environment variables
+	MemoryMB       int      // Resident memory cap enforced where supported; 0 disables it
+	MaxOutputLines int      // Lines of stdout+stderr kept; 0 disables the cap
+	MaxOutputBytes int      // Bytes of stdout+stderr kept; 0 disables the cap
+}
+
+// CommandOutput is what a process started by ExecCommandContextStream wrote.
+type CommandOutput struct {
+	Stdout     string
+	Stderr     string
+	Truncation *OutputTruncation // nil when nothing was dropped
+}
+
 // formatDuration formats a duration with max 3 decimal places for cleaner display
 func formatDuration(d time.Duration) string {
 	if d < time.Microsecond {
@@ -18,3 +43,48 @@ func formatDuration(d time.Duration) string {
 	}
 	return fmt.Sprintf("%.3gs", d.Seconds())
 }
+
+// wasCancelled reports whether ctx was stopped by a user cancellation rather
+// than a timeout.
+func wasCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrExecutionCancelled)
+}
+
+// runCommand starts cmd, enforces the limits in opts while it runs and waits
+// for it to exit.
+func runCommand(cmd *exec.Cmd, input string, opts CommandOptions, onOutput OutputFunc) (CommandOutput, error) {
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
+
+	limiter := newOutputLimiter(opts.MaxOutputLines, opts.MaxOutputBytes)
+	stdout, stderr := newOutputWriters(limiter, onOutput)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return CommandOutput{}, err
+	}
+
+	var exceeded atomic.Bool
+	stopWatch := watchMemory(cmd.Process.Pid, opts.MemoryMB, func() {
+		exceeded.Store(true)
+		cmd.Cancel()
+	})
+	err := cmd.Wait()
+	stopWatch()
+
+	if exceeded.Load() {
+		err = ErrMemoryLimitExceeded
+	}
+
+	return CommandOutput{
+		Stdout:     stdout.String(),
+		Stderr:     stderr.String(),
+		Truncation: limiter.truncation(),
+	}, err
+}
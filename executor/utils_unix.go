@@ -5,25 +5,24 @@ package executor
 import (
 	"context"
 	"os/exec"
-	"strings"
+	"syscall"
+	"time"
 )
 
 func ExecCommandContext(ctx context.Context, command []string, input string, tempDir string) (string, string, error) {
+	output, err := ExecCommandContextStream(ctx, command, input, tempDir, CommandOptions{}, nil)
+	return output.Stdout, output.Stderr, err
+}
+
+// ExecCommandContextStream runs command like ExecCommandContext with the limits in
+// opts, and also passes stdout and stderr to onOutput as soon as the process writes them.
+func ExecCommandContextStream(ctx context.Context, command []string, input string, tempDir string, opts CommandOptions, onOutput OutputFunc) (CommandOutput, error) {
 	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	killProcessTreeOnCancel(cmd)
 
 	cmd.Dir = tempDir
 
-	if input != "" {
-		cmd.Stdin = strings.NewReader(input)
-	}
-
-	var stdout, stderr strings.Builder
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-
-	return stdout.String(), stderr.String(), err
+	return runCommand(cmd, input, opts, onOutput)
 }
 
 func ExecCommand(command []string) (string, error) {
@@ -33,3 +32,13 @@ func ExecCommand(command []string) (string, error) {
 
 	return string(output), err
 }
+
+// killProcessTreeOnCancel starts cmd in its own process group and kills the whole
+// group when the context is done, so binaries spawned by `go run` die with it.
+func killProcessTreeOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+}
@@ -5,30 +5,29 @@ package executor
 import (
 	"context"
 	"os/exec"
-	"strings"
+	"strconv"
 	"syscall"
+	"time"
 )
 
 func ExecCommandContext(ctx context.Context, command []string, input string, tempDir string) (string, string, error) {
+	output, err := ExecCommandContextStream(ctx, command, input, tempDir, CommandOptions{}, nil)
+	return output.Stdout, output.Stderr, err
+}
+
+// ExecCommandContextStream runs command like ExecCommandContext with the limits in
+// opts, and also passes stdout and stderr to onOutput as soon as the process writes them.
+func ExecCommandContextStream(ctx context.Context, command []string, input string, tempDir string, opts CommandOptions, onOutput OutputFunc) (CommandOutput, error) {
 	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
 
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		HideWindow: true,
 	}
+	killProcessTreeOnCancel(cmd)
 
 	cmd.Dir = tempDir
 
-	if input != "" {
-		cmd.Stdin = strings.NewReader(input)
-	}
-
-	var stdout, stderr strings.Builder
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-
-	return stdout.String(), stderr.String(), err
+	return runCommand(cmd, input, opts, onOutput)
 }
 
 func ExecCommand(command []string) (string, error) {
@@ -42,3 +41,16 @@ func ExecCommand(command []string) (string, error) {
 
 	return string(output), err
 }
+
+// killProcessTreeOnCancel kills cmd and every process it spawned when the
+// context is done, so binaries started by `go run` die with it.
+func killProcessTreeOnCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+		kill.SysProcAttr = &syscall.SysProcAttr{
+			HideWindow: true,
+		}
+		return kill.Run()
+	}
+	cmd.WaitDelay = time.Second
+}
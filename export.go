@@ -0,0 +1,146 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"codezone-wails/executor"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// SQLExportTarget says where a SQL export is written.
+type SQLExportTarget string
+
+const (
+	ExportToFile      SQLExportTarget = "file" // A file the user picks in a save dialog
+	ExportToClipboard SQLExportTarget = "clipboard"
+)
+
+// SQLExport describes a finished SQL export.
+type SQLExport struct {
+	Rows      int64  `json:"rows"`
+	Path      string `json:"path,omitempty"`      // The file written, for exports to a file
+	Cancelled bool   `json:"cancelled,omitempty"` // The save dialog was closed without picking a file, or the export was cancelled
+}
+
+// sqlExportTimeout bounds exports that run their query again, which read
+// every row of the result.
+const sqlExportTimeout = 10 * time.Minute
+
+// exportFileTypes are the file extensions and save dialog names of the
+// export formats.
+var exportFileTypes = map[executor.ExportFormat][2]string{
+	executor.ExportCSV:      {"csv", "CSV"},
+	executor.ExportTSV:      {"tsv", "Tab-separated values"},
+	executor.ExportJSON:     {"json", "JSON"},
+	executor.ExportNDJSON:   {"ndjson", "Newline-delimited JSON"},
+	executor.ExportMarkdown: {"md", "Markdown"},
+	executor.ExportInsert:   {"sql", "SQL"},
+}
+
+// ExportSQLResult exports the rows of a result as the frontend holds them.
+func (a *App) ExportSQLResult(result executor.SQLQueryResult, opts executor.ExportOptions, target SQLExportTarget) (SQLExport, error) {
+	return a.exportSQL(opts, target, func(w io.Writer) (int64, error) {
+		return executor.ExportResult(w, &result, opts)
+	})
+}
+
+// ExportSQLQuery runs a query again and exports all of its rows, including
+// those left out of its result by the row limit. CancelExecution with
+// exportID stops the export.
+func (a *App) ExportSQLQuery(statement string, opts executor.ExportOptions, target SQLExportTarget, exportID string) (SQLExport, error) {
+	pgExecutor, err := a.postgresExecutor()
+	if err != nil {
+		return SQLExport{}, err
+	}
+
+	ctx, done, err := a.execMgr.Track(exportID)
+	if err != nil {
+		return SQLExport{}, err
+	}
+	defer done()
+	ctx, cancel := context.WithTimeout(ctx, sqlExportTimeout)
+	defer cancel()
+	return a.exportSQL(opts, target, func(w io.Writer) (int64, error) {
+		return pgExecutor.ExportQuery(ctx, statement, w, opts)
+	})
+}
+
+// exportSQL writes an export to target. Files are written next to their
+// destination first, so that a failed export leaves an existing file as it
+// was.
+func (a *App) exportSQL(opts executor.ExportOptions, target SQLExportTarget, export func(io.Writer) (int64, error)) (SQLExport, error) {
+	fileType, ok := exportFileTypes[opts.Format]
+	if !ok {
+		return SQLExport{}, fmt.Errorf("unknown export format: %q", opts.Format)
+	}
+
+	switch target {
+	case ExportToClipboard:
+		var text strings.Builder
+		rows, err := export(&text)
+		if errors.Is(err, executor.ErrExecutionCancelled) {
+			return SQLExport{Cancelled: true}, nil
+		}
+		if err != nil {
+			return SQLExport{}, err
+		}
+		if err := runtime.ClipboardSetText(a.ctx, text.String()); err != nil {
+			return SQLExport{}, fmt.Errorf("failed to copy to the clipboard: %w", err)
+		}
+		log.Printf("SQL export: Copied %d rows as %s", rows, opts.Format)
+		return SQLExport{Rows: rows}, nil
+
+	case ExportToFile:
+		path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+			Title:           "Export Result",
+			DefaultFilename: "result." + fileType[0],
+			Filters:         []runtime.FileFilter{{DisplayName: fileType[1], Pattern: "*." + fileType[0]}},
+		})
+		if err != nil {
+			return SQLExport{}, fmt.Errorf("failed to open the save dialog: %w", err)
+		}
+		if path == "" {
+			return SQLExport{Cancelled: true}, nil
+		}
+
+		tmp, err := os.CreateTemp(filepath.Dir(path), ".export-*")
+		if err != nil {
+			return SQLExport{}, fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer os.Remove(tmp.Name())
+		// Temporary files are private; exports are not.
+		if err := tmp.Chmod(0o644); err != nil {
+			log.Printf("SQL export: Failed to set export file permissions: %v", err)
+		}
+
+		rows, err := export(tmp)
+		if closeErr := tmp.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to write export file: %w", closeErr)
+		}
+		if errors.Is(err, executor.ErrExecutionCancelled) {
+			log.Printf("SQL export: Cancelled after %d rows", rows)
+			return SQLExport{Cancelled: true}, nil
+		}
+		if err != nil {
+			return SQLExport{}, err
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return SQLExport{}, fmt.Errorf("failed to write export file: %w", err)
+		}
+		log.Printf("SQL export: Wrote %d rows as %s to %s", rows, opts.Format, path)
+		return SQLExport{Rows: rows, Path: path}, nil
+	}
+	return SQLExport{}, fmt.Errorf("unknown export target: %q", target)
+}
@@ -522,7 +522,15 @@ const Editor: Component<EditorProps> = props => {
         timeout: 0 // Use default timeout
       })
 
-      const result = await ExecuteCode(config)
+      let result = await ExecuteCode(config)
+
+      // Safe mode holds back destructive statements until they are confirmed.
+      if (result.exitCode === 154 && result.guarded?.length) {
+        const statements = result.guarded.map(g => `${g.text}\n  ${g.reason}`).join('\n')
+        if (window.confirm(`Safe mode: run these statements?\n\n${statements}`)) {
+          result = await ExecuteCode(new executor.ExecutionConfig({ ...config, confirmed: true }))
+        }
+      }
 
       if (result.sqlResult) {
         if (result.sqlResult?.columns && result.sqlResult?.rows) {
@@ -1,6 +1,7 @@
 import { Language, PostgresConnectionStatus } from '~/types'
 import { Show } from 'solid-js'
 import type { executor } from 'wailsjs/go/models'
+import SQLExport from './ui/SQLExport'
 import SQLTable from './ui/SQLTable'
 
 type OutputProps = {
@@ -15,7 +16,7 @@ const Output = (props: OutputProps) => {
     return (
       props.language === 'postgres' &&
       props.executionResult?.sqlResult &&
-      props.executionResult.sqlResult.queryType === 'SELECT' &&
+      props.executionResult.sqlResult.returnsRows &&
       props.executionResult.sqlResult.rows &&
       props.executionResult.sqlResult.rows.length > 0
     )
@@ -27,6 +28,7 @@ const Output = (props: OutputProps) => {
     return props.executionResult.sqlResult.rows.map(row =>
       row.map(cell => {
         if (cell === null || cell === undefined) return '--'
+        if (typeof cell === 'object') return JSON.stringify(cell)
         return String(cell)
       })
     )
@@ -75,6 +77,9 @@ const Output = (props: OutputProps) => {
           >
             <span class="italic">Running...</span>
           </Show>
+          <Show when={!props.isExecuting && shouldShowTable()}>
+            <SQLExport result={props.executionResult!.sqlResult!} />
+          </Show>
         </div>
 
         <Show when={props.language === 'postgres'}>
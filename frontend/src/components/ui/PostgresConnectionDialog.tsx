@@ -18,6 +18,8 @@ type FormData = {
   username: string
   password: string
   sslMode: string
+  readOnly: boolean
+  safeMode: boolean
 }
 
 const PostgresConnectionDialog: Component<PostgresConnectionDialogProps> = props => {
@@ -27,7 +29,9 @@ const PostgresConnectionDialog: Component<PostgresConnectionDialogProps> = props
     database: '',
     username: '',
     password: '',
-    sslMode: 'disable'
+    sslMode: 'disable',
+    readOnly: false,
+    safeMode: false
   })
 
   const [isConnecting, setIsConnecting] = createSignal(false)
@@ -90,7 +94,7 @@ const PostgresConnectionDialog: Component<PostgresConnectionDialogProps> = props
     }
   }
 
-  const updateField = (field: string, value: string | number) => {
+  const updateField = (field: string, value: string | number | boolean) => {
     setFormData((prev: FormData) => ({ ...prev, [field]: value }))
   }
 
@@ -173,6 +177,24 @@ const PostgresConnectionDialog: Component<PostgresConnectionDialogProps> = props
               <option value="require">Require</option>
             </select>
           </div>
+
+          <label class="flex items-center gap-2 text-sm">
+            <input
+              type="checkbox"
+              checked={formData().readOnly}
+              onChange={e => updateField('readOnly', e.currentTarget.checked)}
+            />
+            Read-only: refuse statements that can write
+          </label>
+
+          <label class="flex items-center gap-2 text-sm">
+            <input
+              type="checkbox"
+              checked={formData().safeMode}
+              onChange={e => updateField('safeMode', e.currentTarget.checked)}
+            />
+            Safe mode: confirm DROP, TRUNCATE, DO blocks, and UPDATE or DELETE without WHERE
+          </label>
         </div>
 
         <DialogFooter class="gap-2">
@@ -1,5 +1,5 @@
-import { Component, createSignal, Show } from 'solid-js'
-import { DisconnectPostgreSQL } from 'wailsjs/go/main/App'
+import { Component, createEffect, createSignal, Show } from 'solid-js'
+import { DisconnectPostgreSQL, GetSQLSessionStatus } from 'wailsjs/go/main/App'
 import { Dialog, DialogContent, DialogFooter, DialogHeader, DialogTitle } from './dialog'
 import { showErrorToast } from './ErrorToast'
 
@@ -11,12 +11,27 @@ interface PostgresDisconnectDialogProps {
 
 const PostgresDisconnectDialog: Component<PostgresDisconnectDialogProps> = props => {
   const [isDisconnecting, setIsDisconnecting] = createSignal(false)
+  const [hasOpenTransaction, setHasOpenTransaction] = createSignal(false)
+
+  createEffect(() => {
+    if (!props.open) return
+    setHasOpenTransaction(false)
+    GetSQLSessionStatus()
+      .then(status => setHasOpenTransaction(status.connected && status.transaction !== 'idle'))
+      .catch(() => setHasOpenTransaction(false))
+  })
 
   const handleDisconnect = async () => {
     setIsDisconnecting(true)
 
     try {
-      await DisconnectPostgreSQL()
+      // The transaction is only rolled back once the dialog has warned about it.
+      const disconnected = await DisconnectPostgreSQL(hasOpenTransaction())
+      if (!disconnected) {
+        // A transaction was opened after the dialog checked; ask again.
+        setHasOpenTransaction(true)
+        return
+      }
 
       // Update connection status
       props.onDisconnect(true)
@@ -44,6 +59,11 @@ const PostgresDisconnectDialog: Component<PostgresDisconnectDialogProps> = props
           <p class="text-sm text-muted-foreground">
             Are you sure you want to disconnect from the PostgreSQL database?
           </p>
+          <Show when={hasOpenTransaction()}>
+            <p class="mt-2 text-sm text-destructive">
+              The session has an open transaction. Disconnecting will roll it back.
+            </p>
+          </Show>
         </div>
 
         <DialogFooter class="gap-2">
@@ -0,0 +1,105 @@
+import { Component, createSignal, Show } from 'solid-js'
+import { CancelExecution, ExportSQLQuery, ExportSQLResult } from 'wailsjs/go/main/App'
+import { executor } from 'wailsjs/go/models'
+import { showErrorToast } from './ErrorToast'
+
+type SQLExportProps = {
+  result: executor.SQLQueryResult
+}
+
+const formats: { value: string; label: string }[] = [
+  { value: 'csv', label: 'CSV' },
+  { value: 'tsv', label: 'TSV (Excel)' },
+  { value: 'json', label: 'JSON' },
+  { value: 'ndjson', label: 'NDJSON' },
+  { value: 'markdown', label: 'Markdown' },
+  { value: 'insert', label: 'INSERT' }
+]
+
+const SQLExport: Component<SQLExportProps> = props => {
+  const [format, setFormat] = createSignal('csv')
+  const [isExporting, setIsExporting] = createSignal(false)
+  // The ID of a running export of all rows, which can be cancelled.
+  const [exportID, setExportID] = createSignal<string>()
+
+  // Rows left out by the row limit are only exported by running the query again.
+  const isPartial = () => props.result.hasMore || props.result.truncated
+
+  const handleExport = async (target: 'file' | 'clipboard', allRows: boolean) => {
+    setIsExporting(true)
+    try {
+      const opts = new executor.ExportOptions({ format: format() })
+      if (allRows && props.result.statement) {
+        const id = crypto.randomUUID()
+        setExportID(id)
+        await ExportSQLQuery(props.result.statement, opts, target, id)
+      } else {
+        await ExportSQLResult(props.result, opts, target)
+      }
+    } catch (err) {
+      showErrorToast({
+        title: 'Export Failed',
+        description: err instanceof Error ? err.message : String(err),
+        duration: 5000
+      })
+    } finally {
+      setExportID(undefined)
+      setIsExporting(false)
+    }
+  }
+
+  const handleCancel = () => {
+    const id = exportID()
+    if (id) {
+      void CancelExecution(id)
+    }
+  }
+
+  return (
+    <div class="flex items-center gap-2">
+      <select
+        value={format()}
+        onChange={e => setFormat(e.currentTarget.value)}
+        class="h-7 rounded-md border border-input bg-background px-2 text-xs"
+      >
+        {formats.map(f => (
+          <option value={f.value}>{f.label}</option>
+        ))}
+      </select>
+      <button
+        onClick={() => void handleExport('clipboard', false)}
+        disabled={isExporting()}
+        class="px-2 py-1 text-xs bg-secondary text-secondary-foreground hover:bg-secondary/80 rounded-md disabled:opacity-50"
+      >
+        Copy
+      </button>
+      <button
+        onClick={() => void handleExport('file', false)}
+        disabled={isExporting()}
+        class="px-2 py-1 text-xs bg-secondary text-secondary-foreground hover:bg-secondary/80 rounded-md disabled:opacity-50"
+      >
+        Save...
+      </button>
+      <Show when={isPartial()}>
+        <button
+          onClick={() => void handleExport('file', true)}
+          disabled={isExporting()}
+          title="Run the query again and save every row"
+          class="px-2 py-1 text-xs bg-secondary text-secondary-foreground hover:bg-secondary/80 rounded-md disabled:opacity-50"
+        >
+          Save all rows...
+        </button>
+      </Show>
+      <Show when={exportID()}>
+        <button
+          onClick={handleCancel}
+          class="px-2 py-1 text-xs bg-destructive text-destructive-foreground hover:bg-destructive/90 rounded-md"
+        >
+          Cancel
+        </button>
+      </Show>
+    </div>
+  )
+}
+
+export default SQLExport
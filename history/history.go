@@ -0,0 +1,466 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+// Package history records past executions in an append-only file in the user
+// config directory.
+//
+// Each line of the file is a JSON record that either stores an entry or deletes
+// one. Records are replayed when the store is opened, and the file is rewritten
+// with only the live entries once superseded records start to pile up.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"codezone-wails/executor"
+
+	"github.com/google/uuid"
+)
+
+// DefaultLimit is the number of unpinned entries kept when no limit is set.
+const DefaultLimit = 500
+
+// maxRecordBytes bounds a single record, which holds at most one capped output.
+const maxRecordBytes = 64 << 20
+
+// ErrNotFound is returned for IDs that are not in the store.
+var ErrNotFound = errors.New("history entry not found")
+
+// Entry is a single recorded execution.
+type Entry struct {
+	ID        string                    `json:"id"`
+	CreatedAt time.Time                 `json:"createdAt"`
+	Language  executor.Language         `json:"language"`
+	Duration  time.Duration             `json:"duration"`
+	ExitCode  int                       `json:"exitCode"`
+	Pinned    bool                      `json:"pinned"`
+	Config    executor.ExecutionConfig  `json:"config"`
+	Result    *executor.ExecutionResult `json:"result,omitempty"`
+}
+
+// Query filters entries. Zero values match everything.
+type Query struct {
+	Text       string            `json:"text"` // Case-insensitive match against code and output
+	Language   executor.Language `json:"language"`
+	PinnedOnly bool              `json:"pinnedOnly"`
+	FailedOnly bool              `json:"failedOnly"`
+	Offset     int               `json:"offset"`
+	Limit      int               `json:"limit"`
+}
+
+type record struct {
+	Entry   *Entry `json:"entry,omitempty"`
+	Deleted string `json:"deleted,omitempty"`
+}
+
+// Store is a persistent, size-limited execution history. It is safe for
+// concurrent use.
+type Store struct {
+	path  string
+	limit int
+
+	mu      sync.Mutex
+	file    *os.File
+	entries []Entry // Oldest first
+	records int     // Records in the file, live or superseded
+}
+
+// DefaultPath returns the history file location in the user config directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+	return filepath.Join(dir, "codezone", "history.jsonl"), nil
+}
+
+// Open loads the history at path, creating it if needed. limit is the number of
+// unpinned entries to keep; zero or less uses DefaultLimit.
+func Open(path string, limit int) (*Store, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	s := &Store{path: path, limit: limit}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	// Evicted entries have no delete records, so apply the limit again and
+	// rewrite the file compactly, which also drops any torn tail.
+	s.trim()
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	byID := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordBytes)
+	for scanner.Scan() {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// A crash mid-write leaves a partial last line; skip it.
+			continue
+		}
+
+		switch {
+		case rec.Entry != nil:
+			if i, ok := byID[rec.Entry.ID]; ok {
+				s.entries[i] = *rec.Entry
+			} else {
+				byID[rec.Entry.ID] = len(s.entries)
+				s.entries = append(s.entries, *rec.Entry)
+			}
+		case rec.Deleted != "":
+			if i, ok := byID[rec.Deleted]; ok {
+				s.entries[i].ID = ""
+				delete(byID, rec.Deleted)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+
+	live := s.entries[:0]
+	for _, entry := range s.entries {
+		if entry.ID != "" {
+			live = append(live, entry)
+		}
+	}
+	s.entries = live
+	sort.SliceStable(s.entries, func(i, j int) bool {
+		return s.entries[i].CreatedAt.Before(s.entries[j].CreatedAt)
+	})
+	return nil
+}
+
+// Close releases the history file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Limit returns the number of unpinned entries kept.
+func (s *Store) Limit() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limit
+}
+
+// SetLimit changes the retention limit, dropping the oldest unpinned entries
+// that no longer fit.
+func (s *Store) SetLimit(limit int) error {
+	if limit <= 0 {
+		return fmt.Errorf("history limit must be positive, got %d", limit)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limit = limit
+	return s.enforceLimit()
+}
+
+// Add records an execution and returns the new entry. Passwords and key
+// passphrases in the PostgreSQL connection settings are not stored.
+func (s *Store) Add(config executor.ExecutionConfig, result *executor.ExecutionResult) (Entry, error) {
+	if config.PostgreSQLConn != nil {
+		conn := *config.PostgreSQLConn
+		conn.Password = ""
+		conn.SSLPassword = ""
+		conn.ConnectionString = executor.ConnStringWithoutSecrets(conn.ConnectionString)
+		if conn.SSHTunnel != nil {
+			tunnel := *conn.SSHTunnel
+			tunnel.KeyPassphrase = ""
+			conn.SSHTunnel = &tunnel
+		}
+		config.PostgreSQLConn = &conn
+	}
+
+	entry := Entry{
+		ID:        uuid.NewString(),
+		CreatedAt: time.Now(),
+		Language:  config.Language,
+		Config:    config,
+		Result:    result,
+	}
+	if result != nil {
+		entry.Duration = result.Duration
+		entry.ExitCode = result.ExitCode
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.append(record{Entry: &entry}); err != nil {
+		return Entry{}, err
+	}
+	s.entries = append(s.entries, entry)
+
+	if err := s.enforceLimit(); err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// Get returns the entry with the given ID.
+func (s *Store) Get(id string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return Entry{}, ErrNotFound
+	}
+	return s.entries[i], nil
+}
+
+// List returns entries newest first.
+func (s *Store) List(offset, limit int) []Entry {
+	return s.Search(Query{Offset: offset, Limit: limit})
+}
+
+// Search returns entries matching q, newest first.
+func (s *Store) Search(q Query) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	text := strings.ToLower(q.Text)
+	matches := []Entry{}
+	skipped := 0
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		entry := s.entries[i]
+		if !entry.matches(q, text) {
+			continue
+		}
+		if skipped < q.Offset {
+			skipped++
+			continue
+		}
+		matches = append(matches, entry)
+		if q.Limit > 0 && len(matches) == q.Limit {
+			break
+		}
+	}
+	return matches
+}
+
+func (e Entry) matches(q Query, text string) bool {
+	if q.Language != "" && e.Language != q.Language {
+		return false
+	}
+	if q.PinnedOnly && !e.Pinned {
+		return false
+	}
+	if q.FailedOnly && e.ExitCode == 0 {
+		return false
+	}
+	if text == "" {
+		return true
+	}
+	if strings.Contains(strings.ToLower(e.Config.Code), text) {
+		return true
+	}
+	return e.Result != nil && strings.Contains(strings.ToLower(e.Result.Output), text)
+}
+
+// SetPinned pins or unpins an entry. Pinned entries are never removed by the
+// retention limit or Prune.
+func (s *Store) SetPinned(id string, pinned bool) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return Entry{}, ErrNotFound
+	}
+
+	entry := s.entries[i]
+	entry.Pinned = pinned
+	if err := s.append(record{Entry: &entry}); err != nil {
+		return Entry{}, err
+	}
+	s.entries[i] = entry
+
+	if !pinned {
+		return entry, s.enforceLimit()
+	}
+	return entry, nil
+}
+
+// Delete removes an entry, pinned or not.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if err := s.append(record{Deleted: id}); err != nil {
+		return err
+	}
+	s.entries = append(s.entries[:i], s.entries[i+1:]...)
+	return nil
+}
+
+// Prune removes unpinned entries created before cutoff and returns how many
+// were removed. A zero cutoff removes every unpinned entry.
+func (s *Store) Prune(cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := make([]Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		if entry.Pinned || (!cutoff.IsZero() && !entry.CreatedAt.Before(cutoff)) {
+			kept = append(kept, entry)
+		}
+	}
+
+	removed := len(s.entries) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	s.entries = kept
+	return removed, s.compact()
+}
+
+func (s *Store) index(id string) int {
+	for i := range s.entries {
+		if s.entries[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// enforceLimit drops the oldest unpinned entries beyond the limit.
+func (s *Store) enforceLimit() error {
+	s.trim()
+
+	// Superseded records are only reclaimed by compaction, so do it once the
+	// file holds noticeably more records than there are live entries.
+	if s.records > 2*len(s.entries)+64 {
+		return s.compact()
+	}
+	return nil
+}
+
+// trim removes the oldest unpinned entries beyond the limit from memory.
+func (s *Store) trim() {
+	unpinned := 0
+	for _, entry := range s.entries {
+		if !entry.Pinned {
+			unpinned++
+		}
+	}
+
+	excess := unpinned - s.limit
+	if excess <= 0 {
+		return
+	}
+
+	kept := make([]Entry, 0, len(s.entries)-excess)
+	for _, entry := range s.entries {
+		if !entry.Pinned && excess > 0 {
+			excess--
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	s.entries = kept
+}
+
+func (s *Store) append(rec record) error {
+	if s.records > 2*len(s.entries)+64 {
+		if err := s.compact(); err != nil {
+			return err
+		}
+	}
+
+	if s.file == nil {
+		f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open history: %w", err)
+		}
+		s.file = f
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %w", err)
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	s.records++
+	return nil
+}
+
+// compact rewrites the file with only the live entries.
+func (s *Store) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".history-*.jsonl")
+	if err != nil {
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for i := range s.entries {
+		if err := encoder.Encode(record{Entry: &s.entries[i]}); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to compact history: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	s.records = len(s.entries)
+	return nil
+}
@@ -0,0 +1,259 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"codezone-wails/executor"
+)
+
+func openTestStore(t *testing.T, limit int) (*Store, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store, err := Open(path, limit)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, path
+}
+
+func addRun(t *testing.T, store *Store, code, output string, exitCode int) Entry {
+	t.Helper()
+
+	entry, err := store.Add(
+		executor.ExecutionConfig{Code: code, Language: executor.TypeScript},
+		&executor.ExecutionResult{Output: output, ExitCode: exitCode, Duration: time.Millisecond},
+	)
+	if err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+	return entry
+}
+
+func TestStore_AddAndList(t *testing.T) {
+	t.Run("should list entries newest first", func(t *testing.T) {
+		store, _ := openTestStore(t, 0)
+		addRun(t, store, "console.log(1)", "1", 0)
+		addRun(t, store, "console.log(2)", "2", 0)
+
+		entries := store.List(0, 0)
+		if len(entries) != 2 {
+			t.Fatalf("Expected 2 entries, got %d", len(entries))
+		}
+		if entries[0].Config.Code != "console.log(2)" {
+			t.Fatalf("Expected newest entry first, got '%s'", entries[0].Config.Code)
+		}
+	})
+
+	t.Run("should not store PostgreSQL passwords", func(t *testing.T) {
+		store, _ := openTestStore(t, 0)
+		conn := &executor.PostgreSQLConfig{Host: "localhost", Password: "secret"}
+
+		entry, err := store.Add(executor.ExecutionConfig{Language: executor.PostgreSQL, PostgreSQLConn: conn}, nil)
+		if err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+		if entry.Config.PostgreSQLConn.Password != "" {
+			t.Fatal("Expected password to be removed")
+		}
+		if conn.Password != "secret" {
+			t.Fatal("Expected the caller's config to be left untouched")
+		}
+	})
+
+	t.Run("should not store client key passphrases", func(t *testing.T) {
+		store, path := openTestStore(t, 0)
+		conn := &executor.PostgreSQLConfig{Host: "localhost", SSLKey: "client.key", SSLPassword: "key-secret"}
+
+		entry, err := store.Add(executor.ExecutionConfig{Language: executor.PostgreSQL, PostgreSQLConn: conn}, nil)
+		if err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+		if entry.Config.PostgreSQLConn.SSLPassword != "" || conn.SSLPassword != "key-secret" {
+			t.Fatal("Expected the passphrase to be removed from the entry only")
+		}
+		if data, _ := os.ReadFile(path); strings.Contains(string(data), "key-secret") {
+			t.Fatal("Expected the passphrase not to be written to the history file")
+		}
+	})
+
+	t.Run("should not store passwords in connection strings", func(t *testing.T) {
+		store, path := openTestStore(t, 0)
+		conn := &executor.PostgreSQLConfig{ConnectionString: "postgres://app:uri-secret@db/orders"}
+
+		entry, err := store.Add(executor.ExecutionConfig{Language: executor.PostgreSQL, PostgreSQLConn: conn}, nil)
+		if err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+		if stored := entry.Config.PostgreSQLConn.ConnectionString; stored != "postgres://app@db/orders" {
+			t.Fatalf("Expected the connection string without its password, got %q", stored)
+		}
+		if data, _ := os.ReadFile(path); strings.Contains(string(data), "uri-secret") {
+			t.Fatal("Expected the password not to be written to the history file")
+		}
+	})
+
+	t.Run("should not store SSH key passphrases", func(t *testing.T) {
+		store, path := openTestStore(t, 0)
+		tunnel := &executor.SSHTunnelConfig{Host: "bastion", User: "deploy", KeyFile: "id_ed25519", KeyPassphrase: "ssh-secret"}
+		conn := &executor.PostgreSQLConfig{Host: "localhost", SSHTunnel: tunnel}
+
+		entry, err := store.Add(executor.ExecutionConfig{Language: executor.PostgreSQL, PostgreSQLConn: conn}, nil)
+		if err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+		if stored := entry.Config.PostgreSQLConn.SSHTunnel; stored.KeyPassphrase != "" || stored.Host != "bastion" {
+			t.Fatalf("Expected the tunnel without its passphrase, got %+v", stored)
+		}
+		if tunnel.KeyPassphrase != "ssh-secret" {
+			t.Fatal("Expected the caller's tunnel config to be left untouched")
+		}
+		if data, _ := os.ReadFile(path); strings.Contains(string(data), "ssh-secret") {
+			t.Fatal("Expected the passphrase not to be written to the history file")
+		}
+	})
+
+	t.Run("should page through entries", func(t *testing.T) {
+		store, _ := openTestStore(t, 0)
+		for i := 0; i < 5; i++ {
+			addRun(t, store, "code", "", 0)
+		}
+
+		if got := len(store.List(3, 10)); got != 2 {
+			t.Fatalf("Expected 2 entries after offset 3, got %d", got)
+		}
+		if got := len(store.List(0, 2)); got != 2 {
+			t.Fatalf("Expected 2 entries with limit 2, got %d", got)
+		}
+	})
+}
+
+func TestStore_Search(t *testing.T) {
+	store, _ := openTestStore(t, 0)
+	addRun(t, store, "console.log('hello')", "hello", 0)
+	addRun(t, store, "throw new Error('boom')", "", 1)
+	addRun(t, store, "console.log('other')", "HELLO world", 0)
+
+	t.Run("should match code and output case-insensitively", func(t *testing.T) {
+		entries := store.Search(Query{Text: "Hello"})
+		if len(entries) != 2 {
+			t.Fatalf("Expected 2 matches, got %d", len(entries))
+		}
+	})
+
+	t.Run("should filter failed runs", func(t *testing.T) {
+		entries := store.Search(Query{FailedOnly: true})
+		if len(entries) != 1 || entries[0].ExitCode != 1 {
+			t.Fatalf("Expected the failed run only, got %+v", entries)
+		}
+	})
+
+	t.Run("should filter by language", func(t *testing.T) {
+		if got := len(store.Search(Query{Language: executor.Go})); got != 0 {
+			t.Fatalf("Expected no Go entries, got %d", got)
+		}
+	})
+}
+
+func TestStore_Retention(t *testing.T) {
+	t.Run("should drop the oldest unpinned entries beyond the limit", func(t *testing.T) {
+		store, _ := openTestStore(t, 2)
+		first := addRun(t, store, "first", "", 0)
+		if _, err := store.SetPinned(first.ID, true); err != nil {
+			t.Fatalf("Failed to pin entry: %v", err)
+		}
+		second := addRun(t, store, "second", "", 0)
+		addRun(t, store, "third", "", 0)
+		addRun(t, store, "fourth", "", 0)
+
+		if _, err := store.Get(second.ID); err != ErrNotFound {
+			t.Fatalf("Expected the oldest unpinned entry to be dropped, got %v", err)
+		}
+		if _, err := store.Get(first.ID); err != nil {
+			t.Fatalf("Expected the pinned entry to be kept, got %v", err)
+		}
+		if got := len(store.List(0, 0)); got != 3 {
+			t.Fatalf("Expected 3 entries, got %d", got)
+		}
+	})
+
+	t.Run("should prune unpinned entries older than the cutoff", func(t *testing.T) {
+		store, _ := openTestStore(t, 0)
+		pinned := addRun(t, store, "pinned", "", 0)
+		store.SetPinned(pinned.ID, true)
+		addRun(t, store, "old", "", 0)
+
+		removed, err := store.Prune(time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatalf("Failed to prune: %v", err)
+		}
+		if removed != 1 {
+			t.Fatalf("Expected 1 entry removed, got %d", removed)
+		}
+		if got := len(store.List(0, 0)); got != 1 {
+			t.Fatalf("Expected only the pinned entry, got %d entries", got)
+		}
+	})
+
+	t.Run("should reject a non-positive limit", func(t *testing.T) {
+		store, _ := openTestStore(t, 0)
+		if err := store.SetLimit(0); err == nil {
+			t.Fatal("Expected an error for limit 0")
+		}
+	})
+}
+
+func TestStore_Persistence(t *testing.T) {
+	t.Run("should reload entries, pins and deletions", func(t *testing.T) {
+		store, path := openTestStore(t, 0)
+		kept := addRun(t, store, "kept", "out", 0)
+		deleted := addRun(t, store, "deleted", "", 0)
+		store.SetPinned(kept.ID, true)
+		if err := store.Delete(deleted.ID); err != nil {
+			t.Fatalf("Failed to delete: %v", err)
+		}
+		store.Close()
+
+		reopened, err := Open(path, 0)
+		if err != nil {
+			t.Fatalf("Failed to reopen store: %v", err)
+		}
+		defer reopened.Close()
+
+		entries := reopened.List(0, 0)
+		if len(entries) != 1 {
+			t.Fatalf("Expected 1 entry after reload, got %d", len(entries))
+		}
+		if !entries[0].Pinned || entries[0].Result.Output != "out" {
+			t.Fatalf("Expected the pinned entry with its output, got %+v", entries[0])
+		}
+	})
+
+	t.Run("should skip a torn last record", func(t *testing.T) {
+		store, path := openTestStore(t, 0)
+		addRun(t, store, "complete", "", 0)
+		store.Close()
+
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatalf("Failed to open history file: %v", err)
+		}
+		f.WriteString(`{"entry":{"id":"torn","con`)
+		f.Close()
+
+		reopened, err := Open(path, 0)
+		if err != nil {
+			t.Fatalf("Failed to reopen store: %v", err)
+		}
+		defer reopened.Close()
+
+		if got := len(reopened.List(0, 0)); got != 1 {
+			t.Fatalf("Expected 1 entry, got %d", got)
+		}
+	})
+}
@@ -5,6 +5,7 @@ package main
 
 import (
 	"embed"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -20,6 +21,11 @@ var assets embed.FS
 var icon []byte
 
 func main() {
+	// Headless commands such as `codezone run main.go` skip the GUI
+	if code, handled := runCLI(os.Args[1:]); handled {
+		os.Exit(code)
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 
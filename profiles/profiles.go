@@ -0,0 +1,385 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+// Package profiles stores saved PostgreSQL connections. Profiles are kept in a
+// JSON file in the user config directory; their passwords and key passphrases
+// never touch that file and live in a secrets.Store instead.
+package profiles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"codezone-wails/executor"
+	"codezone-wails/secrets"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned for profile IDs that do not exist.
+var ErrNotFound = errors.New("connection profile not found")
+
+// Profile is a saved PostgreSQL connection without its secrets.
+type Profile struct {
+	ID                  string     `json:"id"`
+	Name                string     `json:"name"`
+	Host                string     `json:"host"`
+	Port                int        `json:"port"`
+	Database            string     `json:"database"`
+	Username            string     `json:"username"`
+	SSLMode             string     `json:"sslMode"`
+	Service             string     `json:"service,omitempty"`
+	SSLRootCert         string     `json:"sslRootCert,omitempty"`
+	SSLCert             string     `json:"sslCert,omitempty"`
+	SSLKey              string     `json:"sslKey,omitempty"`
+	SSLServerName       string     `json:"sslServerName,omitempty"`
+	SSHTunnel           *SSHTunnel `json:"sshTunnel,omitempty"` // Connect through an SSH jump host
+	Color               string     `json:"color,omitempty"`
+	Tag                 string     `json:"tag,omitempty"`
+	ReadOnly            bool       `json:"readOnly,omitempty"` // See executor.PostgreSQLConfig
+	SafeMode            bool       `json:"safeMode,omitempty"`
+	HasPassword         bool       `json:"hasPassword"`
+	HasSSLPassword      bool       `json:"hasSslPassword,omitempty"`
+	HasSSHKeyPassphrase bool       `json:"hasSshKeyPassphrase,omitempty"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+}
+
+// SSHTunnel is the SSH jump host of a profile; see executor.SSHTunnelConfig.
+// Its key passphrase is one of the profile's Secrets.
+type SSHTunnel struct {
+	Host           string `json:"host"`
+	Port           int    `json:"port,omitempty"`
+	User           string `json:"user"`
+	KeyFile        string `json:"keyFile,omitempty"` // The SSH agent is used when empty
+	KnownHostsFile string `json:"knownHostsFile,omitempty"`
+}
+
+// Secrets are the values of a profile kept in the secrets store rather than
+// in the profiles file. A nil value is left as stored; an empty one removes
+// the stored value.
+type Secrets struct {
+	Password    *string `json:"password,omitempty"`
+	SSLPassword *string `json:"sslPassword,omitempty"` // Passphrase of an encrypted SSLKey
+
+	SSHKeyPassphrase *string `json:"sshKeyPassphrase,omitempty"` // Passphrase of an encrypted SSHTunnel.KeyFile
+}
+
+// secret is one of the Secrets of a profile.
+type secret struct {
+	name  string  // Appended to the profile's key in the secrets store
+	value *string // The new value, if any
+	has   *bool   // The profile's flag recording that the value is stored
+}
+
+// of returns the secrets of profile with their new values.
+func (s Secrets) of(profile *Profile) []secret {
+	return []secret{
+		{"", s.Password, &profile.HasPassword},
+		{"sslpassword", s.SSLPassword, &profile.HasSSLPassword},
+		{"sshkeypassphrase", s.SSHKeyPassphrase, &profile.HasSSHKeyPassphrase},
+	}
+}
+
+// Store manages profiles. It is safe for concurrent use.
+type Store struct {
+	path    string
+	secrets secrets.Store
+
+	mu       sync.Mutex
+	profiles []Profile
+}
+
+// DefaultDir returns the directory profiles and fallback secrets are kept in.
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+	return filepath.Join(dir, "codezone"), nil
+}
+
+// Open loads the profiles at path, using secretStore for passwords.
+func Open(path string, secretStore secrets.Store) (*Store, error) {
+	s := &Store{path: path, secrets: secretStore}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connection profiles: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse connection profiles: %w", err)
+	}
+	return s, nil
+}
+
+// List returns every profile in the order they were created.
+func (s *Store) List() []Profile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Profile{}, s.profiles...)
+}
+
+// Get returns the profile with the given ID.
+func (s *Store) Get(id string) (Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return Profile{}, ErrNotFound
+	}
+	return s.profiles[i], nil
+}
+
+// Create saves a new profile with the secrets set in values.
+func (s *Store) Create(profile Profile, values Secrets) (Profile, error) {
+	if err := validate(profile); err != nil {
+		return Profile{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	profile.ID = uuid.NewString()
+	profile.CreatedAt = now
+	profile.UpdatedAt = now
+	profile.HasPassword = false
+	profile.HasSSLPassword = false
+	profile.HasSSHKeyPassphrase = false
+
+	if err := s.storeSecrets(&profile, values); err != nil {
+		return Profile{}, err
+	}
+
+	s.profiles = append(s.profiles, profile)
+	if err := s.save(); err != nil {
+		s.profiles = s.profiles[:len(s.profiles)-1]
+		return Profile{}, err
+	}
+	return profile, nil
+}
+
+// Update replaces the profile with the same ID and changes the secrets that
+// are set in values.
+func (s *Store) Update(profile Profile, values Secrets) (Profile, error) {
+	if err := validate(profile); err != nil {
+		return Profile{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(profile.ID)
+	if i < 0 {
+		return Profile{}, ErrNotFound
+	}
+
+	existing := s.profiles[i]
+	profile.CreatedAt = existing.CreatedAt
+	profile.UpdatedAt = time.Now()
+	profile.HasPassword = existing.HasPassword
+	profile.HasSSLPassword = existing.HasSSLPassword
+	profile.HasSSHKeyPassphrase = existing.HasSSHKeyPassphrase
+
+	if err := s.storeSecrets(&profile, values); err != nil {
+		return Profile{}, err
+	}
+
+	s.profiles[i] = profile
+	if err := s.save(); err != nil {
+		s.profiles[i] = existing
+		return Profile{}, err
+	}
+	return profile, nil
+}
+
+// Delete removes a profile and its stored secrets.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+
+	removed := s.profiles[i]
+	s.profiles = append(s.profiles[:i], s.profiles[i+1:]...)
+	if err := s.save(); err != nil {
+		s.profiles = append(s.profiles[:i], append([]Profile{removed}, s.profiles[i:]...)...)
+		return err
+	}
+
+	for _, secret := range (Secrets{}).of(&removed) {
+		if !*secret.has {
+			continue
+		}
+		if err := s.secrets.Delete(secretKey(id, secret.name)); err != nil && !errors.Is(err, secrets.ErrNotFound) {
+			return fmt.Errorf("profile deleted but its secrets could not be removed: %w", err)
+		}
+	}
+	return nil
+}
+
+// storeSecrets sets or removes the secrets of profile that have a new value
+// and updates its flags.
+func (s *Store) storeSecrets(profile *Profile, values Secrets) error {
+	for _, secret := range values.of(profile) {
+		switch {
+		case secret.value == nil:
+		case *secret.value != "":
+			if err := s.secrets.Set(secretKey(profile.ID, secret.name), *secret.value); err != nil {
+				return fmt.Errorf("failed to store secret: %w", err)
+			}
+			*secret.has = true
+		case *secret.has:
+			if err := s.secrets.Delete(secretKey(profile.ID, secret.name)); err != nil && !errors.Is(err, secrets.ErrNotFound) {
+				return fmt.Errorf("failed to remove secret: %w", err)
+			}
+			*secret.has = false
+		}
+	}
+	return nil
+}
+
+// Config returns the connection settings of a profile, including its secrets.
+func (s *Store) Config(id string) (*executor.PostgreSQLConfig, error) {
+	profile, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &executor.PostgreSQLConfig{
+		Host:     profile.Host,
+		Port:     profile.Port,
+		Database: profile.Database,
+		Username: profile.Username,
+		SSLMode:  profile.SSLMode,
+		Service:  profile.Service,
+
+		SSLRootCert:   profile.SSLRootCert,
+		SSLCert:       profile.SSLCert,
+		SSLKey:        profile.SSLKey,
+		SSLServerName: profile.SSLServerName,
+
+		ReadOnly: profile.ReadOnly,
+		SafeMode: profile.SafeMode,
+	}
+
+	var keyPassphrase string
+	values := Secrets{Password: &config.Password, SSLPassword: &config.SSLPassword, SSHKeyPassphrase: &keyPassphrase}
+	for _, secret := range values.of(&profile) {
+		if !*secret.has {
+			continue
+		}
+		value, err := s.secrets.Get(secretKey(id, secret.name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secrets for %s: %w", profile.Name, err)
+		}
+		*secret.value = value
+	}
+
+	if tunnel := profile.SSHTunnel; tunnel != nil {
+		config.SSHTunnel = &executor.SSHTunnelConfig{
+			Host:           tunnel.Host,
+			Port:           tunnel.Port,
+			User:           tunnel.User,
+			KeyFile:        tunnel.KeyFile,
+			KeyPassphrase:  keyPassphrase,
+			KnownHostsFile: tunnel.KnownHostsFile,
+		}
+	}
+	return config, nil
+}
+
+func (s *Store) index(id string) int {
+	for i := range s.profiles {
+		if s.profiles[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode connection profiles: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create profiles directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".connections-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save connection profiles: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save connection profiles: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save connection profiles: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save connection profiles: %w", err)
+	}
+	return nil
+}
+
+// secretKey returns the key of a profile's secret in the secrets store. The
+// password has the profile's own key.
+func secretKey(id, name string) string {
+	if name == "" {
+		return "postgres-profile:" + id
+	}
+	return "postgres-profile:" + id + ":" + name
+}
+
+func validate(profile Profile) error {
+	var problems []string
+	if strings.TrimSpace(profile.Name) == "" {
+		problems = append(problems, "name is required")
+	}
+	if strings.TrimSpace(profile.Host) == "" && strings.TrimSpace(profile.Service) == "" {
+		problems = append(problems, "host or service is required")
+	}
+	// A zero port leaves it to the service file or PGPORT.
+	if profile.Port < 0 || profile.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("port must be between 0 and 65535, got %d", profile.Port))
+	}
+	switch profile.SSLMode {
+	case "", "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		problems = append(problems, fmt.Sprintf("unknown sslmode %q", profile.SSLMode))
+	}
+
+	if tunnel := profile.SSHTunnel; tunnel != nil {
+		if strings.TrimSpace(tunnel.Host) == "" || strings.TrimSpace(tunnel.User) == "" {
+			problems = append(problems, "SSH host and user are required")
+		}
+		if tunnel.Port < 0 || tunnel.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("SSH port must be between 0 and 65535, got %d", tunnel.Port))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid connection profile: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package profiles
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codezone-wails/secrets"
+)
+
+// memorySecrets is an in-memory secrets.Store.
+type memorySecrets map[string]string
+
+func (m memorySecrets) Set(key, secret string) error {
+	m[key] = secret
+	return nil
+}
+
+func (m memorySecrets) Get(key string) (string, error) {
+	secret, ok := m[key]
+	if !ok {
+		return "", secrets.ErrNotFound
+	}
+	return secret, nil
+}
+
+func (m memorySecrets) Delete(key string) error {
+	if _, ok := m[key]; !ok {
+		return secrets.ErrNotFound
+	}
+	delete(m, key)
+	return nil
+}
+
+func newTestStore(t *testing.T) (*Store, memorySecrets, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "connections.json")
+	secretStore := memorySecrets{}
+	store, err := Open(path, secretStore)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	return store, secretStore, path
+}
+
+// password returns Secrets that set the password.
+func password(value string) Secrets {
+	return Secrets{Password: &value}
+}
+
+func testProfile() Profile {
+	return Profile{
+		Name:     "Local",
+		Host:     "localhost",
+		Port:     5432,
+		Database: "postgres",
+		Username: "postgres",
+		SSLMode:  "disable",
+		Color:    "#22c55e",
+		Tag:      "dev",
+	}
+}
+
+func TestStore_Create(t *testing.T) {
+	t.Run("should keep the password out of the profiles file", func(t *testing.T) {
+		store, secretStore, path := newTestStore(t)
+
+		profile, err := store.Create(testProfile(), password("s3cret"))
+		if err != nil {
+			t.Fatalf("Failed to create profile: %v", err)
+		}
+		if profile.ID == "" || !profile.HasPassword {
+			t.Fatalf("Expected an ID and a stored password, got %+v", profile)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read profiles file: %v", err)
+		}
+		if strings.Contains(string(data), "s3cret") {
+			t.Fatal("Expected the password not to be written to the profiles file")
+		}
+		if len(secretStore) != 1 {
+			t.Fatalf("Expected 1 stored secret, got %d", len(secretStore))
+		}
+	})
+
+	t.Run("should keep the client key passphrase in the secrets store", func(t *testing.T) {
+		store, secretStore, path := newTestStore(t)
+		profile := testProfile()
+		profile.SSLMode = "verify-full"
+		profile.SSLKey = "/keys/client.key"
+		passphrase := "key-secret"
+
+		created, err := store.Create(profile, Secrets{SSLPassword: &passphrase})
+		if err != nil {
+			t.Fatalf("Failed to create profile: %v", err)
+		}
+		if !created.HasSSLPassword || created.HasPassword || len(secretStore) != 1 {
+			t.Fatalf("Expected only the passphrase to be stored, got %+v", created)
+		}
+		if data, _ := os.ReadFile(path); strings.Contains(string(data), passphrase) {
+			t.Fatal("Expected the passphrase not to be written to the profiles file")
+		}
+
+		config, err := store.Config(created.ID)
+		if err != nil {
+			t.Fatalf("Failed to get config: %v", err)
+		}
+		if config.SSLPassword != passphrase || config.SSLKey != "/keys/client.key" || config.Password != "" {
+			t.Fatalf("Expected the key and its passphrase, got %+v", config)
+		}
+	})
+
+	t.Run("should connect through the saved SSH jump host", func(t *testing.T) {
+		store, secretStore, path := newTestStore(t)
+		profile := testProfile()
+		profile.Host = "db.internal"
+		profile.SSHTunnel = &SSHTunnel{Host: "bastion.example.com", Port: 2222, User: "deploy", KeyFile: "/keys/id_ed25519"}
+		passphrase := "ssh-secret"
+
+		created, err := store.Create(profile, Secrets{SSHKeyPassphrase: &passphrase})
+		if err != nil {
+			t.Fatalf("Failed to create profile: %v", err)
+		}
+		if !created.HasSSHKeyPassphrase || len(secretStore) != 1 {
+			t.Fatalf("Expected the key passphrase to be stored, got %+v", created)
+		}
+		if data, _ := os.ReadFile(path); strings.Contains(string(data), passphrase) {
+			t.Fatal("Expected the passphrase not to be written to the profiles file")
+		}
+
+		reopened, _ := Open(path, secretStore)
+		config, err := reopened.Config(created.ID)
+		if err != nil {
+			t.Fatalf("Failed to get config: %v", err)
+		}
+		tunnel := config.SSHTunnel
+		if tunnel == nil || tunnel.Host != "bastion.example.com" || tunnel.Port != 2222 || tunnel.User != "deploy" ||
+			tunnel.KeyFile != "/keys/id_ed25519" || tunnel.KeyPassphrase != passphrase {
+			t.Fatalf("Expected the jump host with its passphrase, got %+v", tunnel)
+		}
+	})
+
+	t.Run("should reject SSH jump hosts without host or user", func(t *testing.T) {
+		store, _, _ := newTestStore(t)
+		profile := testProfile()
+		profile.SSHTunnel = &SSHTunnel{Host: "bastion.example.com"}
+
+		if _, err := store.Create(profile, Secrets{}); err == nil || !strings.Contains(err.Error(), "SSH host and user") {
+			t.Fatalf("Expected the missing SSH user to be reported, got %v", err)
+		}
+	})
+
+	t.Run("should reject invalid profiles", func(t *testing.T) {
+		store, _, _ := newTestStore(t)
+		profile := testProfile()
+		profile.Name = ""
+		profile.SSLMode = "sometimes"
+
+		_, err := store.Create(profile, Secrets{})
+		if err == nil {
+			t.Fatal("Expected a validation error")
+		}
+		if !strings.Contains(err.Error(), "name is required") || !strings.Contains(err.Error(), "sslmode") {
+			t.Fatalf("Expected name and sslmode problems, got %v", err)
+		}
+	})
+
+	t.Run("should persist profiles across reopen", func(t *testing.T) {
+		store, secretStore, path := newTestStore(t)
+		created, _ := store.Create(testProfile(), password("pw"))
+
+		reopened, err := Open(path, secretStore)
+		if err != nil {
+			t.Fatalf("Failed to reopen store: %v", err)
+		}
+		profile, err := reopened.Get(created.ID)
+		if err != nil {
+			t.Fatalf("Expected profile after reopen, got %v", err)
+		}
+		if profile.Tag != "dev" || profile.Color != "#22c55e" {
+			t.Fatalf("Expected color and tag to persist, got %+v", profile)
+		}
+	})
+}
+
+func TestStore_Update(t *testing.T) {
+	store, secretStore, _ := newTestStore(t)
+	created, _ := store.Create(testProfile(), password("first"))
+
+	t.Run("should keep the password unless asked to change it", func(t *testing.T) {
+		created.Database = "analytics"
+		created.ReadOnly = true
+		updated, err := store.Update(created, Secrets{})
+		if err != nil {
+			t.Fatalf("Failed to update profile: %v", err)
+		}
+		if !updated.HasPassword {
+			t.Fatal("Expected the password to be kept")
+		}
+
+		config, err := store.Config(created.ID)
+		if err != nil {
+			t.Fatalf("Failed to get config: %v", err)
+		}
+		if config.Database != "analytics" || config.Password != "first" || !config.ReadOnly {
+			t.Fatalf("Expected updated database with original password, got %+v", config)
+		}
+	})
+
+	t.Run("should change one secret and keep the others", func(t *testing.T) {
+		passphrase := "key-secret"
+		if _, err := store.Update(created, Secrets{SSLPassword: &passphrase}); err != nil {
+			t.Fatalf("Failed to update profile: %v", err)
+		}
+		config, _ := store.Config(created.ID)
+		if config.Password != "first" || config.SSLPassword != passphrase {
+			t.Fatalf("Expected the password and the new passphrase, got %+v", config)
+		}
+
+		empty := ""
+		updated, err := store.Update(created, Secrets{SSLPassword: &empty})
+		if err != nil {
+			t.Fatalf("Failed to update profile: %v", err)
+		}
+		if updated.HasSSLPassword || !updated.HasPassword || len(secretStore) != 1 {
+			t.Fatalf("Expected only the passphrase to be removed, got %+v", updated)
+		}
+	})
+
+	t.Run("should clear the password when updated to empty", func(t *testing.T) {
+		updated, err := store.Update(created, password(""))
+		if err != nil {
+			t.Fatalf("Failed to update profile: %v", err)
+		}
+		if updated.HasPassword || len(secretStore) != 0 {
+			t.Fatalf("Expected the password to be removed, got %+v", updated)
+		}
+	})
+
+	t.Run("should report unknown profiles", func(t *testing.T) {
+		profile := testProfile()
+		profile.ID = "missing"
+		if _, err := store.Update(profile, Secrets{}); err != ErrNotFound {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestStore_Delete(t *testing.T) {
+	t.Run("should remove the profile and its secrets", func(t *testing.T) {
+		store, secretStore, _ := newTestStore(t)
+		passphrase := "key-secret"
+		created, _ := store.Create(testProfile(), Secrets{Password: &passphrase, SSLPassword: &passphrase})
+
+		if err := store.Delete(created.ID); err != nil {
+			t.Fatalf("Failed to delete profile: %v", err)
+		}
+		if len(store.List()) != 0 || len(secretStore) != 0 {
+			t.Fatal("Expected no profiles or secrets left")
+		}
+		if _, err := store.Config(created.ID); err != ErrNotFound {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore keeps secrets encrypted with AES-GCM in secrets.json. The key is
+// generated on first use and kept next to it in secrets.key, readable only by
+// the current user. This protects against casual disclosure, such as the file
+// being synced or shared, but not against someone with access to the account.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore returns a store that keeps its files in dir.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (s *FileStore) Set(key, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	aead, err := s.cipher()
+	if err != nil {
+		return err
+	}
+
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), []byte(key))
+	secrets[key] = base64.StdEncoding.EncodeToString(sealed)
+
+	return s.save(secrets)
+}
+
+func (s *FileStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secrets, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	encoded, ok := secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	aead, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("stored secret for %s is corrupted", key)
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret for %s: %w", key, err)
+	}
+	return string(plaintext), nil
+}
+
+func (s *FileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[key]; !ok {
+		return ErrNotFound
+	}
+	delete(secrets, key)
+	return s.save(secrets)
+}
+
+func (s *FileStore) cipher() (cipher.AEAD, error) {
+	keyPath := filepath.Join(s.dir, "secrets.key")
+
+	key, err := os.ReadFile(keyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		key = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, fmt.Errorf("failed to generate secrets key: %w", err)
+		}
+		if err := os.MkdirAll(s.dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create secrets directory: %w", err)
+		}
+		if err := os.WriteFile(keyPath, key, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write secrets key: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read secrets key: %w", err)
+	}
+
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secrets key %s is corrupted", keyPath)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *FileStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, "secrets.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets: %w", err)
+	}
+
+	secrets := map[string]string{}
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets: %w", err)
+	}
+	return secrets, nil
+}
+
+func (s *FileStore) save(secrets map[string]string) error {
+	data, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode secrets: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".secrets-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save secrets: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save secrets: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save secrets: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, "secrets.json")); err != nil {
+		return fmt.Errorf("failed to save secrets: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// errSecItemNotFound is the exit status of security(1) for missing items.
+const errSecItemNotFound = 44
+
+// keyring stores generic passwords in the login keychain through security(1).
+type keyring struct{}
+
+func (keyring) Set(key, secret string) error {
+	// Pass the command on stdin so the password does not show up in the
+	// process list.
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		strconv.Quote(Service), strconv.Quote(key), strconv.Quote(secret))
+	_, err := security(command, "-i")
+	return err
+}
+
+func (keyring) Get(key string) (string, error) {
+	output, err := security("", "find-generic-password", "-s", Service, "-a", key, "-w")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(output, "\n"), nil
+}
+
+func (keyring) Delete(key string) error {
+	_, err := security("", "delete-generic-password", "-s", Service, "-a", key)
+	return err
+}
+
+func security(stdin string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("/usr/bin/security", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		// In interactive mode errors are only reported on stderr.
+		if stderr.Len() > 0 {
+			return "", fmt.Errorf("keychain error: %s", strings.TrimSpace(stderr.String()))
+		}
+		return stdout.String(), nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == errSecItemNotFound:
+		return "", ErrNotFound
+	case errors.As(err, &exitErr):
+		return "", fmt.Errorf("keychain error: %s", strings.TrimSpace(stderr.String()))
+	default:
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+}
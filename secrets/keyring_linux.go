@@ -0,0 +1,65 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keyring talks to the freedesktop secret service (GNOME Keyring, KWallet)
+// through libsecret's secret-tool.
+type keyring struct{}
+
+func (keyring) Set(key, secret string) error {
+	_, err := secretTool(secret, "store", "--label", "Code Zone: "+key, "service", Service, "account", key)
+	return err
+}
+
+func (keyring) Get(key string) (string, error) {
+	output, err := secretTool("", "lookup", "service", Service, "account", key)
+	if err != nil {
+		return "", err
+	}
+	return output, nil
+}
+
+func (keyring) Delete(key string) error {
+	if _, err := (keyring{}).Get(key); err != nil {
+		return err
+	}
+	_, err := secretTool("", "clear", "service", Service, "account", key)
+	return err
+}
+
+// secretTool runs secret-tool with stdin as input. Lookups of missing keys
+// exit with status 1 and no output.
+func secretTool(stdin string, args ...string) (string, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return "", fmt.Errorf("%w: secret-tool is not installed", ErrUnavailable)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return stdout.String(), nil
+	case errors.As(err, &exitErr) && args[0] == "lookup" && stderr.Len() == 0:
+		return "", ErrNotFound
+	default:
+		// Without a session bus or an unlocked collection secret-tool fails
+		// with a message on stderr; treat that as no keyring.
+		return "", fmt.Errorf("%w: %s", ErrUnavailable, strings.TrimSpace(stderr.String()))
+	}
+}
@@ -0,0 +1,13 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+//go:build !linux && !darwin && !windows
+
+package secrets
+
+// keyring is unavailable on this platform, so the file store is always used.
+type keyring struct{}
+
+func (keyring) Set(key, secret string) error   { return ErrUnavailable }
+func (keyring) Get(key string) (string, error) { return "", ErrUnavailable }
+func (keyring) Delete(key string) error        { return ErrUnavailable }
@@ -0,0 +1,86 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+// Package secrets stores small secrets such as database passwords in the OS
+// keyring, falling back to an encrypted file where no keyring is available.
+package secrets
+
+import (
+	"errors"
+	"log"
+)
+
+// Service is the keyring service name under which secrets are stored.
+const Service = "codezone"
+
+// ErrNotFound is returned by Get when no secret is stored under a key.
+var ErrNotFound = errors.New("secret not found")
+
+// ErrUnavailable is returned by a keyring that cannot be used on this system,
+// for example on headless Linux without a secret service.
+var ErrUnavailable = errors.New("keyring is not available")
+
+// Store keeps secrets by key.
+type Store interface {
+	Set(key, secret string) error
+	Get(key string) (string, error)
+	Delete(key string) error
+}
+
+// Default returns the OS keyring, backed by an encrypted file in dir for
+// systems where the keyring is unavailable.
+func Default(dir string) Store {
+	return &fallbackStore{
+		primary:  keyring{},
+		fallback: NewFileStore(dir),
+	}
+}
+
+// fallbackStore uses primary and switches to fallback when primary reports
+// ErrUnavailable.
+type fallbackStore struct {
+	primary  Store
+	fallback Store
+}
+
+func (s *fallbackStore) Set(key, secret string) error {
+	err := s.primary.Set(key, secret)
+	if errors.Is(err, ErrUnavailable) {
+		log.Printf("Secrets: Keyring unavailable, using encrypted file: %v", err)
+		return s.fallback.Set(key, secret)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Remove any copy left from a time the keyring was unavailable.
+	if err := s.fallback.Delete(key); err != nil && !errors.Is(err, ErrNotFound) {
+		log.Printf("Secrets: Failed to remove fallback copy: %v", err)
+	}
+	return nil
+}
+
+func (s *fallbackStore) Get(key string) (string, error) {
+	secret, err := s.primary.Get(key)
+	if err == nil {
+		return secret, nil
+	}
+	if !errors.Is(err, ErrUnavailable) && !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+	return s.fallback.Get(key)
+}
+
+func (s *fallbackStore) Delete(key string) error {
+	primaryErr := s.primary.Delete(key)
+	fallbackErr := s.fallback.Delete(key)
+
+	switch {
+	case primaryErr == nil || fallbackErr == nil:
+		return nil
+	case !errors.Is(primaryErr, ErrUnavailable) && !errors.Is(primaryErr, ErrNotFound):
+		return primaryErr
+	default:
+		return fallbackErr
+	}
+}
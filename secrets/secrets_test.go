@@ -0,0 +1,96 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Run("should round-trip secrets", func(t *testing.T) {
+		store := NewFileStore(t.TempDir())
+		if err := store.Set("db", "hunter2"); err != nil {
+			t.Fatalf("Failed to set secret: %v", err)
+		}
+
+		secret, err := store.Get("db")
+		if err != nil {
+			t.Fatalf("Failed to get secret: %v", err)
+		}
+		if secret != "hunter2" {
+			t.Fatalf("Expected 'hunter2', got '%s'", secret)
+		}
+	})
+
+	t.Run("should not write secrets in plain text", func(t *testing.T) {
+		dir := t.TempDir()
+		store := NewFileStore(dir)
+		store.Set("db", "hunter2")
+
+		data, err := os.ReadFile(filepath.Join(dir, "secrets.json"))
+		if err != nil {
+			t.Fatalf("Failed to read secrets file: %v", err)
+		}
+		if strings.Contains(string(data), "hunter2") {
+			t.Fatal("Expected the secret to be encrypted")
+		}
+	})
+
+	t.Run("should report missing secrets", func(t *testing.T) {
+		store := NewFileStore(t.TempDir())
+		if _, err := store.Get("missing"); err != ErrNotFound {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+		if err := store.Delete("missing"); err != ErrNotFound {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should not decrypt a secret stored under another key", func(t *testing.T) {
+		dir := t.TempDir()
+		store := NewFileStore(dir)
+		store.Set("a", "one")
+		store.Set("b", "two")
+
+		// Swap the ciphertexts; authenticated encryption must reject them.
+		path := filepath.Join(dir, "secrets.json")
+		data, _ := os.ReadFile(path)
+		swapped := strings.NewReplacer(`"a"`, `"b"`, `"b"`, `"a"`).Replace(string(data))
+		os.WriteFile(path, []byte(swapped), 0o600)
+
+		if _, err := store.Get("a"); err == nil {
+			t.Fatal("Expected decryption to fail for a swapped secret")
+		}
+	})
+}
+
+// unavailableKeyring always reports that no keyring exists.
+type unavailableKeyring struct{}
+
+func (unavailableKeyring) Set(key, secret string) error   { return ErrUnavailable }
+func (unavailableKeyring) Get(key string) (string, error) { return "", ErrUnavailable }
+func (unavailableKeyring) Delete(key string) error        { return ErrUnavailable }
+
+func TestFallbackStore(t *testing.T) {
+	t.Run("should use the file store when the keyring is unavailable", func(t *testing.T) {
+		fallback := NewFileStore(t.TempDir())
+		store := &fallbackStore{primary: unavailableKeyring{}, fallback: fallback}
+
+		if err := store.Set("db", "pw"); err != nil {
+			t.Fatalf("Failed to set secret: %v", err)
+		}
+		if secret, err := fallback.Get("db"); err != nil || secret != "pw" {
+			t.Fatalf("Expected the secret in the fallback store, got '%s', %v", secret, err)
+		}
+		if secret, err := store.Get("db"); err != nil || secret != "pw" {
+			t.Fatalf("Expected to read the secret back, got '%s', %v", secret, err)
+		}
+		if err := store.Delete("db"); err != nil {
+			t.Fatalf("Failed to delete secret: %v", err)
+		}
+		if _, err := store.Get("db"); err != ErrNotFound {
+			t.Fatalf("Expected ErrNotFound after delete, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,349 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+// Package server exposes an ExecutionManager over a loopback HTTP+JSON API so
+// that editors and scripts on the same machine can run code in Code Zone.
+//
+// Every request must carry the server token as "Authorization: Bearer <token>".
+// Endpoints:
+//
+//	GET  /v1/languages                  supported languages
+//	POST /v1/execute                    run an executor.ExecutionConfig
+//	POST /v1/executions/{id}/cancel     cancel a running execution
+//	POST /v1/executors/{language}/refresh
+//	GET  /v1/postgres                   connection status
+//	POST /v1/postgres/connect           connect with an executor.PostgreSQLConfig
+//	POST /v1/postgres/disconnect
+//
+// POST /v1/execute streams output as server-sent events when the request sets
+// "Accept: text/event-stream": an "output" event per executor.OutputChunk,
+// followed by a single "result" or "error" event.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"codezone-wails/executor"
+
+	"github.com/google/uuid"
+)
+
+// DefaultAddr is the address used when none is configured.
+const DefaultAddr = "127.0.0.1:7878"
+
+// maxRequestBytes limits request bodies, which carry at most a code snippet.
+const maxRequestBytes = 8 << 20
+
+// Info describes a running server, for clients that need to connect to it.
+type Info struct {
+	Running bool   `json:"running"`
+	Address string `json:"address,omitempty"`
+	Token   string `json:"token,omitempty"`
+}
+
+// Server serves the API for a single ExecutionManager.
+type Server struct {
+	execMgr  *executor.ExecutionManager
+	token    string
+	listener net.Listener
+	http     *http.Server
+	done     chan struct{}
+}
+
+// Start listens on addr, which must be a loopback address, and serves requests
+// in the background. An empty addr uses DefaultAddr and an empty token is
+// replaced with a random one.
+func Start(execMgr *executor.ExecutionManager, addr, token string) (*Server, error) {
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	if err := checkLoopback(addr); err != nil {
+		return nil, err
+	}
+
+	if token == "" {
+		var err error
+		token, err = GenerateToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s := &Server{
+		execMgr:  execMgr,
+		token:    token,
+		listener: listener,
+		done:     make(chan struct{}),
+	}
+	s.http = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		defer close(s.done)
+		if err := s.http.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("API Server: Stopped with error: %v", err)
+		}
+	}()
+
+	log.Printf("API Server: Listening on http://%s", listener.Addr())
+	return s, nil
+}
+
+// Info returns the address and token clients should use.
+func (s *Server) Info() Info {
+	return Info{
+		Running: true,
+		Address: s.listener.Addr().String(),
+		Token:   s.token,
+	}
+}
+
+// Close stops the server, giving in-flight requests a moment to finish.
+// Streaming executions still running afterwards are cancelled.
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	err := s.http.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = s.http.Close()
+	}
+	<-s.done
+	return err
+}
+
+// Wait blocks until the server stops.
+func (s *Server) Wait() {
+	<-s.done
+}
+
+// GenerateToken returns a random token suitable for authenticating clients.
+func GenerateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// checkLoopback rejects addresses that other machines could reach.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("address %q is not a loopback address", addr)
+}
+
+// Handler returns the authenticated API handler.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/languages", s.handleLanguages)
+	mux.HandleFunc("POST /v1/execute", s.handleExecute)
+	mux.HandleFunc("POST /v1/executions/{id}/cancel", s.handleCancel)
+	mux.HandleFunc("POST /v1/executors/{language}/refresh", s.handleRefresh)
+	mux.HandleFunc("GET /v1/postgres", s.handlePostgresStatus)
+	mux.HandleFunc("POST /v1/postgres/connect", s.handlePostgresConnect)
+	mux.HandleFunc("POST /v1/postgres/disconnect", s.handlePostgresDisconnect)
+	return s.authenticate(mux)
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleLanguages(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.execMgr.GetSupportedLanguages())
+}
+
+func (s *Server) handleExecute(w http.ResponseWriter, r *http.Request) {
+	var config executor.ExecutionConfig
+	if err := decodeJSON(w, r, &config); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if config.ExecutionID == "" {
+		config.ExecutionID = uuid.NewString()
+	}
+
+	// Stop the execution if the client goes away before it finishes.
+	stop := context.AfterFunc(r.Context(), func() {
+		s.execMgr.CancelExecution(config.ExecutionID)
+	})
+	defer stop()
+
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.executeStream(w, config)
+		return
+	}
+
+	result, err := s.execMgr.Execute(config)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) executeStream(w http.ResponseWriter, config executor.ExecutionConfig) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, errors.New("streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var mu sync.Mutex
+	send := func(event string, payload any) {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+	}
+
+	result, err := s.execMgr.ExecuteStream(config, func(chunk executor.OutputChunk) {
+		send("output", chunk)
+	})
+	if err != nil {
+		send("error", errorBody{Error: err.Error()})
+		return
+	}
+	send("result", result)
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	if err := s.execMgr.CancelExecution(r.PathValue("id")); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if err := s.execMgr.RefreshExecutor(executor.Language(r.PathValue("language"))); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type postgresStatus struct {
+	Connected bool `json:"connected"`
+}
+
+func (s *Server) handlePostgresStatus(w http.ResponseWriter, r *http.Request) {
+	pgExecutor, err := s.postgresExecutor()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, postgresStatus{Connected: pgExecutor.IsConnected()})
+}
+
+func (s *Server) handlePostgresConnect(w http.ResponseWriter, r *http.Request) {
+	var config executor.PostgreSQLConfig
+	if err := decodeJSON(w, r, &config); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pgExecutor, err := s.postgresExecutor()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	log.Printf("API Server: Connecting to PostgreSQL at %s:%d/%s", config.Host, config.Port, config.Database)
+	if err := pgExecutor.CreatePgPool(r.Context(), &config); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if err := pgExecutor.TestConnection(r.Context(), &config); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, postgresStatus{Connected: true})
+}
+
+func (s *Server) handlePostgresDisconnect(w http.ResponseWriter, r *http.Request) {
+	pgExecutor, err := s.postgresExecutor()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err := pgExecutor.Cleanup(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, postgresStatus{Connected: false})
+}
+
+func (s *Server) postgresExecutor() (*executor.PostgreSQLExecutor, error) {
+	pgExecutor, ok := s.execMgr.GetExecutor(executor.PostgreSQL).(*executor.PostgreSQLExecutor)
+	if !ok {
+		return nil, errors.New("PostgreSQL executor not available")
+	}
+	return pgExecutor, nil
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorBody{Error: err.Error()})
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"codezone-wails/executor"
+)
+
+const testToken = "test-token"
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	opts := executor.DefaultExecutorOptions()
+	opts.Timeout = 5 * time.Second
+	execMgr := executor.NewExecutionManager(opts)
+	t.Cleanup(execMgr.Cleanup)
+
+	s := &Server{execMgr: execMgr, token: testToken}
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func doRequest(t *testing.T, ts *httptest.Server, method, path, body string, header http.Header) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestServer_Auth(t *testing.T) {
+	ts := newTestServer(t)
+
+	t.Run("should reject requests without a token", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/v1/languages")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("should reject requests with a wrong token", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodGet, "/v1/languages", "", http.Header{
+			"Authorization": {"Bearer wrong"},
+		})
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("should accept requests with the token", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodGet, "/v1/languages", "", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+
+		var languages []executor.LanguageDescriptor
+		if err := json.NewDecoder(resp.Body).Decode(&languages); err != nil {
+			t.Fatalf("Failed to decode languages: %v", err)
+		}
+		if len(languages) == 0 {
+			t.Fatal("Expected at least one language")
+		}
+	})
+}
+
+func TestServer_Execute(t *testing.T) {
+	ts := newTestServer(t)
+
+	t.Run("should return the execution result", func(t *testing.T) {
+		body := `{"code": "console.log(40 + 2)", "language": "typescript"}`
+		resp := doRequest(t, ts, http.MethodPost, "/v1/execute", body, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+
+		var result executor.ExecutionResult
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode result: %v", err)
+		}
+		if result.Output != "42" {
+			t.Fatalf("Expected output '42', got '%s'", result.Output)
+		}
+		if result.ExecutionID == "" {
+			t.Fatal("Expected an execution ID")
+		}
+	})
+
+	t.Run("should reject unknown languages", func(t *testing.T) {
+		body := `{"code": "print(1)", "language": "cobol"}`
+		resp := doRequest(t, ts, http.MethodPost, "/v1/execute", body, nil)
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected status 422, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("should reject malformed bodies", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodPost, "/v1/execute", `{"code":`, nil)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("Expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("should stream output as server-sent events", func(t *testing.T) {
+		body := `{"code": "console.log('a'); console.log('b')", "language": "typescript"}`
+		resp := doRequest(t, ts, http.MethodPost, "/v1/execute", body, http.Header{
+			"Accept": {"text/event-stream"},
+		})
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Expected event stream, got '%s'", ct)
+		}
+
+		var events []string
+		var result executor.ExecutionResult
+		scanner := bufio.NewScanner(resp.Body)
+		var event string
+		for scanner.Scan() {
+			line := scanner.Text()
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event = name
+				events = append(events, name)
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok && event == "result" {
+				if err := json.Unmarshal([]byte(data), &result); err != nil {
+					t.Fatalf("Failed to decode result event: %v", err)
+				}
+			}
+		}
+
+		if len(events) < 2 || events[len(events)-1] != "result" {
+			t.Fatalf("Expected output events followed by a result, got %v", events)
+		}
+		if events[0] != "output" {
+			t.Fatalf("Expected the first event to be output, got %v", events)
+		}
+		if result.Output != "a\nb" {
+			t.Fatalf("Expected output 'a\\nb', got '%s'", result.Output)
+		}
+	})
+}
+
+func TestServer_Executors(t *testing.T) {
+	ts := newTestServer(t)
+
+	t.Run("should refresh a registered executor", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodPost, "/v1/executors/typescript/refresh", "", nil)
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("should report unknown executions when cancelling", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodPost, "/v1/executions/missing/cancel", "", nil)
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("Expected status 404, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("should report the PostgreSQL connection status", func(t *testing.T) {
+		resp := doRequest(t, ts, http.MethodGet, "/v1/postgres", "", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+
+		var status postgresStatus
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			t.Fatalf("Failed to decode status: %v", err)
+		}
+		if status.Connected {
+			t.Fatal("Expected no PostgreSQL connection")
+		}
+	})
+}
+
+func TestStart(t *testing.T) {
+	t.Run("should refuse non-loopback addresses", func(t *testing.T) {
+		_, err := Start(nil, "0.0.0.0:0", "")
+		if err == nil {
+			t.Fatal("Expected an error for a non-loopback address")
+		}
+	})
+
+	t.Run("should generate a token when none is given", func(t *testing.T) {
+		srv, err := Start(executor.NewExecutionManager(executor.DefaultExecutorOptions()), "127.0.0.1:0", "")
+		if err != nil {
+			t.Fatalf("Failed to start server: %v", err)
+		}
+		defer srv.Close()
+
+		info := srv.Info()
+		if info.Token == "" || !info.Running {
+			t.Fatalf("Expected a running server with a token, got %+v", info)
+		}
+	})
+}
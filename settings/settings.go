@@ -0,0 +1,231 @@
+// Copyright (c) 2024-2025 Stanislav Klymoshenko
+// Licensed under the MIT License. See LICENSE file for details.
+
+// Package settings loads and saves the user's settings file in the OS config
+// directory.
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"codezone-wails/executor"
+	"codezone-wails/history"
+)
+
+// Limits are the execution limits that can be set globally or per language.
+// In a per-language override, zero fields inherit the global value.
+type Limits struct {
+	TimeoutMs      int64 `json:"timeoutMs,omitempty"`
+	MemoryMB       int   `json:"memoryMB,omitempty"`
+	MaxOutputs     int   `json:"maxOutputs,omitempty"`
+	MaxOutputBytes int   `json:"maxOutputBytes,omitempty"`
+}
+
+// SQLSettings configure how PostgreSQL scripts run.
+// Zero session timeouts keep the server's setting.
+type SQLSettings struct {
+	ContinueOnError            bool  `json:"continueOnError"`                      // Run the remaining statements after one fails
+	StatementTimeoutMs         int64 `json:"statementTimeoutMs,omitempty"`         // statement_timeout of the session
+	LockTimeoutMs              int64 `json:"lockTimeoutMs,omitempty"`              // lock_timeout of the session
+	IdleInTransactionTimeoutMs int64 `json:"idleInTransactionTimeoutMs,omitempty"` // idle_in_transaction_session_timeout of the session
+}
+
+// Settings is the content of the settings file.
+type Settings struct {
+	Defaults     Limits                       `json:"defaults"`
+	Languages    map[executor.Language]Limits `json:"languages,omitempty"`
+	HistoryLimit int                          `json:"historyLimit"`
+	SQL          SQLSettings                  `json:"sql"`
+}
+
+// Bounds accepted by Validate.
+const (
+	minTimeout        = 100 * time.Millisecond
+	maxTimeout        = 10 * time.Minute
+	minMemoryMB       = 16
+	maxMemoryMB       = 16 * 1024
+	maxMaxOutputs     = 1_000_000
+	minMaxOutputBytes = 1 << 10
+	maxMaxOutputBytes = 64 << 20
+	maxHistoryLimit   = 100_000
+	maxSessionTimeout = 24 * time.Hour
+)
+
+// Default returns the settings used when no file exists.
+func Default() Settings {
+	return Settings{
+		Defaults: Limits{
+			// Allow a generous timeout for potentially long-running code.
+			TimeoutMs:      15_000,
+			MemoryMB:       128,
+			MaxOutputs:     1000,
+			MaxOutputBytes: 1 << 20,
+		},
+		HistoryLimit: history.DefaultLimit,
+	}
+}
+
+// DefaultPath returns the settings file location in the user config directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+	return filepath.Join(dir, "codezone", "settings.json"), nil
+}
+
+// Load reads the settings at path. A missing file yields Default; fields
+// missing from the file keep their default values.
+func Load(path string) (Settings, error) {
+	s := Default()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Default(), fmt.Errorf("failed to parse settings: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return Default(), err
+	}
+	return s, nil
+}
+
+// Save validates s and writes it to path, replacing the file atomically.
+func Save(path string, s Settings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".settings-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	return nil
+}
+
+// Validate reports every out-of-range value in s.
+func (s Settings) Validate() error {
+	var problems []string
+
+	problems = append(problems, s.Defaults.validate("defaults", true)...)
+	for lang, limits := range s.Languages {
+		if _, ok := executor.Registration(lang); !ok {
+			problems = append(problems, fmt.Sprintf("languages: unknown language %q", lang))
+			continue
+		}
+		problems = append(problems, limits.validate(string(lang), false)...)
+	}
+
+	if s.HistoryLimit < 1 || s.HistoryLimit > maxHistoryLimit {
+		problems = append(problems, fmt.Sprintf("historyLimit must be between 1 and %d, got %d", maxHistoryLimit, s.HistoryLimit))
+	}
+	problems = append(problems, s.SQL.validate()...)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid settings: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validate checks each field against its bounds. Unless required is set, zero
+// means "inherit" and is accepted.
+func (l Limits) validate(scope string, required bool) []string {
+	var problems []string
+	check := func(name string, value, min, max int64, unit string) {
+		if value == 0 && !required {
+			return
+		}
+		if value < min || value > max {
+			problems = append(problems, fmt.Sprintf("%s.%s must be between %d and %d%s, got %d", scope, name, min, max, unit, value))
+		}
+	}
+
+	check("timeoutMs", l.TimeoutMs, minTimeout.Milliseconds(), maxTimeout.Milliseconds(), "ms")
+	check("memoryMB", int64(l.MemoryMB), minMemoryMB, maxMemoryMB, "MB")
+	check("maxOutputs", int64(l.MaxOutputs), 1, maxMaxOutputs, "")
+	check("maxOutputBytes", int64(l.MaxOutputBytes), minMaxOutputBytes, maxMaxOutputBytes, " bytes")
+	return problems
+}
+
+// ExecutorOptions converts l. Zero fields stay zero.
+func (l Limits) ExecutorOptions() executor.ExecutorOptions {
+	return executor.ExecutorOptions{
+		Timeout:        time.Duration(l.TimeoutMs) * time.Millisecond,
+		MemoryMB:       l.MemoryMB,
+		MaxOutputs:     l.MaxOutputs,
+		MaxOutputBytes: l.MaxOutputBytes,
+	}
+}
+
+// ExecutorOptions returns the default options and the per-language overrides in
+// the form ExecutionManager.SetOptions expects. The SQL settings go into the
+// PostgreSQL override, so that changing them leaves the other executors alone.
+func (s Settings) ExecutorOptions() (executor.ExecutorOptions, map[executor.Language]executor.ExecutorOptions) {
+	overrides := make(map[executor.Language]executor.ExecutorOptions, len(s.Languages)+1)
+	for lang, limits := range s.Languages {
+		overrides[lang] = limits.ExecutorOptions()
+	}
+
+	postgres := overrides[executor.PostgreSQL]
+	postgres.SQL = s.SQL.options()
+	overrides[executor.PostgreSQL] = postgres
+
+	return s.Defaults.ExecutorOptions(), overrides
+}
+
+// validate checks the session timeouts, which may be zero.
+func (s SQLSettings) validate() []string {
+	var problems []string
+	check := func(name string, value int64) {
+		if value < 0 || value > maxSessionTimeout.Milliseconds() {
+			problems = append(problems, fmt.Sprintf("sql.%s must be between 0 and %dms, got %d", name, maxSessionTimeout.Milliseconds(), value))
+		}
+	}
+
+	check("statementTimeoutMs", s.StatementTimeoutMs)
+	check("lockTimeoutMs", s.LockTimeoutMs)
+	check("idleInTransactionTimeoutMs", s.IdleInTransactionTimeoutMs)
+	return problems
+}
+
+func (s SQLSettings) options() executor.SQLOptions {
+	return executor.SQLOptions{
+		ContinueOnError:          s.ContinueOnError,
+		StatementTimeout:         time.Duration(s.StatementTimeoutMs) * time.Millisecond,
+		LockTimeout:              time.Duration(s.LockTimeoutMs) * time.Millisecond,
+		IdleInTransactionTimeout: time.Duration(s.IdleInTransactionTimeoutMs) * time.Millisecond,
+	}
+}
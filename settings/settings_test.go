@@ -0,0 +1,137 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"codezone-wails/executor"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("should return defaults when the file is missing", func(t *testing.T) {
+		s, err := Load(filepath.Join(t.TempDir(), "settings.json"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if s.Defaults != Default().Defaults {
+			t.Fatalf("Expected default limits, got %+v", s.Defaults)
+		}
+	})
+
+	t.Run("should keep defaults for fields missing from the file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "settings.json")
+		os.WriteFile(path, []byte(`{"defaults": {"timeoutMs": 3000}}`), 0o600)
+
+		s, err := Load(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if s.Defaults.TimeoutMs != 3000 {
+			t.Fatalf("Expected timeout 3000ms, got %d", s.Defaults.TimeoutMs)
+		}
+		if s.Defaults.MemoryMB != Default().Defaults.MemoryMB {
+			t.Fatalf("Expected default memory limit, got %d", s.Defaults.MemoryMB)
+		}
+	})
+
+	t.Run("should fall back to defaults for invalid files", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "settings.json")
+		os.WriteFile(path, []byte(`{"defaults": {"memoryMB": 1}}`), 0o600)
+
+		s, err := Load(path)
+		if err == nil {
+			t.Fatal("Expected a validation error")
+		}
+		if s.Defaults != Default().Defaults {
+			t.Fatalf("Expected default limits, got %+v", s.Defaults)
+		}
+	})
+}
+
+func TestSave(t *testing.T) {
+	t.Run("should round-trip per-language overrides", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nested", "settings.json")
+		s := Default()
+		s.Languages = map[executor.Language]Limits{executor.Go: {TimeoutMs: 30_000}}
+
+		if err := Save(path, s); err != nil {
+			t.Fatalf("Failed to save: %v", err)
+		}
+		loaded, err := Load(path)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if loaded.Languages[executor.Go].TimeoutMs != 30_000 {
+			t.Fatalf("Expected Go timeout override, got %+v", loaded.Languages)
+		}
+	})
+
+	t.Run("should refuse invalid settings", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "settings.json")
+		s := Default()
+		s.HistoryLimit = 0
+
+		if err := Save(path, s); err == nil {
+			t.Fatal("Expected a validation error")
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatal("Expected no file to be written")
+		}
+	})
+}
+
+func TestSettings_Validate(t *testing.T) {
+	t.Run("should accept zero fields in overrides", func(t *testing.T) {
+		s := Default()
+		s.Languages = map[executor.Language]Limits{executor.TypeScript: {MemoryMB: 256}}
+		if err := s.Validate(); err != nil {
+			t.Fatalf("Expected valid settings, got %v", err)
+		}
+	})
+
+	t.Run("should report every problem", func(t *testing.T) {
+		s := Default()
+		s.Defaults.TimeoutMs = 0
+		s.Languages = map[executor.Language]Limits{
+			"cobol":     {TimeoutMs: 1000},
+			executor.Go: {MaxOutputBytes: 10},
+		}
+		s.SQL.LockTimeoutMs = -1
+
+		err := s.Validate()
+		if err == nil {
+			t.Fatal("Expected a validation error")
+		}
+		for _, want := range []string{"defaults.timeoutMs", "unknown language \"cobol\"", "go.maxOutputBytes", "sql.lockTimeoutMs"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error to mention %s, got %v", want, err)
+			}
+		}
+	})
+}
+
+func TestSettings_ExecutorOptions(t *testing.T) {
+	s := Default()
+	s.Languages = map[executor.Language]Limits{executor.Go: {TimeoutMs: 30_000}}
+
+	defaults, overrides := s.ExecutorOptions()
+	if defaults.Timeout != 15*time.Second {
+		t.Fatalf("Expected default timeout 15s, got %v", defaults.Timeout)
+	}
+	if overrides[executor.Go].Timeout != 30*time.Second || overrides[executor.Go].MemoryMB != 0 {
+		t.Fatalf("Expected only the Go timeout to be overridden, got %+v", overrides[executor.Go])
+	}
+
+	s.SQL.ContinueOnError = true
+	s.SQL.StatementTimeoutMs = 5000
+	_, overrides = s.ExecutorOptions()
+	if !overrides[executor.PostgreSQL].SQL.ContinueOnError || overrides[executor.Go].SQL.ContinueOnError {
+		t.Fatalf("Expected the SQL settings only in the PostgreSQL override, got %+v", overrides)
+	}
+	if overrides[executor.PostgreSQL].SQL.StatementTimeout != 5*time.Second {
+		t.Fatalf("Expected a 5s statement timeout, got %v", overrides[executor.PostgreSQL].SQL.StatementTimeout)
+	}
+}